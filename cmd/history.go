@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/journal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyTypes    []string
+	historyOutcomes []string
+	historySince    time.Duration
+	historyLimit    int
+	historyJSON     bool
+	historyVerbose  bool
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List past failover decisions from the event journal",
+	Long: `List past failover decisions recorded in the event journal in failover.state_dir, oldest first.
+Every decision is journaled with its outcome, the leaderless sample count and gossip at the time, and
+the commands and hooks run with their exit codes and durations. Refusals repeated every sample are
+journaled once. Use --verbose for gossip and command details or --json for the raw journal lines.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		for _, eventType := range historyTypes {
+			if !slices.Contains(journal.EventTypes, eventType) {
+				return fmt.Errorf("--type must be one of %s", strings.Join(journal.EventTypes, ", "))
+			}
+		}
+		for _, outcome := range historyOutcomes {
+			if !slices.Contains(journal.Outcomes, outcome) {
+				return fmt.Errorf("--outcome must be one of %s", strings.Join(journal.Outcomes, ", "))
+			}
+		}
+
+		cfg, err := groupConfig()
+		if err != nil {
+			return err
+		}
+
+		journalFile := journal.FilePath(cfg.Failover.StateDir)
+		if journalFile == "" {
+			return fmt.Errorf("failover.state_dir is not set - there is no event journal")
+		}
+
+		events, err := journal.Read(journalFile)
+		if err != nil {
+			return err
+		}
+
+		filter := journal.Filter{
+			Types:    historyTypes,
+			Outcomes: historyOutcomes,
+			Limit:    historyLimit,
+		}
+		if historySince > 0 {
+			filter.Since = time.Now().Add(-historySince)
+		}
+		events = filter.Apply(events)
+
+		if historyJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			for _, event := range events {
+				if err := encoder.Encode(event); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		if len(events) == 0 {
+			fmt.Printf("no events found in %s\n", journalFile)
+			return nil
+		}
+
+		if historyVerbose {
+			printHistoryVerbose(events)
+			return nil
+		}
+
+		printHistory(events)
+		return nil
+	},
+}
+
+// printHistory prints events as a table
+func printHistory(events []journal.Event) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "TIME\tTYPE\tOUTCOME\tDURATION\tLEADERLESS\tCOMMANDS\tDETAIL")
+	for _, event := range events {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%s\n",
+			event.Time.Local().Format(time.RFC3339),
+			event.Type,
+			event.Outcome,
+			event.Duration().Round(time.Millisecond),
+			event.LeaderlessSamplesCount,
+			len(event.Commands),
+			eventDetail(event),
+		)
+	}
+}
+
+// printHistoryVerbose prints each event with the gossip snapshot and commands run
+func printHistoryVerbose(events []journal.Event) {
+	for _, event := range events {
+		fmt.Printf("%s %s %s in %s\n",
+			event.Time.Local().Format(time.RFC3339),
+			event.Type,
+			event.Outcome,
+			event.Duration().Round(time.Millisecond),
+		)
+		if detail := eventDetail(event); detail != "" {
+			fmt.Printf("  %s\n", detail)
+		}
+		fmt.Printf("  leaderless samples: %d\n", event.LeaderlessSamplesCount)
+
+		for _, peer := range event.Gossip {
+			role := constants.RoleNamePassive
+			if peer.Active {
+				role = constants.RoleNameActive
+			}
+			fmt.Printf("  gossip: %s %s %s %s last seen %s\n", peer.Name, peer.IP, role, peer.Pubkey, peer.LastSeenAt.Local().Format(time.RFC3339))
+		}
+
+		for _, result := range event.Commands {
+			fmt.Printf("  command: %s exit %d in %s: %s\n",
+				result.Name,
+				result.ExitCode,
+				(time.Duration(result.DurationMs) * time.Millisecond).Round(time.Millisecond),
+				strings.Join(append([]string{result.Command}, result.Args...), " "),
+			)
+			if result.Error != "" {
+				fmt.Printf("    error: %s\n", result.Error)
+			}
+		}
+		fmt.Println()
+	}
+}
+
+// eventDetail returns the detail and reason of event
+func eventDetail(event journal.Event) string {
+	if event.Detail == "" {
+		return event.Reason
+	}
+	if event.Reason == "" {
+		return event.Detail
+	}
+	return event.Detail + " - " + event.Reason
+}
+
+func init() {
+	historyCmd.Flags().StringSliceVar(&historyTypes, "type", nil, fmt.Sprintf("Only list events of these types (%s)", strings.Join(journal.EventTypes, ", ")))
+	historyCmd.Flags().StringSliceVar(&historyOutcomes, "outcome", nil, fmt.Sprintf("Only list events with these outcomes (%s)", strings.Join(journal.Outcomes, ", ")))
+	historyCmd.Flags().DurationVar(&historySince, "since", 0, "Only list events within this long ago, e.g. 24h")
+	historyCmd.Flags().IntVar(&historyLimit, "limit", 50, "Only list the most recent events - 0 lists all")
+	historyCmd.Flags().BoolVar(&historyJSON, "json", false, "Print events as JSON lines")
+	historyCmd.Flags().BoolVarP(&historyVerbose, "verbose", "v", false, "Print the gossip snapshot and commands run for each event")
+}
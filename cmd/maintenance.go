@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/sol-strategies/solana-validator-ha/internal/api"
+	"github.com/spf13/cobra"
+)
+
+var maintenanceCmd = &cobra.Command{
+	Use:   "maintenance on|off",
+	Short: "Enable or disable maintenance mode on the running HA manager",
+	Long: `Enable or disable maintenance mode on the locally running HA manager. In maintenance mode the
+manager keeps monitoring and exporting metrics but never takes over as active.
+Maintenance mode set with this command does not survive restarts - use failover.maintenance_file for that.`,
+	Args:          cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	ValidArgs:     []string{"on", "off"},
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := groupConfig()
+		if err != nil {
+			return err
+		}
+
+		if cfg.Validator.IsWitness() {
+			return fmt.Errorf("%s is a witness - it never takes over", cfg.Validator.Name)
+		}
+
+		enabled := args[0] == "on"
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		url := fmt.Sprintf("http://127.0.0.1:%d%s", cfg.Prometheus.HealthCheckPort(), cfg.APIPath(api.PathMaintenance))
+		client := api.NewClient(*cfg.Validator.Identities.ActiveKeyPair)
+		response, err := client.Post(ctx, url, cfg.Validator.Name, api.MaintenanceRequest{Enabled: enabled})
+		if err != nil {
+			log.Error("failed to set maintenance mode", "enabled", enabled, "error", err)
+			return err
+		}
+
+		log.Info(response.Message)
+		return nil
+	},
+}
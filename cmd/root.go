@@ -2,6 +2,7 @@ package cmd
 
 import (
 	_ "embed"
+	"fmt"
 	"strings"
 
 	"github.com/charmbracelet/log"
@@ -17,6 +18,7 @@ var version = strings.TrimSpace(strings.Split(versionFile, "\n")[0])
 var (
 	configFile   string
 	logLevel     string
+	groupName    string
 	loadedConfig *config.Config
 )
 
@@ -40,6 +42,15 @@ It monitors peers and manages failover decisions to ensure continuous validator
 	},
 }
 
+// groupConfig returns the config of the group selected with --group, or the loaded config without groups
+func groupConfig() (*config.Config, error) {
+	cfg, err := loadedConfig.GroupConfig(groupName)
+	if err != nil {
+		return nil, fmt.Errorf("--group: %w", err)
+	}
+	return cfg, nil
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() error {
 	return rootCmd.Execute()
@@ -49,7 +60,11 @@ func init() {
 	// Add global flags here
 	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "~/solana-validator-ha/config.yaml", "Path to configuration file (default: ~/solana-validator-ha/config.yaml)")
 	rootCmd.PersistentFlags().StringVarP(&logLevel, "log-level", "l", "", "Log level (debug, info, warn, error, fatal) - overrides config.yaml log.level if specified")
+	rootCmd.PersistentFlags().StringVarP(&groupName, "group", "g", "", "Name of the group in groups to act on - required when more than one group is configured")
 
 	// Add subcommands here
 	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(switchoverCmd)
+	rootCmd.AddCommand(maintenanceCmd)
+	rootCmd.AddCommand(historyCmd)
 }
@@ -1,11 +1,22 @@
 package cmd
 
 import (
+	"context"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/charmbracelet/log"
 	"github.com/sol-strategies/solana-validator-ha/internal/ha"
 	"github.com/spf13/cobra"
 )
 
+// runner is a manager or, with groups, a daemon running a manager per group
+type runner interface {
+	Run() error
+	Stop(ctx context.Context) error
+}
+
 var runCmd = &cobra.Command{
 	Use:           "run",
 	Short:         "Start the Solana validator HA manager",
@@ -13,13 +24,49 @@ var runCmd = &cobra.Command{
 	SilenceUsage:  true,
 	SilenceErrors: true,
 	Run: func(cmd *cobra.Command, args []string) {
-		// Start the HA manager with the loaded config
-		manager := ha.NewManager(ha.NewManagerOptions{
-			Cfg: loadedConfig,
-		})
-		err := manager.Run()
-		if err != nil {
-			log.Fatal("failed to run manager", "error", err)
+		// trap SIGINT/SIGTERM so that in-flight failovers are not killed half-way through
+		signalCtx, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stopSignals()
+
+		// Start the HA manager with the loaded config - or one per group with groups
+		var manager runner
+		if len(loadedConfig.Groups) > 0 {
+			manager = ha.NewDaemon(ha.NewDaemonOptions{
+				Cfg: loadedConfig,
+			})
+		} else {
+			manager = ha.NewManager(ha.NewManagerOptions{
+				Cfg: loadedConfig,
+			})
+		}
+
+		// allow the slowest group to shut down
+		shutdownTimeout := time.Duration(0)
+		for _, cfg := range loadedConfig.GroupConfigs() {
+			shutdownTimeout = max(shutdownTimeout, cfg.Failover.ShutdownTimeoutDuration)
+		}
+
+		runErr := make(chan error, 1)
+		go func() {
+			runErr <- manager.Run()
+		}()
+
+		select {
+		case err := <-runErr:
+			if err != nil {
+				log.Fatal("failed to run manager", "error", err)
+			}
+		case <-signalCtx.Done():
+			log.Info("received shutdown signal - stopping manager", "shutdown_timeout", shutdownTimeout)
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+
+			if err := manager.Stop(shutdownCtx); err != nil {
+				log.Error("failed to stop manager cleanly", "error", err)
+			}
+			if err := <-runErr; err != nil {
+				log.Fatal("failed to run manager", "error", err)
+			}
 		}
 	},
 }
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/sol-strategies/solana-validator-ha/internal/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	switchoverTo      string
+	switchoverTimeout time.Duration
+)
+
+var switchoverCmd = &cobra.Command{
+	Use:   "switchover",
+	Short: "Hand the active role over to a named peer",
+	Long: `Perform a planned switchover of the active role to a named peer for maintenance.
+Must be run on the active node while its HA manager is running. The active node is demoted with its
+passive command, the target peer is promoted with its active command, and the handover is verified
+with getIdentity and gossip. If the target never confirms, the switchover is rolled back.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := groupConfig()
+		if err != nil {
+			return err
+		}
+
+		if cfg.Validator.IsWitness() {
+			return fmt.Errorf("%s is a witness - switchover must be run against the active peer", cfg.Validator.Name)
+		}
+
+		if _, ok := cfg.Failover.Peers[switchoverTo]; !ok {
+			return fmt.Errorf("peer %s not found in failover.peers", switchoverTo)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), switchoverTimeout)
+		defer cancel()
+
+		// ask the local HA manager to perform the switchover
+		url := fmt.Sprintf("http://127.0.0.1:%d%s", cfg.Prometheus.HealthCheckPort(), cfg.APIPath(api.PathSwitchover))
+		log.Info("requesting switchover", "to", switchoverTo, "url", url)
+
+		client := api.NewClient(*cfg.Validator.Identities.ActiveKeyPair)
+		response, err := client.Post(ctx, url, cfg.Validator.Name, api.SwitchoverRequest{To: switchoverTo})
+		if err != nil {
+			log.Error("switchover failed", "to", switchoverTo, "error", err)
+			return err
+		}
+
+		log.Info(response.Message, "to", switchoverTo)
+		return nil
+	},
+}
+
+func init() {
+	switchoverCmd.Flags().StringVar(&switchoverTo, "to", "", "Name of the peer in failover.peers to hand the active role over to")
+	switchoverCmd.Flags().DurationVar(&switchoverTimeout, "timeout", 10*time.Minute, "How long to wait for the switchover to complete")
+	switchoverCmd.MarkFlagRequired("to")
+}
@@ -0,0 +1,293 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	solanago "github.com/gagliardetto/solana-go"
+)
+
+const (
+	// PathSwitchover is the local endpoint that asks the active node to hand over to a named peer
+	PathSwitchover = "/v1/switchover"
+	// PathPromote is the peer endpoint that asks a passive node to run its active command
+	PathPromote = "/v1/promote"
+	// PathDemote is the peer endpoint that asks a node to run its passive command
+	PathDemote = "/v1/demote"
+	// PathMaintenance is the local endpoint that enables or disables maintenance mode
+	PathMaintenance = "/v1/maintenance"
+	// PathVote is the peer endpoint that asks a node to vote for a candidate taking over as active
+	PathVote = "/v1/vote"
+
+	// HeaderTimestamp is the unix timestamp (seconds) the request was signed at
+	HeaderTimestamp = "X-Solana-Validator-HA-Timestamp"
+	// HeaderSignature is the base58 signature of the request made with the shared active identity
+	HeaderSignature = "X-Solana-Validator-HA-Signature"
+	// HeaderTarget is the name of the node the request is for, so that it cannot be replayed to another
+	HeaderTarget = "X-Solana-Validator-HA-Target"
+	// HeaderNonce is a random value unique to the request, so that it cannot be replayed to the same node
+	HeaderNonce = "X-Solana-Validator-HA-Nonce"
+
+	// MaxClockSkew is how far a request timestamp may drift from the receiver's clock
+	MaxClockSkew = 30 * time.Second
+)
+
+// Response is the JSON body returned by all API endpoints
+type Response struct {
+	OK       bool   `json:"ok"`
+	Message  string `json:"message"`
+	Identity string `json:"identity,omitempty"`
+}
+
+// SwitchoverRequest is the JSON body for PathSwitchover
+type SwitchoverRequest struct {
+	To string `json:"to"`
+}
+
+// MaintenanceRequest is the JSON body for PathMaintenance
+type MaintenanceRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+// VoteRequest is the JSON body for PathVote
+type VoteRequest struct {
+	Candidate string `json:"candidate"`
+}
+
+// Client makes signed requests to solana-validator-ha API servers
+type Client struct {
+	httpClient *http.Client
+	signingKey solanago.PrivateKey
+}
+
+// NewClient creates a new API client signing requests with the given key - this must be the
+// shared active identity so that peers can authenticate requests without any other shared secret
+func NewClient(signingKey solanago.PrivateKey) *Client {
+	return &Client{
+		// no client timeout - role commands can take an indeterminate amount of time, callers
+		// bound requests with their context instead
+		httpClient: &http.Client{},
+		signingKey: signingKey,
+	}
+}
+
+// WithTransport makes the client send requests with transport instead of http.DefaultTransport
+func (c *Client) WithTransport(transport http.RoundTripper) *Client {
+	c.httpClient.Transport = transport
+	return c
+}
+
+// Post sends a JSON POST request to url signed for the node named target and decodes the Response
+func (c *Client) Post(ctx context.Context, url, target string, body any) (Response, error) {
+	var response Response
+
+	bodyBytes := []byte{}
+	if body != nil {
+		var err error
+		bodyBytes, err = json.Marshal(body)
+		if err != nil {
+			return response, fmt.Errorf("failed to marshal request body: %w", err)
+		}
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return response, fmt.Errorf("failed to create request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	if err := Sign(request, bodyBytes, c.signingKey, target, time.Now()); err != nil {
+		return response, err
+	}
+
+	httpResponse, err := c.httpClient.Do(request)
+	if err != nil {
+		return response, fmt.Errorf("request to %s failed: %w", url, err)
+	}
+	defer httpResponse.Body.Close()
+
+	if err := json.NewDecoder(httpResponse.Body).Decode(&response); err != nil {
+		return response, fmt.Errorf("failed to decode response from %s (status %d): %w", url, httpResponse.StatusCode, err)
+	}
+
+	if httpResponse.StatusCode != http.StatusOK || !response.OK {
+		return response, fmt.Errorf("request to %s failed with status %d: %s", url, httpResponse.StatusCode, response.Message)
+	}
+
+	return response, nil
+}
+
+// Sign adds the timestamp, target, nonce and signature headers to the request, signing it for the node
+// named target
+func Sign(request *http.Request, body []byte, signingKey solanago.PrivateKey, target string, now time.Time) error {
+	nonceBytes := make([]byte, 16)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		return fmt.Errorf("failed to generate request nonce: %w", err)
+	}
+	nonce := hex.EncodeToString(nonceBytes)
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	signature, err := signingKey.Sign(signingPayload(request.Method, request.URL.Path, target, timestamp, nonce, body))
+	if err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	request.Header.Set(HeaderTimestamp, timestamp)
+	request.Header.Set(HeaderTarget, target)
+	request.Header.Set(HeaderNonce, nonce)
+	request.Header.Set(HeaderSignature, signature.String())
+	return nil
+}
+
+// Verify checks the request was signed by pubkey for the node named target within MaxClockSkew of now.
+// It does not check the nonce has not been seen before - Verifier does
+func Verify(request *http.Request, body []byte, pubkey solanago.PublicKey, target string, now time.Time) error {
+	_, err := verify(request, body, pubkey, target, now)
+	return err
+}
+
+// verify is Verify returning when the request was signed
+func verify(request *http.Request, body []byte, pubkey solanago.PublicKey, target string, now time.Time) (signedAt time.Time, err error) {
+	timestamp := request.Header.Get(HeaderTimestamp)
+	unixSeconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return signedAt, fmt.Errorf("invalid or missing %s header", HeaderTimestamp)
+	}
+	signedAt = time.Unix(unixSeconds, 0)
+
+	skew := now.Sub(signedAt)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > MaxClockSkew {
+		return signedAt, fmt.Errorf("request timestamp is %s away from local clock, max allowed is %s", skew, MaxClockSkew)
+	}
+
+	if requestTarget := request.Header.Get(HeaderTarget); requestTarget != target {
+		return signedAt, fmt.Errorf("request is for %q, not %q", requestTarget, target)
+	}
+
+	nonce := request.Header.Get(HeaderNonce)
+	if nonce == "" {
+		return signedAt, fmt.Errorf("invalid or missing %s header", HeaderNonce)
+	}
+
+	signature, err := solanago.SignatureFromBase58(request.Header.Get(HeaderSignature))
+	if err != nil {
+		return signedAt, fmt.Errorf("invalid or missing %s header", HeaderSignature)
+	}
+
+	if !pubkey.Verify(signingPayload(request.Method, request.URL.Path, target, timestamp, nonce, body), signature) {
+		return signedAt, fmt.Errorf("invalid request signature")
+	}
+
+	return signedAt, nil
+}
+
+// Verifier authenticates requests signed by pubkey for the node named target, accepting each request
+// once only
+type Verifier struct {
+	pubkey solanago.PublicKey
+	target string
+
+	mu sync.Mutex
+	// seenNonces are the nonces of accepted requests by when they can no longer be replayed - once their
+	// timestamps are more than MaxClockSkew old
+	seenNonces map[string]time.Time
+}
+
+// NewVerifier creates a verifier of requests signed by pubkey for the node named target
+func NewVerifier(pubkey solanago.PublicKey, target string) *Verifier {
+	return &Verifier{
+		pubkey:     pubkey,
+		target:     target,
+		seenNonces: make(map[string]time.Time),
+	}
+}
+
+// Verify checks the request was signed by the verifier's pubkey for its target within MaxClockSkew of
+// now, and that its nonce has not been accepted before
+func (v *Verifier) Verify(request *http.Request, body []byte, now time.Time) error {
+	signedAt, err := verify(request, body, v.pubkey, v.target, now)
+	if err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for nonce, expiresAt := range v.seenNonces {
+		if now.After(expiresAt) {
+			delete(v.seenNonces, nonce)
+		}
+	}
+
+	nonce := request.Header.Get(HeaderNonce)
+	if _, seen := v.seenNonces[nonce]; seen {
+		return fmt.Errorf("request has already been received")
+	}
+	v.seenNonces[nonce] = signedAt.Add(MaxClockSkew)
+
+	return nil
+}
+
+// Authenticated wraps handler so that it only receives requests the verifier accepts. The request body
+// is read for verification and handed to handler as bytes
+func (v *Verifier) Authenticated(handler func(http.ResponseWriter, *http.Request, []byte)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			WriteResponse(w, http.StatusMethodNotAllowed, Response{Message: "method not allowed"})
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			WriteResponse(w, http.StatusBadRequest, Response{Message: "failed to read request body"})
+			return
+		}
+
+		if err := v.Verify(r, body, time.Now()); err != nil {
+			WriteResponse(w, http.StatusUnauthorized, Response{Message: err.Error()})
+			return
+		}
+
+		handler(w, r, body)
+	}
+}
+
+// LocalOnly wraps handler so that it only receives requests from the loopback interface, for endpoints
+// only ever called by the CLI on the same host
+func LocalOnly(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if ip := net.ParseIP(host); err != nil || ip == nil || !ip.IsLoopback() {
+			WriteResponse(w, http.StatusForbidden, Response{Message: "only served to local requests"})
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+// WriteResponse writes response as JSON with the given status code
+func WriteResponse(w http.ResponseWriter, status int, response Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// signingPayload returns the bytes signed for a request
+func signingPayload(method, path, target, timestamp, nonce string, body []byte) []byte {
+	payload := fmt.Sprintf("%s\n%s\n%s\n%s\n%s\n", method, path, target, timestamp, nonce)
+	return append([]byte(payload), body...)
+}
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	solanago "github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createTestKey() solanago.PrivateKey {
+	return solanago.NewWallet().PrivateKey
+}
+
+func TestSignAndVerify(t *testing.T) {
+	key := createTestKey()
+	body := []byte(`{"to":"peer1"}`)
+	now := time.Now()
+
+	request := httptest.NewRequest(http.MethodPost, PathSwitchover, nil)
+	err := Sign(request, body, key, "node1", now)
+	require.NoError(t, err)
+	assert.NotEmpty(t, request.Header.Get(HeaderTimestamp))
+	assert.Equal(t, "node1", request.Header.Get(HeaderTarget))
+	assert.NotEmpty(t, request.Header.Get(HeaderNonce))
+	assert.NotEmpty(t, request.Header.Get(HeaderSignature))
+
+	// Test with valid signature
+	err = Verify(request, body, key.PublicKey(), "node1", now)
+	assert.NoError(t, err)
+
+	// Test with tampered body
+	err = Verify(request, []byte(`{"to":"peer2"}`), key.PublicKey(), "node1", now)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid request signature")
+
+	// Test with different pubkey
+	err = Verify(request, body, createTestKey().PublicKey(), "node1", now)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid request signature")
+
+	// Test with different path
+	otherRequest := httptest.NewRequest(http.MethodPost, PathPromote, nil)
+	otherRequest.Header = request.Header
+	err = Verify(otherRequest, body, key.PublicKey(), "node1", now)
+	assert.Error(t, err)
+
+	// Test replayed to another node
+	err = Verify(request, body, key.PublicKey(), "node2", now)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `request is for "node1", not "node2"`)
+
+	// Test with the target header rewritten for another node
+	otherRequest = httptest.NewRequest(http.MethodPost, PathSwitchover, nil)
+	otherRequest.Header = request.Header.Clone()
+	otherRequest.Header.Set(HeaderTarget, "node2")
+	err = Verify(otherRequest, body, key.PublicKey(), "node2", now)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid request signature")
+
+	// Test with tampered nonce
+	otherRequest.Header = request.Header.Clone()
+	otherRequest.Header.Set(HeaderNonce, "00")
+	err = Verify(otherRequest, body, key.PublicKey(), "node1", now)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid request signature")
+
+	// Test with stale timestamp
+	err = Verify(request, body, key.PublicKey(), "node1", now.Add(MaxClockSkew+time.Second))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "away from local clock")
+}
+
+func TestVerify_MissingHeaders(t *testing.T) {
+	key := createTestKey()
+	request := httptest.NewRequest(http.MethodPost, PathPromote, nil)
+
+	err := Verify(request, nil, key.PublicKey(), "node1", time.Now())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), HeaderTimestamp)
+
+	request.Header.Set(HeaderTimestamp, "1")
+	request.Header.Set(HeaderTarget, "node1")
+	err = Verify(request, nil, key.PublicKey(), "node1", time.Unix(1, 0))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), HeaderNonce)
+
+	request.Header.Set(HeaderNonce, "00")
+	err = Verify(request, nil, key.PublicKey(), "node1", time.Unix(1, 0))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), HeaderSignature)
+}
+
+func TestVerifier_RejectsReplays(t *testing.T) {
+	key := createTestKey()
+	verifier := NewVerifier(key.PublicKey(), "node1")
+	now := time.Now()
+
+	request := httptest.NewRequest(http.MethodPost, PathPromote, nil)
+	require.NoError(t, Sign(request, nil, key, "node1", now))
+	require.NoError(t, verifier.Verify(request, nil, now))
+
+	// the same request is refused however soon it is replayed
+	err := verifier.Verify(request, nil, now.Add(time.Second))
+	assert.ErrorContains(t, err, "already been received")
+
+	// requests signed afresh are accepted
+	otherRequest := httptest.NewRequest(http.MethodPost, PathPromote, nil)
+	require.NoError(t, Sign(otherRequest, nil, key, "node1", now))
+	assert.NoError(t, verifier.Verify(otherRequest, nil, now))
+
+	// once a request is too old to be accepted anyway its nonce is forgotten
+	later := now.Add(MaxClockSkew + 2*time.Second)
+	laterRequest := httptest.NewRequest(http.MethodPost, PathPromote, nil)
+	require.NoError(t, Sign(laterRequest, nil, key, "node1", later))
+	require.NoError(t, verifier.Verify(laterRequest, nil, later))
+	assert.Len(t, verifier.seenNonces, 1)
+}
+
+func TestLocalOnly(t *testing.T) {
+	handler := LocalOnly(func(w http.ResponseWriter, r *http.Request) {
+		WriteResponse(w, http.StatusOK, Response{OK: true})
+	})
+
+	for remoteAddr, expected := range map[string]int{
+		"127.0.0.1:1234":   http.StatusOK,
+		"[::1]:1234":       http.StatusOK,
+		"192.168.1.2:1234": http.StatusForbidden,
+		"invalid":          http.StatusForbidden,
+	} {
+		request := httptest.NewRequest(http.MethodPost, PathMaintenance, nil)
+		request.RemoteAddr = remoteAddr
+		recorder := httptest.NewRecorder()
+		handler(recorder, request)
+		assert.Equal(t, expected, recorder.Code, remoteAddr)
+	}
+}
+
+func TestAuthenticated(t *testing.T) {
+	key := createTestKey()
+	var receivedBody []byte
+
+	server := httptest.NewServer(NewVerifier(key.PublicKey(), "node1").Authenticated(func(w http.ResponseWriter, r *http.Request, body []byte) {
+		receivedBody = body
+		WriteResponse(w, http.StatusOK, Response{OK: true, Message: "done", Identity: "identity"})
+	}))
+	defer server.Close()
+
+	// Test with signed request
+	response, err := NewClient(key).Post(context.Background(), server.URL+PathSwitchover, "node1", SwitchoverRequest{To: "peer1"})
+	require.NoError(t, err)
+	assert.True(t, response.OK)
+	assert.Equal(t, "identity", response.Identity)
+
+	var request SwitchoverRequest
+	require.NoError(t, json.Unmarshal(receivedBody, &request))
+	assert.Equal(t, "peer1", request.To)
+
+	// Test with request signed by another key
+	_, err = NewClient(createTestKey()).Post(context.Background(), server.URL+PathSwitchover, "node1", nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+
+	// Test with request signed for another node
+	_, err = NewClient(key).Post(context.Background(), server.URL+PathSwitchover, "node2", nil)
+	assert.ErrorContains(t, err, "401")
+
+	// Test with unsigned GET
+	httpResponse, err := http.Get(server.URL + PathSwitchover)
+	require.NoError(t, err)
+	httpResponse.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, httpResponse.StatusCode)
+}
+
+func TestClient_Post_NotOK(t *testing.T) {
+	key := createTestKey()
+
+	server := httptest.NewServer(NewVerifier(key.PublicKey(), "node1").Authenticated(func(w http.ResponseWriter, r *http.Request, body []byte) {
+		WriteResponse(w, http.StatusConflict, Response{Message: "we are not active"})
+	}))
+	defer server.Close()
+
+	response, err := NewClient(key).Post(context.Background(), server.URL+PathSwitchover, "node1", nil)
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "we are not active"))
+	assert.False(t, response.OK)
+}
@@ -19,12 +19,57 @@ type State struct {
 	SelfInGossip bool
 
 	// Failover status
-	FailoverStatus string // "idle", "becoming_active", "becoming_passive"
+	FailoverStatus  string // "idle", "becoming_active", "becoming_passive"
+	MaintenanceMode bool   // true when takeover is suppressed
+
+	// SplitBrainDetected is true when more than one node claims the active identity
+	SplitBrainDetected bool
+
+	// Flap damping
+	RoleTransitions           int  // role changes within failover.transition_window_duration
+	RoleTransitionsTotal      int  // role changes ever, persisted across restarts
+	TransitionBudgetExhausted bool // true when failover.max_transitions is reached
+
+	// Leader schedule
+	LeaderSlotsAtRisk     int  // active identity leader slots within failover.leader_schedule_lookahead_slots
+	LeaderScheduleWaiting bool // true while a planned transition waits for a window without leader slots
+
+	// DoubleVoteGuardTripped is true when the last takeover was aborted because the active identity was still voting
+	DoubleVoteGuardTripped bool
+
+	// Cluster RPC consensus - only set with cluster.rpc_mode consensus
+	ClusterRPCLeaderless   map[string]bool // whether each endpoint that answered showed the cluster leaderless
+	ClusterRPCDisagreement bool            // true when endpoints disagreed on the active peer at the last sample
+
+	// PeerVoteLagSlots are how far behind the cluster each peer presenting the active identity last voted
+	PeerVoteLagSlots map[string]uint64
+	// PeerStats are the statistics derived from each config peer's recent gossip samples, by peer name
+	PeerStats map[string]PeerStats
+	// PeerUnexpectedIdentities are how many nodes at each config peer's addresses presented neither its pubkey
+	// nor the active identity at the last gossip refresh, by peer name
+	PeerUnexpectedIdentities map[string]int
+
+	// AccountBalances are the last sampled balances of the accounts validator.balance_monitor watches, by account
+	AccountBalances map[string]AccountBalance
 
 	// Timestamps
 	LastUpdated time.Time
 }
 
+// AccountBalance is the last sampled balance of a monitored account
+type AccountBalance struct {
+	Pubkey   string
+	Lamports uint64
+	Level    string // "ok", "warn", "critical"
+}
+
+// PeerStats are the statistics derived from a peer's recent gossip samples
+type PeerStats struct {
+	AvailabilityPercent float64
+	TransitionsPerHour  float64
+	Flapping            bool // true when the peer's availability changed failover.peer_history.flap_transitions_threshold times
+}
+
 // Cache provides thread-safe access to the HA manager state
 type Cache struct {
 	mu    sync.RWMutex
@@ -0,0 +1,60 @@
+package clock
+
+import (
+	"context"
+	"time"
+)
+
+// Clock tells the time and waits - the HA manager uses it instead of the time package so that
+// simulations can run it on virtual time
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// Since returns the time elapsed since t
+	Since(t time.Time) time.Duration
+	// After waits for d to elapse and then sends the current time on the returned channel
+	After(d time.Duration) <-chan time.Time
+	// Sleep pauses for at least d
+	Sleep(d time.Duration)
+	// NewTicker returns a ticker that ticks every d
+	NewTicker(d time.Duration) Ticker
+	// WithTimeout returns a copy of ctx that is cancelled once d has elapsed
+	WithTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc)
+}
+
+// Ticker delivers ticks at intervals
+type Ticker interface {
+	// C returns the channel ticks are delivered on
+	C() <-chan time.Time
+	// Stop turns off the ticker
+	Stop()
+}
+
+// New returns a Clock backed by the time package
+func New() Clock {
+	return realClock{}
+}
+
+// realClock is a Clock backed by the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+func (realClock) WithTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, d)
+}
+
+// realTicker is a Ticker backed by time.Ticker
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time { return t.ticker.C }
+func (t realTicker) Stop()               { t.ticker.Stop() }
@@ -0,0 +1,121 @@
+package clock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var start = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestNew(t *testing.T) {
+	c := New()
+	before := time.Now()
+	assert.False(t, c.Now().Before(before))
+
+	ctx, cancel := c.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+	assert.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+}
+
+func TestVirtual_SleepAdvances(t *testing.T) {
+	v := NewVirtual(start)
+	v.Sleep(3 * time.Second)
+	assert.Equal(t, start.Add(3*time.Second), v.Now())
+	assert.Equal(t, 3*time.Second, v.Since(start))
+
+	// never moves backwards
+	v.AdvanceTo(start)
+	assert.Equal(t, start.Add(3*time.Second), v.Now())
+}
+
+func TestVirtual_After(t *testing.T) {
+	v := NewVirtual(start)
+
+	select {
+	case now := <-v.After(5 * time.Second):
+		assert.Equal(t, start.Add(5*time.Second), now)
+	default:
+		t.Fatal("After should be ready once it returns")
+	}
+	assert.Equal(t, start.Add(5*time.Second), v.Now())
+}
+
+func TestVirtual_WithTimeout(t *testing.T) {
+	v := NewVirtual(start)
+	ctx, cancel := v.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.Equal(t, start.Add(10*time.Second), deadline)
+
+	v.Advance(9 * time.Second)
+	assert.NoError(t, ctx.Err())
+
+	v.Advance(time.Second)
+	assert.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+}
+
+func TestVirtual_AfterStopsAtTimeout(t *testing.T) {
+	v := NewVirtual(start)
+	ctx, cancel := v.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	// a poll loop waiting on both only ever sees the timeout, even when they fall due together
+	polls := 0
+	for {
+		select {
+		case <-ctx.Done():
+			assert.Equal(t, 1, polls)
+			assert.Equal(t, start.Add(3*time.Second), v.Now())
+			return
+		case <-v.After(time.Second + 500*time.Millisecond):
+			polls++
+		}
+	}
+}
+
+func TestVirtual_CancelledTimeoutDoesNotStopAfter(t *testing.T) {
+	v := NewVirtual(start)
+	_, cancel := v.WithTimeout(context.Background(), time.Second)
+	cancel()
+
+	<-v.After(5 * time.Second)
+	assert.Equal(t, start.Add(5*time.Second), v.Now())
+}
+
+func TestVirtual_Ticker(t *testing.T) {
+	v := NewVirtual(start)
+	ticker := v.NewTicker(5 * time.Second)
+
+	v.Advance(4 * time.Second)
+	select {
+	case <-ticker.C():
+		t.Fatal("ticked early")
+	default:
+	}
+
+	v.Advance(time.Second)
+	assert.Equal(t, start.Add(5*time.Second), <-ticker.C())
+
+	// ticks not received are dropped
+	v.Advance(20 * time.Second)
+	assert.Equal(t, start.Add(10*time.Second), <-ticker.C())
+	select {
+	case <-ticker.C():
+		t.Fatal("missed ticks should be dropped")
+	default:
+	}
+
+	ticker.Stop()
+	v.Advance(time.Minute)
+	select {
+	case <-ticker.C():
+		t.Fatal("stopped ticker ticked")
+	default:
+	}
+}
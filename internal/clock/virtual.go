@@ -0,0 +1,229 @@
+package clock
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Virtual is a Clock whose time only moves when it is advanced, for deterministic simulations driven
+// from a single goroutine. Waiting is time passing: Sleep and After advance the clock themselves rather
+// than blocking, firing any timers, tickers and timeouts that fall due on the way in the order they fall
+// due. A context timeout falling due before an After timer stops the advance short of the timer, so that
+// a select on both only ever has the timeout ready
+type Virtual struct {
+	mu     sync.Mutex
+	now    time.Time
+	seq    uint64
+	timers []*virtualTimer
+}
+
+// virtualTimer is something to fire once the clock reaches at
+type virtualTimer struct {
+	at       time.Time
+	seq      uint64
+	deadline bool
+	stopped  atomic.Bool
+	fire     func(now time.Time)
+}
+
+// NewVirtual returns a Virtual clock starting at start
+func NewVirtual(start time.Time) *Virtual {
+	return &Virtual{now: start}
+}
+
+// Now returns the current virtual time
+func (v *Virtual) Now() time.Time {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.now
+}
+
+// Since returns the virtual time elapsed since t
+func (v *Virtual) Since(t time.Time) time.Duration {
+	return v.Now().Sub(t)
+}
+
+// Sleep advances the clock by d
+func (v *Virtual) Sleep(d time.Duration) {
+	v.Advance(d)
+}
+
+// After advances the clock by d, or only as far as the earliest pending context timeout within d, and
+// returns a channel the time is sent on once the clock reaches d from now
+func (v *Virtual) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	fire := func(now time.Time) { ch <- now }
+
+	v.mu.Lock()
+	target := v.now.Add(d)
+	var timeout *virtualTimer
+	for _, timer := range v.timers {
+		if timer.deadline && !timer.stopped.Load() && !timer.at.After(target) && (timeout == nil || timer.at.Before(timeout.at)) {
+			timeout = timer
+		}
+	}
+
+	// no timeout within d - the time is ready as soon as we return
+	if timeout == nil {
+		v.addTimer(target, false, fire)
+		v.mu.Unlock()
+		v.AdvanceTo(target)
+		return ch
+	}
+	v.mu.Unlock()
+
+	// the timeout falls due first (or at the same time) so the time is not ready yet
+	v.AdvanceTo(timeout.at)
+	v.mu.Lock()
+	v.addTimer(target, false, fire)
+	v.mu.Unlock()
+	return ch
+}
+
+// NewTicker returns a ticker that ticks every d of virtual time - like time.Ticker, ticks are dropped
+// if the previous one has not been received
+func (v *Virtual) NewTicker(d time.Duration) Ticker {
+	ticker := &virtualTicker{clock: v, ch: make(chan time.Time, 1)}
+
+	var tick func(now time.Time)
+	tick = func(now time.Time) {
+		if ticker.stopped.Load() {
+			return
+		}
+		select {
+		case ticker.ch <- now:
+		default:
+		}
+		v.mu.Lock()
+		ticker.timer = v.addTimer(now.Add(d), false, tick)
+		v.mu.Unlock()
+	}
+
+	v.mu.Lock()
+	ticker.timer = v.addTimer(v.now.Add(d), false, tick)
+	v.mu.Unlock()
+
+	return ticker
+}
+
+// WithTimeout returns a copy of ctx that is cancelled with context.DeadlineExceeded once the clock has
+// been advanced by d
+func (v *Virtual) WithTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	cancelCtx, cancel := context.WithCancel(ctx)
+	timeoutCtx := &virtualTimeoutCtx{Context: cancelCtx}
+
+	v.mu.Lock()
+	timeoutCtx.deadline = v.now.Add(d)
+	timer := v.addTimer(timeoutCtx.deadline, true, func(time.Time) {
+		timeoutCtx.timedOut.Store(true)
+		cancel()
+	})
+	v.mu.Unlock()
+
+	return timeoutCtx, func() {
+		timer.stopped.Store(true)
+		cancel()
+	}
+}
+
+// Advance moves the clock forward by d, firing everything that falls due on the way
+func (v *Virtual) Advance(d time.Duration) {
+	v.AdvanceTo(v.Now().Add(d))
+}
+
+// AdvanceTo moves the clock forward to t, firing everything that falls due on the way - the clock
+// never moves backwards
+func (v *Virtual) AdvanceTo(t time.Time) {
+	for {
+		v.mu.Lock()
+		timer := v.nextTimer(t)
+		if timer == nil {
+			if t.After(v.now) {
+				v.now = t
+			}
+			v.mu.Unlock()
+			return
+		}
+		if timer.at.After(v.now) {
+			v.now = timer.at
+		}
+		now := v.now
+		v.mu.Unlock()
+
+		timer.fire(now)
+	}
+}
+
+// addTimer schedules fire for at - callers must hold mu
+func (v *Virtual) addTimer(at time.Time, deadline bool, fire func(now time.Time)) *virtualTimer {
+	v.seq++
+	timer := &virtualTimer{at: at, seq: v.seq, deadline: deadline, fire: fire}
+	v.timers = append(v.timers, timer)
+	return timer
+}
+
+// nextTimer removes and returns the earliest timer due at or before t, dropping stopped timers - callers
+// must hold mu
+func (v *Virtual) nextTimer(t time.Time) *virtualTimer {
+	live := v.timers[:0]
+	for _, timer := range v.timers {
+		if !timer.stopped.Load() {
+			live = append(live, timer)
+		}
+	}
+	v.timers = live
+
+	sort.Slice(v.timers, func(i, j int) bool {
+		if !v.timers[i].at.Equal(v.timers[j].at) {
+			return v.timers[i].at.Before(v.timers[j].at)
+		}
+		return v.timers[i].seq < v.timers[j].seq
+	})
+
+	if len(v.timers) == 0 || v.timers[0].at.After(t) {
+		return nil
+	}
+
+	timer := v.timers[0]
+	v.timers = v.timers[1:]
+	return timer
+}
+
+// virtualTicker is a Ticker on a Virtual clock
+type virtualTicker struct {
+	clock   *Virtual
+	ch      chan time.Time
+	timer   *virtualTimer
+	stopped atomic.Bool
+}
+
+func (t *virtualTicker) C() <-chan time.Time { return t.ch }
+
+func (t *virtualTicker) Stop() {
+	t.stopped.Store(true)
+
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.timer.stopped.Store(true)
+}
+
+// virtualTimeoutCtx is a context cancelled by a Virtual clock timeout
+type virtualTimeoutCtx struct {
+	context.Context
+	deadline time.Time
+	timedOut atomic.Bool
+}
+
+func (c *virtualTimeoutCtx) Deadline() (time.Time, bool) {
+	return c.deadline, true
+}
+
+func (c *virtualTimeoutCtx) Err() error {
+	if c.timedOut.Load() {
+		return context.DeadlineExceeded
+	}
+	return c.Context.Err()
+}
@@ -2,6 +2,8 @@ package command
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os/exec"
@@ -18,6 +20,8 @@ var (
 
 // RunOptions are the options for running a command
 type RunOptions struct {
+	// Ctx cancels the command when done - defaults to context.Background() when nil
+	Ctx          context.Context
 	Name         string
 	Command      string
 	Args         []string
@@ -28,9 +32,24 @@ type RunOptions struct {
 	LoggerArgs   []any
 }
 
+// Runner runs commands - role commands and hooks are run through it so that simulations can run them
+// in process
+type Runner interface {
+	Run(opts RunOptions) error
+}
+
+// ExecRunner is a Runner that runs commands as processes with Run
+type ExecRunner struct{}
+
+// Run runs a command as a process
+func (ExecRunner) Run(opts RunOptions) error {
+	return Run(opts)
+}
+
 // Run runs a command with the given options.
 // Note: This function never times out - commands can take an indeterminate amount of time
-// (e.g., failover commands that may need to wait for services to start/stop).
+// (e.g., failover commands that may need to wait for services to start/stop). The command
+// is only killed if opts.Ctx is cancelled, which is reserved for shutting down.
 func Run(opts RunOptions) error {
 	logger := log.WithPrefix(fmt.Sprintf("[%s command %s]", opts.LoggerPrefix, opts.Name))
 	envString := ""
@@ -47,7 +66,12 @@ func Run(opts RunOptions) error {
 		return nil
 	}
 
-	cmd := exec.Command(opts.Command, opts.Args...)
+	ctx := opts.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	cmd := exec.CommandContext(ctx, opts.Command, opts.Args...)
 
 	// Set environment variables if provided
 	if len(opts.Env) > 0 {
@@ -161,6 +185,21 @@ func runWithoutStreaming(cmd *exec.Cmd, logger *log.Logger) error {
 	return nil
 }
 
+// ExitCode returns the exit code of the command that returned err - 0 if err is nil and -1 if the
+// command did not run to completion
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+
+	return -1
+}
+
 // styledStreamOutputString creates a styled string for stream output
 func styledStreamOutputString(stream string, text string) string {
 	streamStyle := stdoutStyle
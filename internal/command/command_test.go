@@ -1,6 +1,7 @@
 package command
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -404,6 +405,41 @@ func TestRun_CommandWithTimeout(t *testing.T) {
 	}
 }
 
+func TestRun_WithCancelledContext(t *testing.T) {
+	// Create a test script that runs for longer than the test is willing to wait
+	scriptPath := createTestScript(t, "sleep 10", 0)
+	defer os.Remove(scriptPath)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	opts := RunOptions{
+		Ctx:          ctx,
+		Command:      scriptPath,
+		Args:         []string{},
+		DryRun:       false,
+		StreamOutput: true,
+		LoggerArgs: []any{
+			"test", "cancelled_context",
+		},
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(opts)
+	}()
+
+	// Cancel the context while the command is running
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.Error(t, err, "expected command to be killed when context is cancelled")
+	case <-time.After(5 * time.Second):
+		t.Error("command was not cancelled with its context")
+	}
+}
+
 func TestRun_CommandWithComplexArgs(t *testing.T) {
 	// Create a test script that handles complex arguments
 	scriptContent := `echo "arg1: '$1'"; echo "arg2: '$2'"; echo "arg3: '$3'"`
@@ -625,3 +661,15 @@ echo "PATH: $PATH"
 	err := Run(opts)
 	assert.NoError(t, err, "expected command with empty env vars to succeed")
 }
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, 0, ExitCode(nil))
+
+	scriptPath := createTestScript(t, "exit 3", 3)
+	err := Run(RunOptions{Command: scriptPath, StreamOutput: true})
+	assert.Equal(t, 3, ExitCode(err))
+	assert.Equal(t, 3, ExitCode(fmt.Errorf("failed to run command: %w", err)), "wrapped errors keep their exit code")
+
+	err = Run(RunOptions{Command: "/nonexistent/command"})
+	assert.Equal(t, -1, ExitCode(err))
+}
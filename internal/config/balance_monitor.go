@@ -0,0 +1,122 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	solanago "github.com/gagliardetto/solana-go"
+)
+
+// BalanceMonitor is how the balances of the validator's identities and vote account are watched, so that low
+// funds are noticed before they cause delinquency
+type BalanceMonitor struct {
+	PollIntervalDuration time.Duration `koanf:"poll_interval_duration"`
+	// IdentityWarnSOL and IdentityCriticalSOL apply to the active identity, which pays the vote fees
+	IdentityWarnSOL     float64 `koanf:"identity_warn_sol"`
+	IdentityCriticalSOL float64 `koanf:"identity_critical_sol"`
+	// VoteAccountWarnSOL and VoteAccountCriticalSOL apply to the active identity's vote account - which is
+	// critical below its rent-exempt minimum regardless
+	VoteAccountWarnSOL     float64 `koanf:"vote_account_warn_sol"`
+	VoteAccountCriticalSOL float64 `koanf:"vote_account_critical_sol"`
+	// PassiveIdentityWarnSOL and PassiveIdentityCriticalSOL apply to the passive identity - it pays no vote fees,
+	// so they are off by default for those that fund it to catch it being drained
+	PassiveIdentityWarnSOL     float64 `koanf:"passive_identity_warn_sol"`
+	PassiveIdentityCriticalSOL float64 `koanf:"passive_identity_critical_sol"`
+	// Hooks run whenever an account's balance level changes
+	Hooks []Hook `koanf:"hooks"`
+}
+
+// BalanceTemplateData represents data available for balance monitor hook templates
+type BalanceTemplateData struct {
+	Account       string
+	Pubkey        string
+	Level         string
+	PreviousLevel string
+	BalanceSOL    string
+	ThresholdSOL  string
+}
+
+// Validate validates the balance monitor configuration
+func (b *BalanceMonitor) Validate() error {
+	// validator.balance_monitor.poll_interval_duration must not be negative
+	if b.PollIntervalDuration < 0 {
+		return fmt.Errorf("validator.balance_monitor.poll_interval_duration must not be negative")
+	}
+
+	// validator.balance_monitor thresholds must not be negative, and critical must not be above warn
+	thresholds := []struct {
+		name           string
+		warn, critical float64
+	}{
+		{name: "identity", warn: b.IdentityWarnSOL, critical: b.IdentityCriticalSOL},
+		{name: "vote_account", warn: b.VoteAccountWarnSOL, critical: b.VoteAccountCriticalSOL},
+		{name: "passive_identity", warn: b.PassiveIdentityWarnSOL, critical: b.PassiveIdentityCriticalSOL},
+	}
+	for _, threshold := range thresholds {
+		if threshold.warn < 0 || threshold.critical < 0 {
+			return fmt.Errorf("validator.balance_monitor.%s_warn_sol and %s_critical_sol must not be negative", threshold.name, threshold.name)
+		}
+		if threshold.warn > 0 && threshold.critical > threshold.warn {
+			return fmt.Errorf("validator.balance_monitor.%s_critical_sol must not be above %s_warn_sol", threshold.name, threshold.name)
+		}
+	}
+
+	// validator.balance_monitor.hooks must all be valid - they cannot hold anything up so must not must_succeed
+	for i, hook := range b.Hooks {
+		if err := hook.Validate(false); err != nil {
+			return fmt.Errorf("validator.balance_monitor.hooks[%d]: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// SetDefaults sets default values for the balance monitor configuration
+func (b *BalanceMonitor) SetDefaults() {
+	if b.PollIntervalDuration == 0 {
+		b.PollIntervalDuration = time.Minute
+	}
+	if b.IdentityWarnSOL == 0 {
+		b.IdentityWarnSOL = 5 // a few days of vote fees
+	}
+	if b.IdentityCriticalSOL == 0 {
+		b.IdentityCriticalSOL = 1 // about a day of vote fees
+	}
+}
+
+// SOLToLamports converts sol to lamports
+func SOLToLamports(sol float64) uint64 {
+	return uint64(sol * float64(solanago.LAMPORTS_PER_SOL))
+}
+
+// RenderBalanceHook returns a copy of hook with its command and args rendered with data
+func RenderBalanceHook(hook Hook, data BalanceTemplateData) (rendered Hook, err error) {
+	rendered = hook
+
+	render := func(field, templateStr string) (string, error) {
+		tmpl, err := template.New(field).Parse(templateStr)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse %s template: %w", field, err)
+		}
+		var buf strings.Builder
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("failed to execute %s template: %w", field, err)
+		}
+		return buf.String(), nil
+	}
+
+	if rendered.Command, err = render("command", hook.Command); err != nil {
+		return rendered, err
+	}
+
+	rendered.Args = make([]string, len(hook.Args))
+	for i, arg := range hook.Args {
+		if rendered.Args[i], err = render(fmt.Sprintf("args[%d]", i), arg); err != nil {
+			return rendered, err
+		}
+	}
+
+	return rendered, nil
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBalanceMonitor_SetDefaults(t *testing.T) {
+	balanceMonitor := &BalanceMonitor{}
+	balanceMonitor.SetDefaults()
+
+	assert.Equal(t, time.Minute, balanceMonitor.PollIntervalDuration)
+	assert.Equal(t, float64(5), balanceMonitor.IdentityWarnSOL)
+	assert.Equal(t, float64(1), balanceMonitor.IdentityCriticalSOL)
+	assert.Zero(t, balanceMonitor.VoteAccountWarnSOL)
+	assert.Zero(t, balanceMonitor.VoteAccountCriticalSOL)
+	assert.Zero(t, balanceMonitor.PassiveIdentityWarnSOL)
+	assert.Zero(t, balanceMonitor.PassiveIdentityCriticalSOL)
+
+	balanceMonitor = &BalanceMonitor{PollIntervalDuration: 5 * time.Minute, IdentityWarnSOL: 10, IdentityCriticalSOL: 2}
+	balanceMonitor.SetDefaults()
+
+	assert.Equal(t, 5*time.Minute, balanceMonitor.PollIntervalDuration)
+	assert.Equal(t, float64(10), balanceMonitor.IdentityWarnSOL)
+	assert.Equal(t, float64(2), balanceMonitor.IdentityCriticalSOL)
+}
+
+func TestBalanceMonitor_Validate(t *testing.T) {
+	tests := []struct {
+		name           string
+		balanceMonitor BalanceMonitor
+		wantErr        string
+	}{
+		{
+			name: "valid",
+			balanceMonitor: BalanceMonitor{
+				IdentityWarnSOL:        5,
+				IdentityCriticalSOL:    1,
+				VoteAccountWarnSOL:     0.1,
+				VoteAccountCriticalSOL: 0.05,
+				PassiveIdentityWarnSOL: 0.5,
+				Hooks:                  []Hook{{Name: "alert", Command: "alert"}},
+			},
+		},
+		{
+			name:           "negative poll interval",
+			balanceMonitor: BalanceMonitor{PollIntervalDuration: -time.Second},
+			wantErr:        "validator.balance_monitor.poll_interval_duration must not be negative",
+		},
+		{
+			name:           "negative threshold",
+			balanceMonitor: BalanceMonitor{VoteAccountCriticalSOL: -1},
+			wantErr:        "validator.balance_monitor.vote_account_warn_sol and vote_account_critical_sol must not be negative",
+		},
+		{
+			name:           "critical above warn",
+			balanceMonitor: BalanceMonitor{IdentityWarnSOL: 1, IdentityCriticalSOL: 5},
+			wantErr:        "validator.balance_monitor.identity_critical_sol must not be above identity_warn_sol",
+		},
+		{
+			name:           "passive identity critical above warn",
+			balanceMonitor: BalanceMonitor{PassiveIdentityWarnSOL: 0.1, PassiveIdentityCriticalSOL: 0.5},
+			wantErr:        "validator.balance_monitor.passive_identity_critical_sol must not be above passive_identity_warn_sol",
+		},
+		{
+			name:           "hook must succeed",
+			balanceMonitor: BalanceMonitor{Hooks: []Hook{{Name: "alert", Command: "alert", MustSucceed: true}}},
+			wantErr:        "validator.balance_monitor.hooks[0]: hook must_succeed not allowed for post hooks",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.balanceMonitor.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestSOLToLamports(t *testing.T) {
+	assert.Equal(t, uint64(1_000_000_000), SOLToLamports(1))
+	assert.Equal(t, uint64(50_000_000), SOLToLamports(0.05))
+	assert.Zero(t, SOLToLamports(0))
+}
+
+func TestRenderBalanceHook(t *testing.T) {
+	hook := Hook{
+		Name:    "alert",
+		Command: "/usr/local/bin/alert-{{ .Level }}",
+		Args:    []string{"--account={{ .Account }}", "--pubkey={{ .Pubkey }}", "{{ .PreviousLevel }} -> {{ .Level }}: {{ .BalanceSOL }} < {{ .ThresholdSOL }}"},
+	}
+
+	rendered, err := RenderBalanceHook(hook, BalanceTemplateData{
+		Account:       "active_identity",
+		Pubkey:        "pubkey",
+		Level:         "warn",
+		PreviousLevel: "ok",
+		BalanceSOL:    "3",
+		ThresholdSOL:  "5",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "/usr/local/bin/alert-warn", rendered.Command)
+	assert.Equal(t, []string{"--account=active_identity", "--pubkey=pubkey", "ok -> warn: 3 < 5"}, rendered.Args)
+	// the hook itself is left unrendered for the next level change
+	assert.Equal(t, "/usr/local/bin/alert-{{ .Level }}", hook.Command)
+
+	_, err = RenderBalanceHook(Hook{Command: "{{ .Unknown }}"}, BalanceTemplateData{})
+	assert.ErrorContains(t, err, "failed to execute command template")
+}
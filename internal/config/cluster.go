@@ -9,10 +9,25 @@ import (
 	solanagorpc "github.com/gagliardetto/solana-go/rpc"
 )
 
+const (
+	// ClusterRPCModeFirst uses the first of cluster.rpc_urls to answer
+	ClusterRPCModeFirst = "first"
+	// ClusterRPCModeConsensus gets gossip from all of cluster.rpc_urls and only counts a sample as leaderless when
+	// cluster.rpc_quorum of them agree
+	ClusterRPCModeConsensus = "consensus"
+)
+
+var validClusterRPCModes = []string{
+	ClusterRPCModeFirst,
+	ClusterRPCModeConsensus,
+}
+
 // Cluster represents the Solana cluster configuration
 type Cluster struct {
-	Name    string   `koanf:"name"`
-	RPCURLs []string `koanf:"rpc_urls"`
+	Name      string   `koanf:"name"`
+	RPCURLs   []string `koanf:"rpc_urls"`
+	RPCMode   string   `koanf:"rpc_mode"`
+	RPCQuorum int      `koanf:"rpc_quorum"`
 }
 
 // Validate validates the cluster configuration
@@ -45,9 +60,28 @@ func (c *Cluster) Validate() error {
 		}
 	}
 
+	// cluster.rpc_mode must be a known mode if set
+	if c.RPCMode != "" && !slices.Contains(validClusterRPCModes, c.RPCMode) {
+		return fmt.Errorf("cluster.rpc_mode must be one of %s", strings.Join(validClusterRPCModes, ", "))
+	}
+
+	// cluster.rpc_quorum must be attainable
+	if c.RPCQuorum < 0 || c.RPCQuorum > len(c.RPCURLs) {
+		return fmt.Errorf("cluster.rpc_quorum must be between 1 and the number of cluster.rpc_urls (%d)", len(c.RPCURLs))
+	}
+
 	return nil
 }
 
+// LeaderlessRPCQuorum returns how many cluster RPC endpoints must agree for a sample to count as leaderless, or zero
+// if it is decided by the first to answer
+func (c *Cluster) LeaderlessRPCQuorum() int {
+	if c.RPCMode != ClusterRPCModeConsensus {
+		return 0
+	}
+	return c.RPCQuorum
+}
+
 // SetDefaults sets default values for the cluster configuration
 func (c *Cluster) SetDefaults() {
 	// if cluster.rpc_urls is empty, set it to the default RPC URLs for the cluster
@@ -61,4 +95,11 @@ func (c *Cluster) SetDefaults() {
 			c.RPCURLs = []string{solanagorpc.DevNet.RPC}
 		}
 	}
+	if c.RPCMode == "" {
+		c.RPCMode = ClusterRPCModeFirst
+	}
+	// a majority of cluster.rpc_urls by default
+	if c.RPCQuorum == 0 {
+		c.RPCQuorum = len(c.RPCURLs)/2 + 1
+	}
 }
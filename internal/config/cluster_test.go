@@ -31,6 +31,26 @@ func TestCluster_SetDefaults(t *testing.T) {
 	}
 	cluster.SetDefaults()
 	assert.Equal(t, customURLs, cluster.RPCURLs)
+	assert.Equal(t, ClusterRPCModeFirst, cluster.RPCMode)
+	assert.Equal(t, 1, cluster.RPCQuorum)
+
+	// Test rpc_quorum defaults to a majority of rpc_urls
+	cluster = &Cluster{
+		Name:    solanagorpc.MainNetBeta.Name,
+		RPCURLs: []string{"https://rpc-1.com", "https://rpc-2.com", "https://rpc-3.com", "https://rpc-4.com"},
+		RPCMode: ClusterRPCModeConsensus,
+	}
+	cluster.SetDefaults()
+	assert.Equal(t, ClusterRPCModeConsensus, cluster.RPCMode)
+	assert.Equal(t, 3, cluster.RPCQuorum)
+}
+
+func TestCluster_LeaderlessRPCQuorum(t *testing.T) {
+	cluster := &Cluster{RPCMode: ClusterRPCModeFirst, RPCQuorum: 2}
+	assert.Equal(t, 0, cluster.LeaderlessRPCQuorum())
+
+	cluster.RPCMode = ClusterRPCModeConsensus
+	assert.Equal(t, 2, cluster.LeaderlessRPCQuorum())
 }
 
 func TestCluster_Validate(t *testing.T) {
@@ -70,4 +90,23 @@ func TestCluster_Validate(t *testing.T) {
 	err = cluster.Validate()
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "cluster.rpc_urls must be a list of valid RPC URLs")
+
+	// Test with invalid RPC mode
+	cluster = &Cluster{
+		Name:    solanagorpc.TestNet.Name,
+		RPCURLs: []string{"https://api.testnet.solana.com"},
+		RPCMode: "fastest",
+	}
+	err = cluster.Validate()
+	assert.EqualError(t, err, "cluster.rpc_mode must be one of first, consensus")
+
+	// Test with unattainable RPC quorum
+	cluster = &Cluster{
+		Name:      solanagorpc.TestNet.Name,
+		RPCURLs:   []string{"https://rpc-1.com", "https://rpc-2.com"},
+		RPCMode:   ClusterRPCModeConsensus,
+		RPCQuorum: 3,
+	}
+	err = cluster.Validate()
+	assert.EqualError(t, err, "cluster.rpc_quorum must be between 1 and the number of cluster.rpc_urls (2)")
 }
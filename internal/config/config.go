@@ -29,6 +29,11 @@ type Config struct {
 	Prometheus Prometheus `koanf:"prometheus"`
 	// Failover is the failover decision parameters
 	Failover Failover `koanf:"failover"`
+	// Groups are independent validator HA groups run by the one daemon, each with its own validator,
+	// cluster and failover - instead of the top-level validator, cluster and failover
+	Groups map[string]Group `koanf:"groups"`
+	// Group is the name of the group this config was created for from Groups, empty without groups
+	Group string `koanf:"-"`
 	// File is the file that the config was loaded from
 	File string `koanf:"-"`
 	// GetPublicIPFunc is a function that returns the public IP address of the current validator
@@ -36,7 +41,8 @@ type Config struct {
 	// something else
 	GetPublicIPFunc func() (string, error)
 
-	logger *log.Logger
+	logger       *log.Logger
+	groupConfigs []*Config
 }
 
 // NewConfigParams represents parameters for creating a new Config
@@ -125,9 +131,19 @@ func (c *Config) LoadFromFile(filePath string) error {
 
 // Initialize processes and validates the loaded configuration
 func (c *Config) Initialize() error {
+	// each group is initialized as a config of its own
+	if len(c.Groups) > 0 {
+		return c.initializeGroups()
+	}
+
 	// Set defaults
 	c.setDefaults()
 
+	// witnesses have no identity key pair files or role commands
+	if c.Validator.IsWitness() {
+		return c.validate()
+	}
+
 	// load identity key pair files
 	if err := c.Validator.Identities.Load(); err != nil {
 		return err
@@ -175,11 +191,20 @@ func (c *Config) validate() error {
 		return err
 	}
 
-	err = c.Failover.Validate()
+	if c.Validator.IsWitness() {
+		err = c.Failover.ValidateWitness()
+	} else {
+		err = c.Failover.Validate()
+	}
 	if err != nil {
 		return err
 	}
 
+	// failover.peers pubkeys must be peers' own passive identities
+	if err := c.validatePeerPubkeys(); err != nil {
+		return err
+	}
+
 	// failover.dry_run if true print warning
 	if c.Failover.DryRun {
 		c.logger.Warn("failover.dry_run is true - failovers will dry-run commands only and be no-op")
@@ -193,6 +218,23 @@ func (c *Config) validate() error {
 	return nil
 }
 
+// validatePeerPubkeys validates failover.peers pubkeys against our identities
+func (c *Config) validatePeerPubkeys() error {
+	activePubkey := c.Validator.Identities.ActivePublicKey().String()
+	for name, peer := range c.Failover.Peers {
+		if peer.Pubkey == "" {
+			continue
+		}
+		if peer.Pubkey == activePubkey {
+			return fmt.Errorf("failover.peers - pubkey for peer %s must be its passive identity, not the active identity", name)
+		}
+		if c.Validator.Identities.PassiveKeyPair != nil && peer.Pubkey == c.Validator.Identities.PassiveKeyPair.PublicKey().String() {
+			return fmt.Errorf("failover.peers - pubkey for peer %s must be its own passive identity, not validator.identities.passive", name)
+		}
+	}
+	return nil
+}
+
 // setDefaults sets default values for configuration
 func (c *Config) setDefaults() {
 	c.Log.SetDefaults()
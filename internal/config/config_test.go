@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	solanago "github.com/gagliardetto/solana-go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -65,6 +66,46 @@ func TestInitialize(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestInitialize_Witness(t *testing.T) {
+	content := `
+validator:
+  name: "test-witness"
+  mode: "witness"
+  identities:
+    active_pubkey: "8Pi6ZJ7L9XpZ3uYTTw1H7zHyG1SBkpqAeqaxXSWvh5nE"
+
+cluster:
+  name: "testnet"
+  rpc_urls:
+    - "https://api.testnet.solana.com"
+
+failover:
+  peers:
+    validator-1:
+      ip: "192.168.1.10"
+      pubkey: "Vote111111111111111111111111111111111111111"
+    validator-2:
+      ip: "192.168.1.11"
+      pubkey: "Stake11111111111111111111111111111111111111"
+`
+	tempFile, err := os.CreateTemp("", "config-*.yaml")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.Remove(tempFile.Name()) })
+	_, err = tempFile.WriteString(content)
+	require.NoError(t, err)
+	require.NoError(t, tempFile.Close())
+
+	cfg, err := New(NewConfigParams{})
+	require.NoError(t, err)
+	require.NoError(t, cfg.LoadFromFile(tempFile.Name()))
+
+	// no identity key pair files or role commands are needed
+	require.NoError(t, cfg.Initialize())
+	assert.True(t, cfg.Validator.IsWitness())
+	assert.Nil(t, cfg.Validator.Identities.ActiveKeyPair)
+	assert.Equal(t, "8Pi6ZJ7L9XpZ3uYTTw1H7zHyG1SBkpqAeqaxXSWvh5nE", cfg.Validator.Identities.ActivePublicKey().String())
+}
+
 func TestSetDefaults(t *testing.T) {
 	cfg := &Config{}
 	cfg.setDefaults()
@@ -100,7 +141,7 @@ func TestValidate(t *testing.T) {
 				Command: "systemctl stop solana",
 			},
 			Peers: Peers{
-				"validator-1": {IP: "192.168.1.10"},
+				"validator-1": {IP: "192.168.1.10", Pubkey: "Vote111111111111111111111111111111111111111"},
 			},
 		},
 	}
@@ -123,6 +164,19 @@ func TestValidate(t *testing.T) {
 	err = cfg.validate()
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "validator.rpc_url must be a valid URL")
+
+	// Test with a peer pubkey that is one of our identities
+	cfg.Validator.RPCURL = "http://localhost:8899"
+	cfg.Validator.Identities.ActivePubkey = "8Pi6ZJ7L9XpZ3uYTTw1H7zHyG1SBkpqAeqaxXSWvh5nE"
+	cfg.Failover.Peers["validator-1"] = Peer{IP: "192.168.1.10", Pubkey: "8Pi6ZJ7L9XpZ3uYTTw1H7zHyG1SBkpqAeqaxXSWvh5nE"}
+	err = cfg.validate()
+	assert.EqualError(t, err, "failover.peers - pubkey for peer validator-1 must be its passive identity, not the active identity")
+
+	passiveKeyPair := solanago.NewWallet().PrivateKey
+	cfg.Validator.Identities.PassiveKeyPair = &passiveKeyPair
+	cfg.Failover.Peers["validator-1"] = Peer{IP: "192.168.1.10", Pubkey: passiveKeyPair.PublicKey().String()}
+	err = cfg.validate()
+	assert.EqualError(t, err, "failover.peers - pubkey for peer validator-1 must be its own passive identity, not validator.identities.passive")
 }
 
 func createTempConfigFile(t *testing.T) string {
@@ -166,8 +220,10 @@ failover:
   peers:
     validator-1:
       ip: "192.168.1.10"
+      pubkey: "Vote111111111111111111111111111111111111111"
     validator-2:
       ip: "192.168.1.11"
+      pubkey: "Stake11111111111111111111111111111111111111"
 `
 
 	tempFile, err := os.CreateTemp("", "config-*.yaml")
@@ -223,8 +279,10 @@ failover:
   peers:
     validator-1:
       ip: "192.168.1.10"
+      pubkey: "Vote111111111111111111111111111111111111111"
     validator-2:
       ip: "192.168.1.11"
+      pubkey: "Stake11111111111111111111111111111111111111"
 `
 
 	tempFile, err := os.CreateTemp("", "config-*.yaml")
@@ -3,21 +3,134 @@ package config
 import (
 	"fmt"
 	"net"
+	"slices"
+	"strings"
 	"time"
+
+	solanago "github.com/gagliardetto/solana-go"
+)
+
+// DefaultStateDir is the default failover.state_dir - each of groups defaults to a directory named after
+// the group within it
+const DefaultStateDir = "/var/lib/solana-validator-ha"
+
+const (
+	// FailoverOnShutdownNone leaves the validator identity untouched when the daemon shuts down
+	FailoverOnShutdownNone = "none"
+	// FailoverOnShutdownPassive runs the passive command on shutdown if this node is active
+	FailoverOnShutdownPassive = "passive"
+)
+
+var validFailoverOnShutdownPolicies = []string{
+	FailoverOnShutdownNone,
+	FailoverOnShutdownPassive,
+}
+
+const (
+	// FailoverOnStartupForcePassive runs the passive command on startup if this node is active, leaving failover
+	// to decide who becomes active
+	FailoverOnStartupForcePassive = "force_passive"
+	// FailoverOnStartupReconcile runs the passive command on startup if this node is active while gossip shows
+	// the active identity on a peer
+	FailoverOnStartupReconcile = "reconcile"
+	// FailoverOnStartupObserve only logs how the local identity compares with gossip on startup
+	FailoverOnStartupObserve = "observe"
+)
+
+var validFailoverOnStartupPolicies = []string{
+	FailoverOnStartupForcePassive,
+	FailoverOnStartupReconcile,
+	FailoverOnStartupObserve,
+}
+
+const (
+	// FailoverFailbackNone leaves the active role where it is
+	FailoverFailbackNone = "none"
+	// FailoverFailbackPriority hands the active role back to the highest priority peer once it has soaked
+	FailoverFailbackPriority = "priority"
+)
+
+var validFailoverFailbackPolicies = []string{
+	FailoverFailbackNone,
+	FailoverFailbackPriority,
+}
+
+const (
+	// FailoverOnActivationFailureNone stays active and only logs when activation verification fails
+	FailoverOnActivationFailureNone = "none"
+	// FailoverOnActivationFailureRollback runs the passive command and active rollback hooks when activation
+	// verification fails so that the next ranked peer can take over
+	FailoverOnActivationFailureRollback = "rollback"
 )
 
+var validFailoverOnActivationFailurePolicies = []string{
+	FailoverOnActivationFailureNone,
+	FailoverOnActivationFailureRollback,
+}
+
 // Failover represents failover decision parameters
 type Failover struct {
-	DryRun                     bool          `koanf:"dry_run"`
-	PollIntervalDuration       time.Duration `koanf:"poll_interval_duration"`
-	LeaderlessSamplesThreshold int           `koanf:"leaderless_samples_threshold"`
-	TakeoverJitterDuration     time.Duration `koanf:"takeover_jitter_duration"`
-	Active                     Role          `koanf:"active"`
-	Passive                    Role          `koanf:"passive"`
-	Peers                      Peers         `koanf:"peers"`
+	DryRun                            bool          `koanf:"dry_run"`
+	PollIntervalDuration              time.Duration `koanf:"poll_interval_duration"`
+	LeaderlessSamplesThreshold        int           `koanf:"leaderless_samples_threshold"`
+	TakeoverJitterDuration            time.Duration `koanf:"takeover_jitter_duration"`
+	OnStartup                         string        `koanf:"on_startup"`
+	OnShutdown                        string        `koanf:"on_shutdown"`
+	ShutdownTimeoutDuration           time.Duration `koanf:"shutdown_timeout_duration"`
+	SwitchoverTimeoutDuration         time.Duration `koanf:"switchover_timeout_duration"`
+	MaintenanceFile                   string        `koanf:"maintenance_file"`
+	ActiveUnhealthySamplesThreshold   int           `koanf:"active_unhealthy_samples_threshold"`
+	SplitBrainSamplesThreshold        int           `koanf:"split_brain_samples_threshold"`
+	Priority                          int           `koanf:"priority"`
+	Failback                          string        `koanf:"failback"`
+	FailbackSoakDuration              time.Duration `koanf:"failback_soak_duration"`
+	TakeoverQuorum                    bool          `koanf:"takeover_quorum"`
+	TakeoverVoteLeaseDuration         time.Duration `koanf:"takeover_vote_lease_duration"`
+	TakeoverVoteTimeoutDuration       time.Duration `koanf:"takeover_vote_timeout_duration"`
+	StateDir                          string        `koanf:"state_dir"`
+	MaxTransitions                    int           `koanf:"max_transitions"`
+	TransitionWindowDuration          time.Duration `koanf:"transition_window_duration"`
+	TransitionCooldownDuration        time.Duration `koanf:"transition_cooldown_duration"`
+	ActivationVerifyTimeoutDuration   time.Duration `koanf:"activation_verify_timeout_duration"`
+	OnActivationFailure               string        `koanf:"on_activation_failure"`
+	LeaderScheduleLookaheadSlots      int           `koanf:"leader_schedule_lookahead_slots"`
+	LeaderScheduleWaitTimeoutDuration time.Duration `koanf:"leader_schedule_wait_timeout_duration"`
+	Fencing                           Fencing       `koanf:"fencing"`
+	GossipProbe                       GossipProbe   `koanf:"gossip_probe"`
+	PeerHistory                       PeerHistory   `koanf:"peer_history"`
+	DoubleVoteGuardSlots              int           `koanf:"double_vote_guard_slots"`
+	DoubleVoteGuardTimeoutDuration    time.Duration `koanf:"double_vote_guard_timeout_duration"`
+	MaxVoteLagSlots                   int           `koanf:"max_vote_lag_slots"`
+	VoteLagSamplesThreshold           int           `koanf:"vote_lag_samples_threshold"`
+	Active                            Role          `koanf:"active"`
+	Passive                           Role          `koanf:"passive"`
+	Peers                             Peers         `koanf:"peers"`
+	// AllowPeersWithoutPubkey opts out of requiring peers running a validator to declare a pubkey, leaving them
+	// told apart by their addresses only
+	AllowPeersWithoutPubkey bool `koanf:"allow_peers_without_pubkey"`
 }
 
+// Validate validates the failover configuration of a validator
 func (f *Failover) Validate() error {
+	if err := f.validateSettings(); err != nil {
+		return err
+	}
+	if err := f.validateRoles(); err != nil {
+		return err
+	}
+	return f.validatePeers()
+}
+
+// ValidateWitness validates the failover configuration of a witness, which has no role commands to run
+func (f *Failover) ValidateWitness() error {
+	if err := f.validateSettings(); err != nil {
+		return err
+	}
+	return f.validatePeers()
+}
+
+// validateSettings validates the failover decision parameters
+func (f *Failover) validateSettings() error {
 	// failover.poll_interval must be greater than zero
 	if f.PollIntervalDuration == 0 {
 		return fmt.Errorf("failover.poll_interval_duration must be greater than zero")
@@ -28,6 +141,136 @@ func (f *Failover) Validate() error {
 		return fmt.Errorf("failover.leaderless_samples_threshold must be positive and non-zero")
 	}
 
+	// failover.on_startup must be a known policy if set
+	if f.OnStartup != "" && !slices.Contains(validFailoverOnStartupPolicies, f.OnStartup) {
+		return fmt.Errorf("failover.on_startup must be one of %s", strings.Join(validFailoverOnStartupPolicies, ", "))
+	}
+
+	// failover.on_shutdown must be a known policy if set
+	if f.OnShutdown != "" && !slices.Contains(validFailoverOnShutdownPolicies, f.OnShutdown) {
+		return fmt.Errorf("failover.on_shutdown must be one of %s", strings.Join(validFailoverOnShutdownPolicies, ", "))
+	}
+
+	// failover.shutdown_timeout_duration must not be negative
+	if f.ShutdownTimeoutDuration < 0 {
+		return fmt.Errorf("failover.shutdown_timeout_duration must not be negative")
+	}
+
+	// failover.switchover_timeout_duration must not be negative
+	if f.SwitchoverTimeoutDuration < 0 {
+		return fmt.Errorf("failover.switchover_timeout_duration must not be negative")
+	}
+
+	// failover.active_unhealthy_samples_threshold must not be negative
+	if f.ActiveUnhealthySamplesThreshold < 0 {
+		return fmt.Errorf("failover.active_unhealthy_samples_threshold must not be negative")
+	}
+
+	// failover.split_brain_samples_threshold must not be negative
+	if f.SplitBrainSamplesThreshold < 0 {
+		return fmt.Errorf("failover.split_brain_samples_threshold must not be negative")
+	}
+
+	// failover.priority must not be negative
+	if f.Priority < 0 {
+		return fmt.Errorf("failover.priority must not be negative")
+	}
+
+	// failover.failback must be a known policy if set
+	if f.Failback != "" && !slices.Contains(validFailoverFailbackPolicies, f.Failback) {
+		return fmt.Errorf("failover.failback must be one of %s", strings.Join(validFailoverFailbackPolicies, ", "))
+	}
+
+	// failover.failback_soak_duration must not be negative
+	if f.FailbackSoakDuration < 0 {
+		return fmt.Errorf("failover.failback_soak_duration must not be negative")
+	}
+
+	// failover.takeover_vote_lease_duration must not be negative
+	if f.TakeoverVoteLeaseDuration < 0 {
+		return fmt.Errorf("failover.takeover_vote_lease_duration must not be negative")
+	}
+
+	// failover.takeover_vote_timeout_duration must not be negative
+	if f.TakeoverVoteTimeoutDuration < 0 {
+		return fmt.Errorf("failover.takeover_vote_timeout_duration must not be negative")
+	}
+
+	// failover.max_transitions must not be negative
+	if f.MaxTransitions < 0 {
+		return fmt.Errorf("failover.max_transitions must not be negative")
+	}
+
+	// failover.transition_window_duration must not be negative
+	if f.TransitionWindowDuration < 0 {
+		return fmt.Errorf("failover.transition_window_duration must not be negative")
+	}
+
+	// failover.transition_cooldown_duration must not be negative
+	if f.TransitionCooldownDuration < 0 {
+		return fmt.Errorf("failover.transition_cooldown_duration must not be negative")
+	}
+
+	// failover.activation_verify_timeout_duration must not be negative
+	if f.ActivationVerifyTimeoutDuration < 0 {
+		return fmt.Errorf("failover.activation_verify_timeout_duration must not be negative")
+	}
+
+	// failover.on_activation_failure must be a known policy if set
+	if f.OnActivationFailure != "" && !slices.Contains(validFailoverOnActivationFailurePolicies, f.OnActivationFailure) {
+		return fmt.Errorf("failover.on_activation_failure must be one of %s", strings.Join(validFailoverOnActivationFailurePolicies, ", "))
+	}
+
+	// failover.leader_schedule_lookahead_slots must not be negative
+	if f.LeaderScheduleLookaheadSlots < 0 {
+		return fmt.Errorf("failover.leader_schedule_lookahead_slots must not be negative")
+	}
+
+	// failover.leader_schedule_wait_timeout_duration must not be negative
+	if f.LeaderScheduleWaitTimeoutDuration < 0 {
+		return fmt.Errorf("failover.leader_schedule_wait_timeout_duration must not be negative")
+	}
+
+	// failover.double_vote_guard_slots must not be negative
+	if f.DoubleVoteGuardSlots < 0 {
+		return fmt.Errorf("failover.double_vote_guard_slots must not be negative")
+	}
+
+	// failover.double_vote_guard_timeout_duration must not be negative
+	if f.DoubleVoteGuardTimeoutDuration < 0 {
+		return fmt.Errorf("failover.double_vote_guard_timeout_duration must not be negative")
+	}
+
+	// failover.max_vote_lag_slots must not be negative
+	if f.MaxVoteLagSlots < 0 {
+		return fmt.Errorf("failover.max_vote_lag_slots must not be negative")
+	}
+
+	// failover.vote_lag_samples_threshold must not be negative
+	if f.VoteLagSamplesThreshold < 0 {
+		return fmt.Errorf("failover.vote_lag_samples_threshold must not be negative")
+	}
+
+	// failover.fencing must be valid
+	if err := f.Fencing.Validate(); err != nil {
+		return err
+	}
+
+	// failover.gossip_probe must be valid
+	if err := f.GossipProbe.Validate(); err != nil {
+		return err
+	}
+
+	// failover.peer_history must be valid
+	if err := f.PeerHistory.Validate(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateRoles validates the failover.active and failover.passive role commands and hooks
+func (f *Failover) validateRoles() error {
 	// failover.active.command must be defined
 	if f.Active.Command == "" {
 		return fmt.Errorf("failover.active.command must be defined")
@@ -53,6 +296,16 @@ func (f *Failover) Validate() error {
 		}
 	}
 
+	// failover.active.hooks.rollback must all be valid if defined
+	for _, hook := range f.Active.Hooks.Rollback {
+		if hook.Name == "" {
+			return fmt.Errorf("failover.active.hooks.rollback must have a name")
+		}
+		if hook.Command == "" {
+			return fmt.Errorf("failover.active.hooks.rollback must have a command")
+		}
+	}
+
 	// failover.passive.command must be defined
 	if f.Passive.Command == "" {
 		return fmt.Errorf("failover.passive.command must be defined")
@@ -78,21 +331,60 @@ func (f *Failover) Validate() error {
 		}
 	}
 
+	// failover.passive.hooks.rollback are never run - only activation is rolled back
+	if len(f.Passive.Hooks.Rollback) > 0 {
+		return fmt.Errorf("failover.passive.hooks.rollback is not supported - use failover.active.hooks.rollback")
+	}
+
+	return nil
+}
+
+// validatePeers validates failover.peers
+func (f *Failover) validatePeers() error {
 	// failover.peers must be at least 1
 	if len(f.Peers) == 0 {
 		return fmt.Errorf("failover.peers - at least one peer must be defined")
 	}
 
-	// failover.peers must have unique valid IP addresses
+	// failover.peers must have unique valid IPv4 or IPv6 addresses - however they are written - and unique
+	// pubkeys, which every peer running a validator must declare so that nodes at its addresses can be verified
+	// unless failover.allow_peers_without_pubkey is set
 	ips := make(map[string]bool)
+	pubkeys := make(map[string]bool)
 	for name, peer := range f.Peers {
-		if net.ParseIP(peer.IP) == nil || net.ParseIP(peer.IP).To4() == nil {
-			return fmt.Errorf("failover.peers - invalid IP address %s for peer %s", peer.IP, name)
+		for _, address := range peer.Addresses() {
+			ip := net.ParseIP(address)
+			if ip == nil {
+				return fmt.Errorf("failover.peers - invalid IP address %s for peer %s", address, name)
+			}
+			if ips[ip.String()] {
+				return fmt.Errorf("failover.peers - duplicate IP address %s found for peer %s", address, name)
+			}
+			ips[ip.String()] = true
+		}
+		if peer.GossipPort < 0 || peer.GossipPort > 65535 {
+			return fmt.Errorf("failover.peers - gossip_port must be between 1 and 65535 for peer %s", name)
 		}
-		if ips[peer.IP] {
-			return fmt.Errorf("failover.peers - duplicate IP address %s found for peer %s", peer.IP, name)
+		if peer.Pubkey == "" && !peer.Witness && !f.AllowPeersWithoutPubkey {
+			return fmt.Errorf("failover.peers - pubkey is required for peer %s unless failover.allow_peers_without_pubkey is set", name)
+		}
+		if peer.Pubkey != "" {
+			if _, err := solanago.PublicKeyFromBase58(peer.Pubkey); err != nil {
+				return fmt.Errorf("failover.peers - invalid pubkey for peer %s: %w", name, err)
+			}
+			if pubkeys[peer.Pubkey] {
+				return fmt.Errorf("failover.peers - duplicate pubkey %s found for peer %s", peer.Pubkey, name)
+			}
+			pubkeys[peer.Pubkey] = true
+		}
+		if peer.Priority < 0 {
+			return fmt.Errorf("failover.peers - priority must not be negative for peer %s", name)
+		}
+		if peer.RPCURL != "" {
+			if err := validateRPCURL(peer.RPCURL); err != nil {
+				return fmt.Errorf("failover.peers - invalid rpc_url for peer %s: %w", name, err)
+			}
 		}
-		ips[peer.IP] = true
 	}
 
 	return nil
@@ -125,6 +417,60 @@ func (f *Failover) SetDefaults() {
 	if f.TakeoverJitterDuration == 0 {
 		f.TakeoverJitterDuration = 3 * time.Second
 	}
+	if f.OnStartup == "" {
+		f.OnStartup = FailoverOnStartupReconcile
+	}
+	if f.OnShutdown == "" {
+		f.OnShutdown = FailoverOnShutdownNone
+	}
+	if f.ShutdownTimeoutDuration == 0 {
+		f.ShutdownTimeoutDuration = 30 * time.Second
+	}
+	if f.SwitchoverTimeoutDuration == 0 {
+		f.SwitchoverTimeoutDuration = 2 * time.Minute
+	}
+	if f.SplitBrainSamplesThreshold == 0 {
+		f.SplitBrainSamplesThreshold = 3 // tolerate stale gossip contact info right after an identity change
+	}
+	if f.Failback == "" {
+		f.Failback = FailoverFailbackNone
+	}
+	if f.FailbackSoakDuration == 0 {
+		f.FailbackSoakDuration = 10 * time.Minute
+	}
+	if f.TakeoverVoteLeaseDuration == 0 {
+		f.TakeoverVoteLeaseDuration = time.Minute // long enough for the winner to run its active command and show in gossip
+	}
+	if f.TakeoverVoteTimeoutDuration == 0 {
+		f.TakeoverVoteTimeoutDuration = 5 * time.Second
+	}
+	if f.StateDir == "" {
+		f.StateDir = DefaultStateDir
+	}
+	if f.TransitionWindowDuration == 0 {
+		f.TransitionWindowDuration = time.Hour
+	}
+	if f.ActivationVerifyTimeoutDuration == 0 {
+		f.ActivationVerifyTimeoutDuration = 2 * time.Minute
+	}
+	if f.OnActivationFailure == "" {
+		f.OnActivationFailure = FailoverOnActivationFailureNone
+	}
+	if f.LeaderScheduleWaitTimeoutDuration == 0 {
+		f.LeaderScheduleWaitTimeoutDuration = 2 * time.Minute
+	}
+	if f.DoubleVoteGuardSlots == 0 {
+		f.DoubleVoteGuardSlots = 8 // ~3s of a vote landing every slot
+	}
+	if f.DoubleVoteGuardTimeoutDuration == 0 {
+		f.DoubleVoteGuardTimeoutDuration = 30 * time.Second
+	}
+	if f.VoteLagSamplesThreshold == 0 {
+		f.VoteLagSamplesThreshold = 3
+	}
+	f.Fencing.SetDefaults()
+	f.GossipProbe.SetDefaults()
+	f.PeerHistory.SetDefaults()
 
 	// Set role names
 	f.Active.Name = "active"
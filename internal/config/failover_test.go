@@ -15,6 +15,28 @@ func TestFailover_SetDefaults(t *testing.T) {
 	assert.Equal(t, 5*time.Second, failover.PollIntervalDuration)
 	assert.Equal(t, 3, failover.LeaderlessSamplesThreshold)
 	assert.Equal(t, 3*time.Second, failover.TakeoverJitterDuration)
+	assert.Equal(t, FailoverOnStartupReconcile, failover.OnStartup)
+	assert.Equal(t, 8, failover.DoubleVoteGuardSlots)
+	assert.Equal(t, 30*time.Second, failover.DoubleVoteGuardTimeoutDuration)
+	assert.Equal(t, 0, failover.MaxVoteLagSlots)
+	assert.Equal(t, 3, failover.VoteLagSamplesThreshold)
+	assert.Equal(t, FailoverOnShutdownNone, failover.OnShutdown)
+	assert.Equal(t, 30*time.Second, failover.ShutdownTimeoutDuration)
+	assert.Equal(t, 2*time.Minute, failover.SwitchoverTimeoutDuration)
+	assert.Equal(t, 3, failover.SplitBrainSamplesThreshold)
+	assert.Equal(t, FailoverFailbackNone, failover.Failback)
+	assert.Equal(t, 10*time.Minute, failover.FailbackSoakDuration)
+	assert.False(t, failover.TakeoverQuorum)
+	assert.Equal(t, time.Minute, failover.TakeoverVoteLeaseDuration)
+	assert.Equal(t, 5*time.Second, failover.TakeoverVoteTimeoutDuration)
+	assert.Equal(t, "/var/lib/solana-validator-ha", failover.StateDir)
+	assert.Equal(t, 0, failover.MaxTransitions)
+	assert.Equal(t, time.Hour, failover.TransitionWindowDuration)
+	assert.Equal(t, time.Duration(0), failover.TransitionCooldownDuration)
+	assert.Equal(t, 2*time.Minute, failover.ActivationVerifyTimeoutDuration)
+	assert.Equal(t, FailoverOnActivationFailureNone, failover.OnActivationFailure)
+	assert.Equal(t, 0, failover.LeaderScheduleLookaheadSlots)
+	assert.Equal(t, 2*time.Minute, failover.LeaderScheduleWaitTimeoutDuration)
 }
 
 func TestFailover_Validate(t *testing.T) {
@@ -31,8 +53,8 @@ func TestFailover_Validate(t *testing.T) {
 			Command: "systemctl stop solana",
 		},
 		Peers: Peers{
-			"validator-1": {IP: "192.168.1.10"},
-			"validator-2": {IP: "192.168.1.11"},
+			"validator-1": {IP: "192.168.1.10", Pubkey: "Vote111111111111111111111111111111111111111"},
+			"validator-2": {IP: "192.168.1.11", Pubkey: "Stake11111111111111111111111111111111111111"},
 		},
 	}
 
@@ -75,7 +97,7 @@ func TestFailover_Validate(t *testing.T) {
 
 	// Test with invalid IP address
 	failover.Peers = Peers{
-		"validator-1": {IP: "invalid-ip"},
+		"validator-1": {IP: "invalid-ip", Pubkey: "Vote111111111111111111111111111111111111111"},
 	}
 	err = failover.Validate()
 	assert.Error(t, err)
@@ -83,12 +105,299 @@ func TestFailover_Validate(t *testing.T) {
 
 	// Test with duplicate IP addresses
 	failover.Peers = Peers{
-		"validator-1": {IP: "192.168.1.10"},
-		"validator-2": {IP: "192.168.1.10"},
+		"validator-1": {IP: "192.168.1.10", Pubkey: "Vote111111111111111111111111111111111111111"},
+		"validator-2": {IP: "192.168.1.10", Pubkey: "Stake11111111111111111111111111111111111111"},
 	}
 	err = failover.Validate()
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failover.peers - duplicate IP address")
+
+	// Test with duplicate IP addresses among further addresses, however they are written
+	failover.Peers = Peers{
+		"validator-1": {IP: "192.168.1.10", IPs: []string{"2001:db8::10"}, Pubkey: "Vote111111111111111111111111111111111111111"},
+		"validator-2": {IP: "192.168.1.11", IPs: []string{"2001:db8:0:0::10"}, Pubkey: "Stake11111111111111111111111111111111111111"},
+	}
+	err = failover.Validate()
+	assert.EqualError(t, err, "failover.peers - duplicate IP address 2001:db8:0:0::10 found for peer validator-2")
+
+	// Test with invalid gossip port
+	failover.Peers = Peers{
+		"validator-1": {IP: "192.168.1.10", GossipPort: 70000, Pubkey: "Vote111111111111111111111111111111111111111"},
+	}
+	err = failover.Validate()
+	assert.EqualError(t, err, "failover.peers - gossip_port must be between 1 and 65535 for peer validator-1")
+
+	// Test with a peer running a validator without a pubkey
+	failover.Peers = Peers{
+		"validator-1": {IP: "192.168.1.10"},
+	}
+	err = failover.Validate()
+	assert.EqualError(t, err, "failover.peers - pubkey is required for peer validator-1 unless failover.allow_peers_without_pubkey is set")
+
+	// Test with a peer without a pubkey having explicitly opted out
+	failover.AllowPeersWithoutPubkey = true
+	assert.NoError(t, failover.Validate())
+	failover.AllowPeersWithoutPubkey = false
+
+	// Test with a witness peer, which has no identity of its own to declare
+	failover.Peers = Peers{
+		"witness": {IP: "192.168.1.10", Witness: true},
+	}
+	assert.NoError(t, failover.Validate())
+
+	// Test with invalid pubkey
+	failover.Peers = Peers{
+		"validator-1": {IP: "192.168.1.10", Pubkey: "not-a-pubkey"},
+	}
+	err = failover.Validate()
+	assert.ErrorContains(t, err, "failover.peers - invalid pubkey for peer validator-1")
+
+	// Test with duplicate pubkeys
+	failover.Peers = Peers{
+		"validator-1": {IP: "192.168.1.10", Pubkey: "11111111111111111111111111111111"},
+		"validator-2": {IP: "192.168.1.11", Pubkey: "11111111111111111111111111111111"},
+	}
+	err = failover.Validate()
+	assert.ErrorContains(t, err, "failover.peers - duplicate pubkey 11111111111111111111111111111111 found for peer")
+
+	// Test with IPv6 addresses, a gossip port and a pubkey
+	failover.Peers = Peers{
+		"validator-1": {IP: "2001:db8::10", IPs: []string{"192.168.1.10"}, GossipPort: 8001, Pubkey: "11111111111111111111111111111111"},
+	}
+	assert.NoError(t, failover.Validate())
+
+	// Test with negative double vote guard slots
+	failover.DoubleVoteGuardSlots = -1
+	err = failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.double_vote_guard_slots must not be negative")
+	failover.DoubleVoteGuardSlots = 0
+
+	// Test with negative max vote lag slots
+	failover.MaxVoteLagSlots = -1
+	err = failover.Validate()
+	assert.EqualError(t, err, "failover.max_vote_lag_slots must not be negative")
+	failover.MaxVoteLagSlots = 0
+
+	// Test with negative vote lag samples threshold
+	failover.VoteLagSamplesThreshold = -1
+	err = failover.Validate()
+	assert.EqualError(t, err, "failover.vote_lag_samples_threshold must not be negative")
+	failover.VoteLagSamplesThreshold = 0
+
+	// Test with invalid rpc_url
+	failover.Peers = Peers{
+		"validator-1": {IP: "192.168.1.10", RPCURL: "192.168.1.10:8899", Pubkey: "Vote111111111111111111111111111111111111111"},
+	}
+	err = failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.peers - invalid rpc_url for peer validator-1")
+}
+
+func TestFailover_ValidateOnStartup(t *testing.T) {
+	failover := &Failover{
+		PollIntervalDuration:       30 * time.Second,
+		LeaderlessSamplesThreshold: 10,
+		OnStartup:                  FailoverOnStartupForcePassive,
+		Active: Role{
+			Command: "systemctl start solana",
+		},
+		Passive: Role{
+			Command: "systemctl stop solana",
+		},
+		Peers: Peers{
+			"validator-1": {IP: "192.168.1.10", Pubkey: "Vote111111111111111111111111111111111111111"},
+		},
+	}
+
+	assert.NoError(t, failover.Validate())
+
+	// Test with unknown on_startup policy
+	failover.OnStartup = "takeover"
+	err := failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.on_startup must be one of force_passive, reconcile, observe")
+}
+
+func TestFailover_ValidateWitness(t *testing.T) {
+	failover := &Failover{
+		PollIntervalDuration:       30 * time.Second,
+		LeaderlessSamplesThreshold: 10,
+		Peers: Peers{
+			"validator-1": {IP: "192.168.1.10", Pubkey: "Vote111111111111111111111111111111111111111"},
+			"validator-2": {IP: "192.168.1.11", Pubkey: "Stake11111111111111111111111111111111111111"},
+		},
+	}
+
+	// witnesses have no role commands
+	assert.NoError(t, failover.ValidateWitness())
+	assert.ErrorContains(t, failover.Validate(), "failover.active.command must be defined")
+
+	// but the rest is validated the same
+	failover.Peers = Peers{}
+	assert.ErrorContains(t, failover.ValidateWitness(), "failover.peers - at least one peer must be defined")
+}
+
+func TestFailover_ValidateOnShutdown(t *testing.T) {
+	failover := &Failover{
+		PollIntervalDuration:       30 * time.Second,
+		LeaderlessSamplesThreshold: 10,
+		OnShutdown:                 FailoverOnShutdownPassive,
+		ShutdownTimeoutDuration:    10 * time.Second,
+		Active: Role{
+			Command: "systemctl start solana",
+		},
+		Passive: Role{
+			Command: "systemctl stop solana",
+		},
+		Peers: Peers{
+			"validator-1": {IP: "192.168.1.10", Pubkey: "Vote111111111111111111111111111111111111111"},
+		},
+	}
+
+	err := failover.Validate()
+	assert.NoError(t, err)
+
+	// Test with unknown on_shutdown policy
+	failover.OnShutdown = "explode"
+	err = failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.on_shutdown must be one of none, passive")
+
+	// Test with negative shutdown timeout
+	failover.OnShutdown = FailoverOnShutdownNone
+	failover.ShutdownTimeoutDuration = -1 * time.Second
+	err = failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.shutdown_timeout_duration must not be negative")
+
+	// Test with negative switchover timeout
+	failover.ShutdownTimeoutDuration = 0
+	failover.SwitchoverTimeoutDuration = -1 * time.Second
+	err = failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.switchover_timeout_duration must not be negative")
+
+	// Test with negative active unhealthy samples threshold
+	failover.SwitchoverTimeoutDuration = 0
+	failover.ActiveUnhealthySamplesThreshold = -1
+	err = failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.active_unhealthy_samples_threshold must not be negative")
+
+	// Test with negative split-brain samples threshold
+	failover.ActiveUnhealthySamplesThreshold = 0
+	failover.SplitBrainSamplesThreshold = -1
+	err = failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.split_brain_samples_threshold must not be negative")
+
+	// Test with negative priority
+	failover.SplitBrainSamplesThreshold = 0
+	failover.Priority = -1
+	err = failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.priority must not be negative")
+
+	// Test with unknown failback policy
+	failover.Priority = 0
+	failover.Failback = "sometimes"
+	err = failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.failback must be one of none, priority")
+
+	// Test with negative failback soak duration
+	failover.Failback = FailoverFailbackPriority
+	failover.FailbackSoakDuration = -1 * time.Second
+	err = failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.failback_soak_duration must not be negative")
+
+	// Test with negative takeover vote lease duration
+	failover.FailbackSoakDuration = 0
+	failover.TakeoverVoteLeaseDuration = -1 * time.Second
+	err = failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.takeover_vote_lease_duration must not be negative")
+
+	// Test with negative takeover vote timeout duration
+	failover.TakeoverVoteLeaseDuration = 0
+	failover.TakeoverVoteTimeoutDuration = -1 * time.Second
+	err = failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.takeover_vote_timeout_duration must not be negative")
+
+	// Test with negative max transitions
+	failover.TakeoverVoteTimeoutDuration = 0
+	failover.MaxTransitions = -1
+	err = failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.max_transitions must not be negative")
+
+	// Test with negative transition window duration
+	failover.MaxTransitions = 0
+	failover.TransitionWindowDuration = -1 * time.Second
+	err = failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.transition_window_duration must not be negative")
+
+	// Test with negative transition cooldown duration
+	failover.TransitionWindowDuration = 0
+	failover.TransitionCooldownDuration = -1 * time.Second
+	err = failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.transition_cooldown_duration must not be negative")
+
+	// Test with negative activation verify timeout
+	failover.TransitionCooldownDuration = 0
+	failover.ActivationVerifyTimeoutDuration = -1 * time.Second
+	err = failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.activation_verify_timeout_duration must not be negative")
+
+	// Test with unknown on_activation_failure policy
+	failover.ActivationVerifyTimeoutDuration = 0
+	failover.OnActivationFailure = "panic"
+	err = failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.on_activation_failure must be one of none, rollback")
+
+	// Test with active rollback hooks missing a name
+	failover.OnActivationFailure = FailoverOnActivationFailureRollback
+	failover.Active.Hooks.Rollback = []Hook{{Command: "echo 'rollback'"}}
+	err = failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.active.hooks.rollback must have a name")
+
+	// Test with passive rollback hooks
+	failover.Active.Hooks.Rollback = []Hook{{Name: "rollback", Command: "echo 'rollback'"}}
+	failover.Passive.Hooks.Rollback = []Hook{{Name: "rollback", Command: "echo 'rollback'"}}
+	err = failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.passive.hooks.rollback is not supported")
+
+	// Test with negative leader schedule lookahead
+	failover.Passive.Hooks.Rollback = nil
+	failover.LeaderScheduleLookaheadSlots = -1
+	err = failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.leader_schedule_lookahead_slots must not be negative")
+
+	// Test with negative leader schedule wait timeout
+	failover.LeaderScheduleLookaheadSlots = 0
+	failover.LeaderScheduleWaitTimeoutDuration = -1 * time.Second
+	err = failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.leader_schedule_wait_timeout_duration must not be negative")
+
+	// Test with negative peer priority
+	failover.LeaderScheduleWaitTimeoutDuration = 0
+	failover.Peers = Peers{
+		"validator-1": {IP: "192.168.1.10", Priority: -1, Pubkey: "Vote111111111111111111111111111111111111111"},
+	}
+	err = failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.peers - priority must not be negative for peer validator-1")
 }
 
 func TestFailover_ValidateWithHooks(t *testing.T) {
@@ -119,7 +428,7 @@ func TestFailover_ValidateWithHooks(t *testing.T) {
 			},
 		},
 		Peers: Peers{
-			"validator-1": {IP: "192.168.1.10"},
+			"validator-1": {IP: "192.168.1.10", Pubkey: "Vote111111111111111111111111111111111111111"},
 		},
 	}
 
@@ -0,0 +1,235 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	// FenceAgentTypeCommand runs a local command, e.g. to power off the previous active through its BMC
+	FenceAgentTypeCommand = "command"
+	// FenceAgentTypeHTTP makes an HTTP request, e.g. to a PDU or switch API, and succeeds on a 2xx response
+	FenceAgentTypeHTTP = "http"
+	// FenceAgentTypeSSH runs a command on the previous active over ssh
+	FenceAgentTypeSSH = "ssh"
+)
+
+var validFenceAgentTypes = []string{
+	FenceAgentTypeCommand,
+	FenceAgentTypeHTTP,
+	FenceAgentTypeSSH,
+}
+
+const (
+	// FencingOnTimeoutRefuse refuses to take over when the previous active could not be fenced in time
+	FencingOnTimeoutRefuse = "refuse"
+	// FencingOnTimeoutProceed takes over when the previous active could not be fenced in time
+	FencingOnTimeoutProceed = "proceed"
+)
+
+var validFencingOnTimeoutPolicies = []string{
+	FencingOnTimeoutRefuse,
+	FencingOnTimeoutProceed,
+}
+
+// Fencing is how a failover makes sure the previous active has stopped signing before taking over
+type Fencing struct {
+	Agents          []FenceAgent  `koanf:"agents"`
+	TimeoutDuration time.Duration `koanf:"timeout_duration"`
+	OnTimeout       string        `koanf:"on_timeout"`
+}
+
+// FenceAgent stops a peer from signing with the active identity
+type FenceAgent struct {
+	Name string `koanf:"name"`
+	Type string `koanf:"type"`
+	// Command is the local command to run for command agents and the remote command for ssh agents
+	Command string            `koanf:"command"`
+	Args    []string          `koanf:"args"`
+	Env     map[string]string `koanf:"env"`
+	// URL, Method, Headers and Body are the request made by http agents
+	URL     string            `koanf:"url"`
+	Method  string            `koanf:"method"`
+	Headers map[string]string `koanf:"headers"`
+	Body    string            `koanf:"body"`
+	// User, Port and IdentityFile are how ssh agents connect to the peer
+	User         string `koanf:"user"`
+	Port         int    `koanf:"port"`
+	IdentityFile string `koanf:"identity_file"`
+}
+
+// FenceTemplateData represents data available for fence agent templates
+type FenceTemplateData struct {
+	PeerName             string
+	PeerIP               string
+	ActiveIdentityPubkey string
+	SelfName             string
+}
+
+// Enabled returns true if there is any way to fence - agents, or peers with an rpc_url to check
+func (f *Fencing) Enabled(peers Peers) bool {
+	if len(f.Agents) > 0 {
+		return true
+	}
+	for _, peer := range peers {
+		if peer.RPCURL != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate validates the fencing configuration
+func (f *Fencing) Validate() error {
+	// failover.fencing.timeout_duration must not be negative
+	if f.TimeoutDuration < 0 {
+		return fmt.Errorf("failover.fencing.timeout_duration must not be negative")
+	}
+
+	// failover.fencing.on_timeout must be a known policy if set
+	if f.OnTimeout != "" && !slices.Contains(validFencingOnTimeoutPolicies, f.OnTimeout) {
+		return fmt.Errorf("failover.fencing.on_timeout must be one of %s", strings.Join(validFencingOnTimeoutPolicies, ", "))
+	}
+
+	// failover.fencing.agents must all be valid
+	for i, agent := range f.Agents {
+		if err := agent.Validate(); err != nil {
+			return fmt.Errorf("failover.fencing.agents[%d]: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// SetDefaults sets default values for the fencing configuration
+func (f *Fencing) SetDefaults() {
+	if f.TimeoutDuration == 0 {
+		f.TimeoutDuration = 30 * time.Second
+	}
+	if f.OnTimeout == "" {
+		f.OnTimeout = FencingOnTimeoutRefuse
+	}
+	for i := range f.Agents {
+		if f.Agents[i].Type == FenceAgentTypeHTTP && f.Agents[i].Method == "" {
+			f.Agents[i].Method = http.MethodPost
+		}
+	}
+}
+
+// Validate validates the fence agent configuration
+func (a *FenceAgent) Validate() error {
+	// agent.name must be defined
+	if a.Name == "" {
+		return fmt.Errorf("must have a name")
+	}
+
+	switch a.Type {
+	case FenceAgentTypeCommand, FenceAgentTypeSSH:
+		if a.Command == "" {
+			return fmt.Errorf("%s agents must have a command", a.Type)
+		}
+	case FenceAgentTypeHTTP:
+		if a.URL == "" {
+			return fmt.Errorf("http agents must have a url")
+		}
+	default:
+		return fmt.Errorf("type must be one of %s", strings.Join(validFenceAgentTypes, ", "))
+	}
+
+	// agent.port must be a valid port if set
+	if a.Port < 0 || a.Port > 65535 {
+		return fmt.Errorf("port must be between 1 and 65535")
+	}
+
+	return nil
+}
+
+// Render returns a copy of the agent with its command, args, env, url, headers and body rendered with data
+func (a FenceAgent) Render(data FenceTemplateData) (rendered FenceAgent, err error) {
+	rendered = a
+
+	render := func(field, templateStr string) (string, error) {
+		tmpl, err := template.New(field).Parse(templateStr)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse %s template: %w", field, err)
+		}
+		var buf strings.Builder
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("failed to execute %s template: %w", field, err)
+		}
+		return buf.String(), nil
+	}
+
+	if rendered.Command, err = render("command", a.Command); err != nil {
+		return rendered, err
+	}
+	if rendered.URL, err = render("url", a.URL); err != nil {
+		return rendered, err
+	}
+	if rendered.Body, err = render("body", a.Body); err != nil {
+		return rendered, err
+	}
+
+	rendered.Args = make([]string, len(a.Args))
+	for i, arg := range a.Args {
+		if rendered.Args[i], err = render(fmt.Sprintf("args[%d]", i), arg); err != nil {
+			return rendered, err
+		}
+	}
+
+	rendered.Env = make(map[string]string, len(a.Env))
+	for key, value := range a.Env {
+		if rendered.Env[key], err = render(fmt.Sprintf("env[%s]", key), value); err != nil {
+			return rendered, err
+		}
+	}
+
+	rendered.Headers = make(map[string]string, len(a.Headers))
+	for key, value := range a.Headers {
+		if rendered.Headers[key], err = render(fmt.Sprintf("headers[%s]", key), value); err != nil {
+			return rendered, err
+		}
+	}
+
+	return rendered, nil
+}
+
+// SSHArgs returns the ssh args to run the agent's command on host
+func (a *FenceAgent) SSHArgs(host string) []string {
+	args := []string{"-o", "BatchMode=yes"}
+	if a.Port > 0 {
+		args = append(args, "-p", strconv.Itoa(a.Port))
+	}
+	if a.IdentityFile != "" {
+		args = append(args, "-i", a.IdentityFile)
+	}
+
+	destination := host
+	if a.User != "" {
+		destination = a.User + "@" + host
+	}
+	args = append(args, destination, a.Command)
+
+	return append(args, a.Args...)
+}
+
+// validateRPCURL returns an error if rpcURL is not an http(s) URL
+func validateRPCURL(rpcURL string) error {
+	parsed, err := url.Parse(rpcURL)
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("scheme must be http or https")
+	}
+	if parsed.Host == "" {
+		return fmt.Errorf("host must be set")
+	}
+	return nil
+}
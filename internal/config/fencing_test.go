@@ -0,0 +1,151 @@
+package config
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFencing_SetDefaults(t *testing.T) {
+	fencing := &Fencing{
+		Agents: []FenceAgent{
+			{Name: "pdu", Type: FenceAgentTypeHTTP, URL: "https://pdu.example.com/off"},
+			{Name: "bmc", Type: FenceAgentTypeCommand, Command: "ipmitool"},
+		},
+	}
+	fencing.SetDefaults()
+
+	assert.Equal(t, 30*time.Second, fencing.TimeoutDuration)
+	assert.Equal(t, FencingOnTimeoutRefuse, fencing.OnTimeout)
+	assert.Equal(t, http.MethodPost, fencing.Agents[0].Method)
+	assert.Empty(t, fencing.Agents[1].Method)
+}
+
+func TestFencing_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		fencing Fencing
+		wantErr string
+	}{
+		{
+			name: "valid",
+			fencing: Fencing{Agents: []FenceAgent{
+				{Name: "bmc", Type: FenceAgentTypeCommand, Command: "ipmitool"},
+				{Name: "pdu", Type: FenceAgentTypeHTTP, URL: "https://pdu.example.com/off"},
+				{Name: "stop", Type: FenceAgentTypeSSH, Command: "systemctl", Port: 2222},
+			}},
+		},
+		{
+			name:    "negative timeout",
+			fencing: Fencing{TimeoutDuration: -time.Second},
+			wantErr: "failover.fencing.timeout_duration must not be negative",
+		},
+		{
+			name:    "unknown on_timeout",
+			fencing: Fencing{OnTimeout: "shrug"},
+			wantErr: "failover.fencing.on_timeout must be one of refuse, proceed",
+		},
+		{
+			name:    "agent without name",
+			fencing: Fencing{Agents: []FenceAgent{{Type: FenceAgentTypeCommand, Command: "ipmitool"}}},
+			wantErr: "failover.fencing.agents[0]: must have a name",
+		},
+		{
+			name:    "unknown agent type",
+			fencing: Fencing{Agents: []FenceAgent{{Name: "stonith", Type: "laser"}}},
+			wantErr: "failover.fencing.agents[0]: type must be one of command, http, ssh",
+		},
+		{
+			name:    "ssh agent without command",
+			fencing: Fencing{Agents: []FenceAgent{{Name: "stop", Type: FenceAgentTypeSSH}}},
+			wantErr: "failover.fencing.agents[0]: ssh agents must have a command",
+		},
+		{
+			name:    "http agent without url",
+			fencing: Fencing{Agents: []FenceAgent{{Name: "pdu", Type: FenceAgentTypeHTTP}}},
+			wantErr: "failover.fencing.agents[0]: http agents must have a url",
+		},
+		{
+			name:    "invalid port",
+			fencing: Fencing{Agents: []FenceAgent{{Name: "stop", Type: FenceAgentTypeSSH, Command: "systemctl", Port: 70000}}},
+			wantErr: "failover.fencing.agents[0]: port must be between 1 and 65535",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.fencing.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestFencing_Enabled(t *testing.T) {
+	peers := Peers{"peer1": {Name: "peer1", IP: "192.168.1.10"}}
+
+	fencing := &Fencing{}
+	assert.False(t, fencing.Enabled(peers))
+
+	peers["peer1"] = Peer{Name: "peer1", IP: "192.168.1.10", RPCURL: "http://192.168.1.10:8899"}
+	assert.True(t, fencing.Enabled(peers))
+
+	fencing.Agents = []FenceAgent{{Name: "bmc", Type: FenceAgentTypeCommand, Command: "ipmitool"}}
+	assert.True(t, fencing.Enabled(Peers{}))
+}
+
+func TestFenceAgent_Render(t *testing.T) {
+	agent := FenceAgent{
+		Name:    "pdu",
+		Type:    FenceAgentTypeHTTP,
+		URL:     "https://pdu.example.com/outlets/{{ .PeerName }}/off",
+		Headers: map[string]string{"X-Requested-By": "{{ .SelfName }}"},
+		Body:    `{"ip": "{{ .PeerIP }}", "identity": "{{ .ActiveIdentityPubkey }}"}`,
+		Args:    []string{"{{ .PeerIP }}"},
+	}
+
+	rendered, err := agent.Render(FenceTemplateData{
+		PeerName:             "validator-2",
+		PeerIP:               "192.168.1.11",
+		ActiveIdentityPubkey: "active-pubkey",
+		SelfName:             "validator-1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "https://pdu.example.com/outlets/validator-2/off", rendered.URL)
+	assert.Equal(t, "validator-1", rendered.Headers["X-Requested-By"])
+	assert.Equal(t, `{"ip": "192.168.1.11", "identity": "active-pubkey"}`, rendered.Body)
+	assert.Equal(t, []string{"192.168.1.11"}, rendered.Args)
+
+	// the agent itself is left as configured
+	assert.Equal(t, "{{ .PeerIP }}", agent.Args[0])
+
+	agent.URL = "{{ .Nope"
+	_, err = agent.Render(FenceTemplateData{})
+	assert.ErrorContains(t, err, "failed to parse url template")
+}
+
+func TestFenceAgent_SSHArgs(t *testing.T) {
+	agent := FenceAgent{
+		Name:         "stop",
+		Type:         FenceAgentTypeSSH,
+		Command:      "systemctl",
+		Args:         []string{"stop", "solana-validator"},
+		User:         "solana",
+		Port:         2222,
+		IdentityFile: "/home/solana/.ssh/fence",
+	}
+
+	assert.Equal(t, []string{
+		"-o", "BatchMode=yes",
+		"-p", "2222",
+		"-i", "/home/solana/.ssh/fence",
+		"solana@192.168.1.11",
+		"systemctl", "stop", "solana-validator",
+	}, agent.SSHArgs("192.168.1.11"))
+}
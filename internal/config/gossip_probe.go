@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// GossipProbeStrategyTCP dials peers' gossip address over TCP
+	GossipProbeStrategyTCP = "tcp"
+	// GossipProbeStrategyUDPPing sends peers a signed gossip ping over UDP and waits for their pong
+	GossipProbeStrategyUDPPing = "udp-ping"
+	// GossipProbeStrategyNone trusts the cluster RPC's view of gossip without probing peers
+	GossipProbeStrategyNone = "none"
+)
+
+var validGossipProbeStrategies = []string{
+	GossipProbeStrategyTCP,
+	GossipProbeStrategyUDPPing,
+	GossipProbeStrategyNone,
+}
+
+// GossipProbe is how peers listed in gossip are confirmed alive on their gossip address
+type GossipProbe struct {
+	Strategy        string        `koanf:"strategy"`
+	TimeoutDuration time.Duration `koanf:"timeout_duration"`
+}
+
+// Validate validates the gossip probe configuration
+func (g *GossipProbe) Validate() error {
+	// failover.gossip_probe.strategy must be a known strategy if set
+	if g.Strategy != "" && !slices.Contains(validGossipProbeStrategies, g.Strategy) {
+		return fmt.Errorf("failover.gossip_probe.strategy must be one of %s", strings.Join(validGossipProbeStrategies, ", "))
+	}
+
+	// failover.gossip_probe.timeout_duration must not be negative
+	if g.TimeoutDuration < 0 {
+		return fmt.Errorf("failover.gossip_probe.timeout_duration must not be negative")
+	}
+
+	return nil
+}
+
+// SetDefaults sets default values for the gossip probe configuration
+func (g *GossipProbe) SetDefaults() {
+	if g.Strategy == "" {
+		g.Strategy = GossipProbeStrategyTCP
+	}
+	if g.TimeoutDuration == 0 {
+		g.TimeoutDuration = time.Second
+	}
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGossipProbe_SetDefaults(t *testing.T) {
+	probe := &GossipProbe{}
+	probe.SetDefaults()
+
+	assert.Equal(t, GossipProbeStrategyTCP, probe.Strategy)
+	assert.Equal(t, time.Second, probe.TimeoutDuration)
+
+	probe = &GossipProbe{Strategy: GossipProbeStrategyUDPPing, TimeoutDuration: 500 * time.Millisecond}
+	probe.SetDefaults()
+
+	assert.Equal(t, GossipProbeStrategyUDPPing, probe.Strategy)
+	assert.Equal(t, 500*time.Millisecond, probe.TimeoutDuration)
+}
+
+func TestGossipProbe_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		probe   GossipProbe
+		wantErr string
+	}{
+		{
+			name:  "valid",
+			probe: GossipProbe{Strategy: GossipProbeStrategyUDPPing, TimeoutDuration: time.Second},
+		},
+		{
+			name:    "unknown strategy",
+			probe:   GossipProbe{Strategy: "icmp"},
+			wantErr: "failover.gossip_probe.strategy must be one of tcp, udp-ping, none",
+		},
+		{
+			name:    "negative timeout",
+			probe:   GossipProbe{TimeoutDuration: -time.Second},
+			wantErr: "failover.gossip_probe.timeout_duration must not be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.probe.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
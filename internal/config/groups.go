@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/charmbracelet/log"
+)
+
+// groupNameRegexp is what group names must match - they appear in HA API paths and metric labels
+var groupNameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Group is a validator HA group - a validator and its peers managed independently of other groups
+type Group struct {
+	// Validator is the local validator of the group
+	Validator Validator `koanf:"validator"`
+	// Cluster is the Solana cluster the group's validator runs on
+	Cluster Cluster `koanf:"cluster"`
+	// Failover is the failover decision parameters of the group
+	Failover Failover `koanf:"failover"`
+}
+
+// GroupConfigs returns a config for each of groups sorted by group name, or the config itself without
+// groups - Initialize must have been called
+func (c *Config) GroupConfigs() []*Config {
+	if len(c.Groups) == 0 {
+		return []*Config{c}
+	}
+	return c.groupConfigs
+}
+
+// GroupConfig returns the config of the named group, or the config itself without groups when name is empty
+func (c *Config) GroupConfig(name string) (*Config, error) {
+	if len(c.Groups) == 0 {
+		if name != "" {
+			return nil, fmt.Errorf("group %s not found - there are no groups configured", name)
+		}
+		return c, nil
+	}
+
+	if name == "" {
+		if len(c.groupConfigs) == 1 {
+			return c.groupConfigs[0], nil
+		}
+		return nil, fmt.Errorf("more than one group configured - a group name is required")
+	}
+
+	for _, groupConfig := range c.groupConfigs {
+		if groupConfig.Group == name {
+			return groupConfig, nil
+		}
+	}
+	return nil, fmt.Errorf("group %s not found in groups", name)
+}
+
+// APIPath returns path on the HA API for this config's group - all groups share the one HA API server
+// with their paths under /groups/<name>, without groups path is returned as is
+func (c *Config) APIPath(path string) string {
+	if c.Group == "" {
+		return path
+	}
+	return "/groups/" + c.Group + path
+}
+
+// initializeGroups initializes a config for each of groups, sharing the top-level log and prometheus
+func (c *Config) initializeGroups() error {
+	if c.Validator.Name != "" || c.Cluster.Name != "" || len(c.Failover.Peers) > 0 {
+		return fmt.Errorf("validator, cluster and failover must be configured within each of groups when groups are configured")
+	}
+
+	c.Log.SetDefaults()
+	c.Prometheus.SetDefaults()
+
+	if err := c.Log.Validate(); err != nil {
+		return err
+	}
+	if err := c.Prometheus.Validate(); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(c.Groups))
+	for name := range c.Groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	c.groupConfigs = nil
+	stateDirs := map[string]string{}
+	for _, name := range names {
+		if !groupNameRegexp.MatchString(name) {
+			return fmt.Errorf("groups.%s: group names must be lowercase letters, digits, - and _", name)
+		}
+
+		group := c.Groups[name]
+		groupConfig := &Config{
+			Log:             c.Log,
+			Validator:       group.Validator,
+			Cluster:         group.Cluster,
+			Prometheus:      c.Prometheus,
+			Failover:        group.Failover,
+			File:            c.File,
+			Group:           name,
+			GetPublicIPFunc: c.GetPublicIPFunc,
+			logger:          log.WithPrefix(fmt.Sprintf("config %s", name)),
+		}
+
+		// groups must not share flap damping state and event journals
+		if groupConfig.Failover.StateDir == "" {
+			groupConfig.Failover.StateDir = filepath.Join(DefaultStateDir, name)
+		}
+
+		if err := groupConfig.Initialize(); err != nil {
+			return fmt.Errorf("groups.%s: %w", name, err)
+		}
+
+		if other, ok := stateDirs[groupConfig.Failover.StateDir]; ok {
+			return fmt.Errorf("groups.%s.failover.state_dir must differ from groups.%s.failover.state_dir", name, other)
+		}
+		stateDirs[groupConfig.Failover.StateDir] = name
+
+		c.groupConfigs = append(c.groupConfigs, groupConfig)
+	}
+
+	return nil
+}
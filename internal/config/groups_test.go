@@ -0,0 +1,180 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTempGroupsConfigFile creates a config file with a mainnet and a testnet group, with extra
+// appended to the end of the file
+func createTempGroupsConfigFile(t *testing.T, extra string) string {
+	identityFiles := map[string]string{}
+	for _, name := range []string{"mainnet-active", "mainnet-passive", "testnet-active", "testnet-passive"} {
+		identityFiles[name] = createTempIdentityFile(t)
+	}
+	t.Cleanup(func() {
+		for _, file := range identityFiles {
+			os.Remove(file)
+		}
+	})
+
+	content := `
+prometheus:
+  port: 9090
+
+groups:
+  testnet:
+    validator:
+      name: "testnet-validator"
+      rpc_url: "http://localhost:9899"
+      identities:
+        active: "` + identityFiles["testnet-active"] + `"
+        passive: "` + identityFiles["testnet-passive"] + `"
+    cluster:
+      name: "testnet"
+    failover:
+      active:
+        command: "testnet-active"
+      passive:
+        command: "testnet-passive"
+      peers:
+        testnet-2:
+          ip: "192.168.1.11"
+          pubkey: "Vote111111111111111111111111111111111111111"
+  mainnet:
+    validator:
+      name: "mainnet-validator"
+      identities:
+        active: "` + identityFiles["mainnet-active"] + `"
+        passive: "` + identityFiles["mainnet-passive"] + `"
+    cluster:
+      name: "mainnet-beta"
+    failover:
+      takeover_quorum: true
+      active:
+        command: "mainnet-active"
+        args: ["{{ .SelfName }}"]
+      passive:
+        command: "mainnet-passive"
+      peers:
+        mainnet-2:
+          ip: "192.168.1.10"
+          pubkey: "Stake11111111111111111111111111111111111111"
+` + extra
+
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o600))
+	return file
+}
+
+func TestGroups_Initialize(t *testing.T) {
+	cfg, err := NewFromConfigFile(createTempGroupsConfigFile(t, ""))
+	require.NoError(t, err)
+
+	groupConfigs := cfg.GroupConfigs()
+	require.Len(t, groupConfigs, 2)
+
+	// sorted by group name
+	mainnet, testnet := groupConfigs[0], groupConfigs[1]
+	assert.Equal(t, "mainnet", mainnet.Group)
+	assert.Equal(t, "testnet", testnet.Group)
+
+	// each group has its own validator, cluster and failover
+	assert.Equal(t, "mainnet-validator", mainnet.Validator.Name)
+	assert.Equal(t, "testnet-validator", testnet.Validator.Name)
+	assert.Equal(t, "http://localhost:9899", testnet.Validator.RPCURL)
+	assert.Equal(t, "mainnet-beta", mainnet.Cluster.Name)
+	assert.Equal(t, "testnet", testnet.Cluster.Name)
+	assert.True(t, mainnet.Failover.TakeoverQuorum)
+	assert.False(t, testnet.Failover.TakeoverQuorum)
+	assert.Contains(t, mainnet.Failover.Peers, "mainnet-2")
+	assert.NotContains(t, mainnet.Failover.Peers, "testnet-2")
+	assert.NotEqual(t, mainnet.Validator.Identities.ActiveKeyPair.PublicKey(), testnet.Validator.Identities.ActiveKeyPair.PublicKey())
+
+	// defaults and role commands are applied per group
+	assert.Equal(t, []string{"mainnet-validator"}, mainnet.Failover.Active.Args)
+	assert.Equal(t, filepath.Join(DefaultStateDir, "mainnet"), mainnet.Failover.StateDir)
+	assert.Equal(t, filepath.Join(DefaultStateDir, "testnet"), testnet.Failover.StateDir)
+
+	// prometheus is shared
+	assert.Equal(t, 9090, mainnet.Prometheus.Port)
+	assert.Equal(t, 9090, testnet.Prometheus.Port)
+
+	// HA API paths are per group
+	assert.Equal(t, "/groups/mainnet/vote", mainnet.APIPath("/vote"))
+	assert.Equal(t, "/vote", cfg.APIPath("/vote"))
+}
+
+func TestGroups_GroupConfig(t *testing.T) {
+	cfg, err := NewFromConfigFile(createTempGroupsConfigFile(t, ""))
+	require.NoError(t, err)
+
+	testnet, err := cfg.GroupConfig("testnet")
+	require.NoError(t, err)
+	assert.Equal(t, "testnet-validator", testnet.Validator.Name)
+
+	_, err = cfg.GroupConfig("devnet")
+	assert.ErrorContains(t, err, "group devnet not found")
+
+	_, err = cfg.GroupConfig("")
+	assert.ErrorContains(t, err, "a group name is required")
+
+	// without groups the config is its own only group
+	ungrouped := &Config{}
+	self, err := ungrouped.GroupConfig("")
+	require.NoError(t, err)
+	assert.Same(t, ungrouped, self)
+	assert.Equal(t, []*Config{ungrouped}, ungrouped.GroupConfigs())
+
+	_, err = ungrouped.GroupConfig("mainnet")
+	assert.ErrorContains(t, err, "there are no groups configured")
+}
+
+func TestGroups_InitializeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		extra string
+		error string
+	}{
+		{
+			name:  "top-level validator",
+			extra: "validator:\n  name: top-level\n",
+			error: "must be configured within each of groups",
+		},
+		{
+			name:  "invalid group name",
+			extra: "  MainNet:\n    validator:\n      name: invalid\n",
+			error: "groups.MainNet: group names must be",
+		},
+		{
+			name:  "invalid group config",
+			extra: "  devnet:\n    validator:\n      name: devnet-validator\n",
+			error: "groups.devnet:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFromConfigFile(createTempGroupsConfigFile(t, tt.extra))
+			assert.ErrorContains(t, err, tt.error)
+		})
+	}
+}
+
+func TestGroups_SharedStateDir(t *testing.T) {
+	cfg, err := New(NewConfigParams{})
+	require.NoError(t, err)
+	require.NoError(t, cfg.LoadFromFile(createTempGroupsConfigFile(t, "")))
+
+	for name, group := range cfg.Groups {
+		group.Failover.StateDir = "/var/lib/shared"
+		cfg.Groups[name] = group
+	}
+
+	err = cfg.Initialize()
+	assert.ErrorContains(t, err, "groups.testnet.failover.state_dir must differ from groups.mainnet.failover.state_dir")
+}
@@ -1,7 +1,9 @@
 package config
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/iancoleman/strcase"
@@ -11,8 +13,9 @@ import (
 
 // Hooks represents a pre/post hook command
 type Hooks struct {
-	Pre  []Hook `koanf:"pre"`
-	Post []Hook `koanf:"post"`
+	Pre      []Hook `koanf:"pre"`
+	Post     []Hook `koanf:"post"`
+	Rollback []Hook `koanf:"rollback"`
 }
 
 // Hook represents a pre/post hook command
@@ -25,17 +28,42 @@ type Hook struct {
 
 // HookRunOptions represents options for running a hook
 type HookRunOptions struct {
+	Ctx          context.Context
 	HookType     string // "pre" or "post"
 	DryRun       bool
 	LoggerPrefix string
 	LoggerArgs   []any
+	// Runner runs the hook command - defaults to command.ExecRunner
+	Runner command.Runner
 }
 
 // HooksRunOptions represents options for running hooks
 type HooksRunOptions struct {
+	Ctx          context.Context
 	DryRun       bool
 	LoggerPrefix string
 	LoggerArgs   []any
+	// Runner runs the hook commands - defaults to command.ExecRunner
+	Runner command.Runner
+	// OnHookRun is called with the result of each hook after it has run, if set
+	OnHookRun func(hookType string, hook Hook, duration time.Duration, err error)
+}
+
+// runHook runs hook and reports its result to opts.OnHookRun
+func (opts HooksRunOptions) runHook(hookType string, hook Hook, loggerArgs []any) error {
+	started := time.Now()
+	err := hook.Run(HookRunOptions{
+		Ctx:          opts.Ctx,
+		HookType:     hookType,
+		DryRun:       opts.DryRun,
+		LoggerPrefix: opts.LoggerPrefix,
+		LoggerArgs:   loggerArgs,
+		Runner:       opts.Runner,
+	})
+	if opts.OnHookRun != nil {
+		opts.OnHookRun(hookType, hook, time.Since(started), err)
+	}
+	return err
 }
 
 // Validate validates the hooks configuration
@@ -54,6 +82,13 @@ func (h *Hooks) Validate() error {
 		}
 	}
 
+	// hooks.rollback must all be valid if defined
+	for i, hook := range h.Rollback {
+		if err := hook.Validate(false); err != nil {
+			return fmt.Errorf("hooks.%s[%d]: %w", constants.HookTypeRollback, i, err)
+		}
+	}
+
 	return nil
 }
 
@@ -89,7 +124,13 @@ func (h *Hook) Run(opts HookRunOptions) error {
 		return nil
 	}
 
-	return command.Run(command.RunOptions{
+	runner := opts.Runner
+	if runner == nil {
+		runner = command.ExecRunner{}
+	}
+
+	return runner.Run(command.RunOptions{
+		Ctx:          opts.Ctx,
 		Name:         fmt.Sprintf("%s-hook %s", opts.HookType, h.Name),
 		Command:      h.Command,
 		Args:         h.Args,
@@ -109,12 +150,7 @@ func (h *Hooks) RunPre(opts HooksRunOptions) error {
 
 	// run pre hooks
 	for _, hook := range h.Pre {
-		err := hook.Run(HookRunOptions{
-			HookType:     constants.HookTypePre,
-			DryRun:       opts.DryRun,
-			LoggerPrefix: opts.LoggerPrefix,
-			LoggerArgs:   loggerArgs,
-		})
+		err := opts.runHook(constants.HookTypePre, hook, loggerArgs)
 		if err != nil && hook.MustSucceed {
 			return err
 		}
@@ -135,12 +171,23 @@ func (h *Hooks) RunPost(opts HooksRunOptions) {
 
 	// run post hooks - failures are logged but not returned
 	for _, hook := range h.Post {
-		err := hook.Run(HookRunOptions{
-			HookType:     constants.HookTypePost,
-			DryRun:       opts.DryRun,
-			LoggerPrefix: opts.LoggerPrefix,
-			LoggerArgs:   loggerArgs,
-		})
+		err := opts.runHook(constants.HookTypePost, hook, loggerArgs)
+		if err != nil {
+			log.Error("hook failed", loggerArgs...)
+		}
+	}
+}
+
+// RunRollback runs the rollback hooks
+func (h *Hooks) RunRollback(opts HooksRunOptions) {
+	loggerArgs := []any{
+		"hook_type", constants.HookTypeRollback,
+	}
+	loggerArgs = append(loggerArgs, opts.LoggerArgs...)
+
+	// run rollback hooks - failures are logged but not returned
+	for _, hook := range h.Rollback {
+		err := opts.runHook(constants.HookTypeRollback, hook, loggerArgs)
 		if err != nil {
 			log.Error("hook failed", loggerArgs...)
 		}
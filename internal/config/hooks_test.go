@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -39,6 +40,15 @@ func TestHooks_Validate(t *testing.T) {
 	err = hooks.Validate()
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "hooks.post[0]: must have a name")
+
+	// Test with rollback hook using must_succeed (not allowed)
+	hooks.Post[0].Name = "post-hook"
+	hooks.Rollback = []Hook{
+		{Name: "rollback-hook", Command: "echo 'rollback'", MustSucceed: true},
+	}
+	err = hooks.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "hooks.rollback[0]: hook must_succeed not allowed")
 }
 
 func TestHook_Validate(t *testing.T) {
@@ -124,3 +134,44 @@ func TestHooks_RunPost(t *testing.T) {
 	// Test actual run
 	hooks.RunPost(HooksRunOptions{DryRun: false})
 }
+
+func TestHooks_RunRollback(t *testing.T) {
+	hooks := &Hooks{
+		Rollback: []Hook{
+			{Name: "rollback-hook-1", Command: "echo", Args: []string{"rollback1"}},
+			{Name: "rollback-hook-2", Command: "echo", Args: []string{"rollback2"}},
+		},
+	}
+
+	// Test dry run
+	hooks.RunRollback(HooksRunOptions{DryRun: true})
+
+	// Test actual run
+	hooks.RunRollback(HooksRunOptions{DryRun: false})
+}
+
+func TestHooks_OnHookRun(t *testing.T) {
+	hooks := &Hooks{
+		Pre: []Hook{
+			{Name: "pre-hook-1", Command: "echo", Args: []string{"pre1"}},
+			{Name: "pre-hook-2", Command: "false"},
+		},
+	}
+
+	type hookRun struct {
+		hookType string
+		name     string
+		failed   bool
+	}
+	var runs []hookRun
+	err := hooks.RunPre(HooksRunOptions{
+		OnHookRun: func(hookType string, hook Hook, duration time.Duration, err error) {
+			runs = append(runs, hookRun{hookType: hookType, name: hook.Name, failed: err != nil})
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []hookRun{
+		{hookType: "pre", name: "pre-hook-1", failed: false},
+		{hookType: "pre", name: "pre-hook-2", failed: true},
+	}, runs)
+}
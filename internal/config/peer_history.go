@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+)
+
+// PeerHistory is how many gossip samples are kept per peer and how many availability changes within them make
+// a peer flapping
+type PeerHistory struct {
+	Size                     int `koanf:"size"`
+	FlapTransitionsThreshold int `koanf:"flap_transitions_threshold"`
+}
+
+// Validate validates the peer history configuration
+func (p *PeerHistory) Validate() error {
+	// failover.peer_history.size must not be negative
+	if p.Size < 0 {
+		return fmt.Errorf("failover.peer_history.size must not be negative")
+	}
+
+	// failover.peer_history.flap_transitions_threshold must not be negative
+	if p.FlapTransitionsThreshold < 0 {
+		return fmt.Errorf("failover.peer_history.flap_transitions_threshold must not be negative")
+	}
+
+	// a peer cannot change availability more often than it has samples
+	if p.Size > 0 && p.FlapTransitionsThreshold >= p.Size {
+		return fmt.Errorf("failover.peer_history.flap_transitions_threshold must be less than failover.peer_history.size (%d)", p.Size)
+	}
+
+	return nil
+}
+
+// SetDefaults sets default values for the peer history configuration
+func (p *PeerHistory) SetDefaults() {
+	if p.Size == 0 {
+		p.Size = 120 // 10 minutes at the default poll interval
+	}
+	if p.FlapTransitionsThreshold == 0 {
+		p.FlapTransitionsThreshold = 4 // dropping out of gossip and back twice
+	}
+}
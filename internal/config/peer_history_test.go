@@ -0,0 +1,60 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeerHistory_SetDefaults(t *testing.T) {
+	peerHistory := &PeerHistory{}
+	peerHistory.SetDefaults()
+
+	assert.Equal(t, 120, peerHistory.Size)
+	assert.Equal(t, 4, peerHistory.FlapTransitionsThreshold)
+
+	peerHistory = &PeerHistory{Size: 30, FlapTransitionsThreshold: 6}
+	peerHistory.SetDefaults()
+
+	assert.Equal(t, 30, peerHistory.Size)
+	assert.Equal(t, 6, peerHistory.FlapTransitionsThreshold)
+}
+
+func TestPeerHistory_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		peerHistory PeerHistory
+		wantErr     string
+	}{
+		{
+			name:        "valid",
+			peerHistory: PeerHistory{Size: 120, FlapTransitionsThreshold: 4},
+		},
+		{
+			name:        "negative size",
+			peerHistory: PeerHistory{Size: -1},
+			wantErr:     "failover.peer_history.size must not be negative",
+		},
+		{
+			name:        "negative flap transitions threshold",
+			peerHistory: PeerHistory{FlapTransitionsThreshold: -1},
+			wantErr:     "failover.peer_history.flap_transitions_threshold must not be negative",
+		},
+		{
+			name:        "flap transitions threshold not below size",
+			peerHistory: PeerHistory{Size: 4, FlapTransitionsThreshold: 4},
+			wantErr:     "failover.peer_history.flap_transitions_threshold must be less than failover.peer_history.size (4)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.peerHistory.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -2,6 +2,9 @@ package config
 
 import (
 	"fmt"
+	"maps"
+	"net"
+	"slices"
 	"sort"
 	"strings"
 )
@@ -11,8 +14,20 @@ type Peers map[string]Peer
 
 // Peer represents a peer validator
 type Peer struct {
-	IP   string `koanf:"ip"`
-	Name string `koanf:"-"`
+	IP string `koanf:"ip"`
+	// IPs are further addresses the peer may appear at in gossip, such as its IPv6 address
+	IPs []string `koanf:"ips"`
+	// GossipPort is the port the peer's gossip address must be on - zero for any
+	GossipPort int `koanf:"gossip_port"`
+	// Pubkey is the peer's passive identity - when set, nodes at the peer's addresses presenting neither it
+	// nor the active identity are not taken for the peer
+	Pubkey   string `koanf:"pubkey"`
+	Priority int    `koanf:"priority"`
+	// RPCURL is the peer's validator RPC, checked with getIdentity when fencing it
+	RPCURL string `koanf:"rpc_url"`
+	// Witness is true for peers running in witness mode - they vote on takeovers but never become active
+	Witness bool   `koanf:"witness"`
+	Name    string `koanf:"-"`
 }
 
 // Add adds a peer to the peers map
@@ -20,16 +35,37 @@ func (p *Peers) Add(peer Peer) {
 	(*p)[peer.Name] = peer
 }
 
-// HasIP returns true if the peers map has a peer with the given IP address
+// HasIP returns true if the peers map has a peer with the given IP address among its addresses
 func (p *Peers) HasIP(ip string) bool {
 	for _, peer := range *p {
-		if peer.IP == ip {
+		if peer.HasAddress(net.ParseIP(ip)) {
 			return true
 		}
 	}
 	return false
 }
 
+// Addresses returns the IP addresses the peer may appear at in gossip, IP first
+func (p Peer) Addresses() []string {
+	return append([]string{p.IP}, p.IPs...)
+}
+
+// HasAddress returns true if ip is one of the peer's addresses, however either is written
+func (p Peer) HasAddress(ip net.IP) bool {
+	for _, address := range p.Addresses() {
+		if ip != nil && ip.Equal(net.ParseIP(address)) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesGossipAddress returns true if ip is one of the peer's addresses and port is its gossip port, when it
+// declares one
+func (p Peer) MatchesGossipAddress(ip net.IP, port int) bool {
+	return p.HasAddress(ip) && (p.GossipPort == 0 || p.GossipPort == port)
+}
+
 // String returns a string representation of the peers
 func (p *Peers) String() string {
 	peerStrings := []string{}
@@ -48,17 +84,42 @@ func (p *Peers) GetIPs() []string {
 	return ips
 }
 
-// GetRankedIPs returns the IP addresses in ascending order
-// this is arbitrary but used to impose some portable guaranteed
-// rank among peers without sharing any other configuration
+// Validators returns the peers that run a validator, leaving out witnesses
+func (p *Peers) Validators() Peers {
+	validators := Peers{}
+	for name, peer := range *p {
+		if !peer.Witness {
+			validators[name] = peer
+		}
+	}
+	return validators
+}
+
+// GetRankedPeers returns the peers that run a validator in rank order, best first. Peers are ordered by
+// ascending priority with peers without a priority (zero) after those with one, and ties broken by IP
+// address in ascending order. Every node must declare the same priorities for the rank to be common across
+// all of them. Witnesses never become active so are left out
+func (p *Peers) GetRankedPeers() (rankedPeers []Peer) {
+	rankedPeers = slices.Collect(maps.Values(p.Validators()))
+	sort.Slice(rankedPeers, func(i, j int) bool {
+		iPriority, jPriority := rankedPeers[i].Priority, rankedPeers[j].Priority
+		if iPriority != jPriority {
+			// unset priorities rank last
+			if iPriority == 0 || jPriority == 0 {
+				return jPriority == 0
+			}
+			return iPriority < jPriority
+		}
+		return rankedPeers[i].IP < rankedPeers[j].IP
+	})
+	return rankedPeers
+}
+
+// GetRankedIPs returns the rank (starting at 1) of each peer IP address as ordered by GetRankedPeers
 func (p *Peers) GetRankedIPs() (rankedIPs map[string]int) {
 	rankedIPs = make(map[string]int)
-	ips := p.GetIPs()
-	sort.Strings(ips)
-
-	// ips are sorted in ascending order now
-	for ipIndex, ip := range ips {
-		rankedIPs[ip] = ipIndex + 1
+	for peerIndex, peer := range p.GetRankedPeers() {
+		rankedIPs[peer.IP] = peerIndex + 1
 	}
 
 	return rankedIPs
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"net"
 	"strings"
 	"testing"
 
@@ -61,3 +62,84 @@ func TestPeers_GetIPs(t *testing.T) {
 	ips = emptyPeers.GetIPs()
 	assert.Len(t, ips, 0)
 }
+
+func TestPeers_GetRankedIPs(t *testing.T) {
+	// Test without priorities - ranked by IP
+	peers := &Peers{
+		"validator-1": {Name: "validator-1", IP: "192.168.1.12"},
+		"validator-2": {Name: "validator-2", IP: "192.168.1.10"},
+		"validator-3": {Name: "validator-3", IP: "192.168.1.11"},
+	}
+
+	rankedIPs := peers.GetRankedIPs()
+	assert.Equal(t, map[string]int{
+		"192.168.1.10": 1,
+		"192.168.1.11": 2,
+		"192.168.1.12": 3,
+	}, rankedIPs)
+
+	// Test with priorities - ranked by priority, unset priorities last
+	peers = &Peers{
+		"validator-1": {Name: "validator-1", IP: "192.168.1.10"},
+		"validator-2": {Name: "validator-2", IP: "192.168.1.11", Priority: 2},
+		"validator-3": {Name: "validator-3", IP: "192.168.1.12", Priority: 1},
+		"validator-4": {Name: "validator-4", IP: "192.168.1.9", Priority: 2},
+	}
+
+	rankedIPs = peers.GetRankedIPs()
+	assert.Equal(t, map[string]int{
+		"192.168.1.12": 1,
+		"192.168.1.11": 2,
+		"192.168.1.9":  3,
+		"192.168.1.10": 4,
+	}, rankedIPs)
+
+	rankedPeers := peers.GetRankedPeers()
+	assert.Len(t, rankedPeers, 4)
+	assert.Equal(t, "validator-3", rankedPeers[0].Name)
+	assert.Equal(t, "validator-1", rankedPeers[3].Name)
+
+	// Test with a witness - never ranked as it never becomes active
+	peers = &Peers{
+		"validator-1": {Name: "validator-1", IP: "192.168.1.11"},
+		"witness":     {Name: "witness", IP: "192.168.1.10", Witness: true},
+	}
+	assert.Equal(t, map[string]int{"192.168.1.11": 1}, peers.GetRankedIPs())
+
+	// Test with empty peers
+	emptyPeers := &Peers{}
+	assert.Empty(t, emptyPeers.GetRankedIPs())
+}
+
+func TestPeers_Validators(t *testing.T) {
+	peers := &Peers{
+		"validator-1": {Name: "validator-1", IP: "192.168.1.10"},
+		"validator-2": {Name: "validator-2", IP: "192.168.1.11"},
+		"witness":     {Name: "witness", IP: "192.168.1.12", Witness: true},
+	}
+
+	validators := peers.Validators()
+	assert.Len(t, validators, 2)
+	assert.NotContains(t, validators, "witness")
+	assert.Len(t, *peers, 3)
+}
+
+func TestPeer_Addresses(t *testing.T) {
+	peer := Peer{IP: "192.168.1.10", IPs: []string{"2001:db8::10"}, GossipPort: 8001}
+
+	assert.Equal(t, []string{"192.168.1.10", "2001:db8::10"}, peer.Addresses())
+
+	assert.True(t, peer.HasAddress(net.ParseIP("192.168.1.10")))
+	assert.True(t, peer.HasAddress(net.ParseIP("2001:db8:0:0::10")))
+	assert.False(t, peer.HasAddress(net.ParseIP("192.168.1.11")))
+	assert.False(t, peer.HasAddress(nil))
+
+	assert.True(t, peer.MatchesGossipAddress(net.ParseIP("2001:db8::10"), 8001))
+	assert.False(t, peer.MatchesGossipAddress(net.ParseIP("2001:db8::10"), 8002))
+	peer.GossipPort = 0
+	assert.True(t, peer.MatchesGossipAddress(net.ParseIP("2001:db8::10"), 8002))
+
+	peers := Peers{"validator-1": peer}
+	assert.True(t, peers.HasIP("2001:db8::10"))
+	assert.False(t, peers.HasIP("not-an-ip"))
+}
@@ -18,6 +18,11 @@ func (p *Prometheus) Validate() error {
 	return nil
 }
 
+// HealthCheckPort returns the port the health check and HA API server listens on
+func (p *Prometheus) HealthCheckPort() int {
+	return p.Port + 1
+}
+
 // SetDefaults sets default values for the Prometheus configuration
 func (p *Prometheus) SetDefaults() {
 	// if prometheus.port is 0, set it to the default port
@@ -42,3 +42,9 @@ func TestPrometheus_Validate(t *testing.T) {
 	err = prometheus.Validate()
 	assert.NoError(t, err)
 }
+
+func TestPrometheus_HealthCheckPort(t *testing.T) {
+	prometheus := &Prometheus{Port: 9090}
+
+	assert.Equal(t, 9091, prometheus.HealthCheckPort())
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"text/template"
@@ -27,9 +28,12 @@ type Role struct {
 }
 
 type RoleCommandRunOptions struct {
+	Ctx          context.Context
 	DryRun       bool
 	LoggerPrefix string
 	LoggerArgs   []any
+	// Runner runs the role command - defaults to command.ExecRunner
+	Runner command.Runner
 }
 
 // Validate validates the role configuration
@@ -66,6 +70,14 @@ func (r *Role) RenderCommands(data RoleCommandTemplateData) (err error) {
 		}
 	}
 
+	// render role.hooks.rollback
+	for i := range r.Hooks.Rollback {
+		err = r.renderHook(data, &r.Hooks.Rollback[i])
+		if err != nil {
+			return fmt.Errorf("failed to render role.hooks.rollback[%d]: %w", i, err)
+		}
+	}
+
 	return nil
 }
 
@@ -140,7 +152,13 @@ func (r *Role) RunCommand(opts RoleCommandRunOptions) error {
 		return nil
 	}
 
-	err := command.Run(command.RunOptions{
+	runner := opts.Runner
+	if runner == nil {
+		runner = command.ExecRunner{}
+	}
+
+	err := runner.Run(command.RunOptions{
+		Ctx:          opts.Ctx,
 		Name:         r.Name,
 		Command:      r.Command,
 		Args:         r.Args,
@@ -1,9 +1,12 @@
 package config
 
 import (
+	"errors"
 	"testing"
 
+	"github.com/sol-strategies/solana-validator-ha/internal/command"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRole_Validate(t *testing.T) {
@@ -127,3 +130,40 @@ func TestRole_RenderTemplateString(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to execute command template")
 }
+
+// recordingRunner records the commands it is asked to run
+type recordingRunner struct {
+	runs []command.RunOptions
+	err  error
+}
+
+func (r *recordingRunner) Run(opts command.RunOptions) error {
+	r.runs = append(r.runs, opts)
+	return r.err
+}
+
+func TestRole_RunCommandWithRunner(t *testing.T) {
+	role := &Role{Name: "active", Command: "set-identity", Args: []string{"active"}}
+	runner := &recordingRunner{}
+
+	require.NoError(t, role.RunCommand(RoleCommandRunOptions{Runner: runner}))
+	require.Len(t, runner.runs, 1)
+	assert.Equal(t, "set-identity", runner.runs[0].Command)
+	assert.Equal(t, []string{"active"}, runner.runs[0].Args)
+
+	// dry run never reaches the runner
+	require.NoError(t, role.RunCommand(RoleCommandRunOptions{Runner: runner, DryRun: true}))
+	assert.Len(t, runner.runs, 1)
+
+	runner.err = errors.New("exit status 1")
+	assert.ErrorContains(t, role.RunCommand(RoleCommandRunOptions{Runner: runner}), "exit status 1")
+}
+
+func TestHooks_RunWithRunner(t *testing.T) {
+	hooks := &Hooks{Pre: []Hook{{Name: "notify", Command: "notify-send", MustSucceed: true}}}
+	runner := &recordingRunner{err: errors.New("exit status 2")}
+
+	assert.Error(t, hooks.RunPre(HooksRunOptions{Runner: runner}))
+	require.Len(t, runner.runs, 1)
+	assert.Equal(t, "notify-send", runner.runs[0].Command)
+}
@@ -6,6 +6,7 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 
 	"github.com/charmbracelet/log"
@@ -19,12 +20,27 @@ var publicIPServices = []string{
 	"https://4.icanhazip.com",
 }
 
+const (
+	// ValidatorModeValidator manages a local validator, running the role commands to change its identity
+	ValidatorModeValidator = "validator"
+	// ValidatorModeWitness runs without a local validator, only taking part in takeover votes and exporting
+	// gossip-derived metrics - e.g. a tie-breaker at a third site
+	ValidatorModeWitness = "witness"
+)
+
+var validValidatorModes = []string{
+	ValidatorModeValidator,
+	ValidatorModeWitness,
+}
+
 // Validator represents the local validator configuration
 type Validator struct {
 	Name                string              `koanf:"name"`
+	Mode                string              `koanf:"mode"`
 	RPCURL              string              `koanf:"rpc_url"`
 	PublicIPServiceURLs []string            `koanf:"public_ip_service_urls"`
 	Identities          ValidatorIdentities `koanf:"identities"`
+	BalanceMonitor      BalanceMonitor      `koanf:"balance_monitor"`
 }
 
 // ValidatorIdentities represents the identities for the validator
@@ -33,6 +49,18 @@ type ValidatorIdentities struct {
 	ActiveKeyPair      *solanago.PrivateKey `koanf:"-"`
 	PassiveKeyPairFile string               `koanf:"passive"`
 	PassiveKeyPair     *solanago.PrivateKey `koanf:"-"`
+	// ActivePubkey is the active identity public key of witnesses, which hold no key pairs
+	ActivePubkey string `koanf:"active_pubkey"`
+}
+
+// ActivePublicKey returns the active identity public key - from the active key pair when loaded, else from
+// active_pubkey
+func (v *ValidatorIdentities) ActivePublicKey() solanago.PublicKey {
+	if v.ActiveKeyPair != nil {
+		return v.ActiveKeyPair.PublicKey()
+	}
+	publicKey, _ := solanago.PublicKeyFromBase58(v.ActivePubkey)
+	return publicKey
 }
 
 // Load loads the identities from the key pair files
@@ -67,6 +95,22 @@ func (v *Validator) Validate() error {
 		return fmt.Errorf("validator.name must be defined")
 	}
 
+	// validator.mode must be a known mode if set
+	if v.Mode != "" && !slices.Contains(validValidatorModes, v.Mode) {
+		return fmt.Errorf("validator.mode must be one of %s", strings.Join(validValidatorModes, ", "))
+	}
+
+	// witnesses have no validator rpc or key pairs, only the active identity public key
+	if v.IsWitness() {
+		if _, err := solanago.PublicKeyFromBase58(v.Identities.ActivePubkey); err != nil {
+			return fmt.Errorf("validator.identities.active_pubkey must be a valid public key in witness mode: %w", err)
+		}
+		if err := v.validatePublicIPServiceURLs(); err != nil {
+			return err
+		}
+		return v.BalanceMonitor.Validate()
+	}
+
 	// validator.rpc_url must be a valid URL
 	if v.RPCURL == "" {
 		return fmt.Errorf("validator.rpc_url must be a valid URL")
@@ -80,6 +124,24 @@ func (v *Validator) Validate() error {
 		return fmt.Errorf("validator.rpc_url must be a valid URL: invalid URL %s", v.RPCURL)
 	}
 
+	if err := v.validatePublicIPServiceURLs(); err != nil {
+		return err
+	}
+
+	if err := v.BalanceMonitor.Validate(); err != nil {
+		return err
+	}
+
+	// Only validate identities if they've been loaded
+	if v.Identities.ActiveKeyPair != nil && v.Identities.PassiveKeyPair != nil {
+		return v.Identities.Validate()
+	}
+
+	return nil
+}
+
+// validatePublicIPServiceURLs validates validator.public_ip_service_urls
+func (v *Validator) validatePublicIPServiceURLs() error {
 	// validator.public_ip_service_urls must be a valid URL
 	for _, publicIPServiceURL := range v.PublicIPServiceURLs {
 		parsedURL, err := url.Parse(publicIPServiceURL)
@@ -91,16 +153,20 @@ func (v *Validator) Validate() error {
 		}
 	}
 
-	// Only validate identities if they've been loaded
-	if v.Identities.ActiveKeyPair != nil && v.Identities.PassiveKeyPair != nil {
-		return v.Identities.Validate()
-	}
-
 	return nil
 }
 
+// IsWitness returns true if validator.mode is witness
+func (v *Validator) IsWitness() bool {
+	return v.Mode == ValidatorModeWitness
+}
+
 // SetDefaults sets default values for the validator configuration
 func (v *Validator) SetDefaults() {
+	if v.Mode == "" {
+		v.Mode = ValidatorModeValidator
+	}
+
 	// Set default validator RPC URL
 	if v.RPCURL == "" {
 		v.RPCURL = "http://localhost:8899"
@@ -109,6 +175,8 @@ func (v *Validator) SetDefaults() {
 	if len(v.PublicIPServiceURLs) == 0 {
 		v.PublicIPServiceURLs = publicIPServices
 	}
+
+	v.BalanceMonitor.SetDefaults()
 }
 
 // PublicIP returns the public IP address of the validator using the public IP service URLs
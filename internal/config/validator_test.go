@@ -13,6 +13,8 @@ func TestValidator_SetDefaults(t *testing.T) {
 	validator.SetDefaults()
 
 	assert.Equal(t, "http://localhost:8899", validator.RPCURL)
+	assert.Equal(t, ValidatorModeValidator, validator.Mode)
+	assert.False(t, validator.IsWitness())
 }
 
 func TestValidator_Validate(t *testing.T) {
@@ -50,6 +52,26 @@ func TestValidator_Validate(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestValidator_ValidateWitness(t *testing.T) {
+	validator := &Validator{
+		Name: "test-witness",
+		Mode: ValidatorModeWitness,
+	}
+
+	// witnesses need the active identity public key
+	err := validator.Validate()
+	assert.ErrorContains(t, err, "validator.identities.active_pubkey must be a valid public key in witness mode")
+
+	// but no rpc_url or identity key pair files
+	validator.Identities.ActivePubkey = "8Pi6ZJ7L9XpZ3uYTTw1H7zHyG1SBkpqAeqaxXSWvh5nE"
+	assert.NoError(t, validator.Validate())
+	assert.True(t, validator.IsWitness())
+	assert.Equal(t, "8Pi6ZJ7L9XpZ3uYTTw1H7zHyG1SBkpqAeqaxXSWvh5nE", validator.Identities.ActivePublicKey().String())
+
+	validator.Mode = "observer"
+	assert.EqualError(t, validator.Validate(), "validator.mode must be one of validator, witness")
+}
+
 func TestValidatorIdentities_Load(t *testing.T) {
 	// Create temporary identity files
 	activeIdentityFile := createTempIdentityFile(t)
@@ -7,6 +7,8 @@ const (
 	RoleNamePassive = "passive"
 	// RoleNameUnknown is the name of the unknown role
 	RoleNameUnknown = "unknown"
+	// RoleNameWitness is the role of nodes running in witness mode, without a validator
+	RoleNameWitness = "witness"
 
 	// StatusHealthy is the name of the healthy status
 	StatusHealthy = "healthy"
@@ -22,4 +24,15 @@ const (
 	HookTypePre = "pre"
 	// HookTypePost is the name of the post hook type
 	HookTypePost = "post"
+	// HookTypeRollback is the name of the rollback hook type
+	HookTypeRollback = "rollback"
+	// HookTypeBalance is the name of the balance monitor hook type
+	HookTypeBalance = "balance"
+
+	// BalanceLevelOK is the level of an account balance above its warn threshold
+	BalanceLevelOK = "ok"
+	// BalanceLevelWarn is the level of an account balance below its warn threshold
+	BalanceLevelWarn = "warn"
+	// BalanceLevelCritical is the level of an account balance below its critical threshold
+	BalanceLevelCritical = "critical"
 )
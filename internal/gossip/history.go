@@ -0,0 +1,102 @@
+package gossip
+
+import (
+	"slices"
+	"time"
+)
+
+const (
+	// defaultHistorySize and defaultFlapTransitionsThreshold apply when Options leave them unset
+	defaultHistorySize              = 120
+	defaultFlapTransitionsThreshold = 4
+)
+
+// PeerSample is what a refresh saw of a config peer
+type PeerSample struct {
+	// SampledAt is when the cluster nodes the sample is from were fetched
+	SampledAt time.Time
+	// Present is true if the peer was listed in gossip
+	Present bool
+	// Alive is true if the peer answered its gossip probe
+	Alive bool
+	// Active is true if the peer presented the active identity
+	Active bool
+	// Voting is true if the peer presented the active identity and was voting
+	Voting bool
+	// VoteLagSlots is how far behind the cluster the peer last voted - only set when Active
+	VoteLagSlots uint64
+}
+
+// Available returns true if the peer was in gossip, alive and, when presenting the active identity, voting
+func (s PeerSample) Available() bool {
+	return s.Present && s.Alive && (!s.Active || s.Voting)
+}
+
+// PeerStats are statistics derived from a peer's sample history
+type PeerStats struct {
+	// Samples is the number of samples the statistics are derived from
+	Samples int
+	// AvailabilityPercent is the percentage of samples the peer was available in
+	AvailabilityPercent float64
+	// Transitions is the number of times the peer's availability changed between samples
+	Transitions int
+	// TransitionsPerHour is Transitions over the time the samples span
+	TransitionsPerHour float64
+	// Flapping is true when Transitions reached the flap transitions threshold
+	Flapping bool
+}
+
+// peerHistory is a bounded ring buffer of a peer's samples
+type peerHistory struct {
+	samples []PeerSample
+	// next is where the next sample goes, overwriting the oldest once the buffer is full
+	next int
+}
+
+// newPeerHistory returns an empty history keeping up to size samples
+func newPeerHistory(size int) *peerHistory {
+	return &peerHistory{samples: make([]PeerSample, 0, size)}
+}
+
+// add records sample, dropping the oldest sample if the history is full
+func (h *peerHistory) add(sample PeerSample) {
+	if len(h.samples) < cap(h.samples) {
+		h.samples = append(h.samples, sample)
+		return
+	}
+	h.samples[h.next] = sample
+	h.next = (h.next + 1) % len(h.samples)
+}
+
+// all returns a copy of the samples, oldest first
+func (h *peerHistory) all() []PeerSample {
+	return slices.Concat(h.samples[h.next:], h.samples[:h.next])
+}
+
+// stats returns the statistics of the samples, flapping when their availability changed at least
+// flapTransitionsThreshold times
+func (h *peerHistory) stats(flapTransitionsThreshold int) (stats PeerStats) {
+	samples := h.all()
+	stats.Samples = len(samples)
+	if stats.Samples == 0 {
+		return stats
+	}
+
+	available := 0
+	for i, sample := range samples {
+		if sample.Available() {
+			available++
+		}
+		if i > 0 && sample.Available() != samples[i-1].Available() {
+			stats.Transitions++
+		}
+	}
+	stats.AvailabilityPercent = float64(available) / float64(stats.Samples) * 100
+
+	if span := samples[len(samples)-1].SampledAt.Sub(samples[0].SampledAt); span > 0 {
+		stats.TransitionsPerHour = float64(stats.Transitions) / span.Hours()
+	}
+	stats.Flapping = stats.Transitions >= flapTransitionsThreshold
+
+	return stats
+}
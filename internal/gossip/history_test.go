@@ -0,0 +1,112 @@
+package gossip
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	solanago "github.com/gagliardetto/solana-go"
+	solanagorpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerSample_Available(t *testing.T) {
+	assert.True(t, PeerSample{Present: true, Alive: true}.Available())
+	assert.True(t, PeerSample{Present: true, Alive: true, Active: true, Voting: true}.Available())
+	assert.False(t, PeerSample{Present: true, Alive: true, Active: true}.Available())
+	assert.False(t, PeerSample{Present: true}.Available())
+	assert.False(t, PeerSample{}.Available())
+}
+
+func TestPeerHistory_Add(t *testing.T) {
+	history := newPeerHistory(3)
+	start := time.Now()
+	for i := range 5 {
+		history.add(PeerSample{SampledAt: start.Add(time.Duration(i) * time.Second)})
+	}
+
+	// only the last three samples are kept, oldest first
+	samples := history.all()
+	require.Len(t, samples, 3)
+	for i, sample := range samples {
+		assert.Equal(t, start.Add(time.Duration(i+2)*time.Second), sample.SampledAt)
+	}
+}
+
+func TestPeerHistory_Stats(t *testing.T) {
+	start := time.Now()
+	available := PeerSample{Present: true, Alive: true}
+	missing := PeerSample{}
+
+	// in, out, in, out over 6 minutes
+	history := newPeerHistory(10)
+	for i, sample := range []PeerSample{available, available, missing, available, missing, missing, available} {
+		sample.SampledAt = start.Add(time.Duration(i) * time.Minute)
+		history.add(sample)
+	}
+
+	stats := history.stats(4)
+	assert.Equal(t, 7, stats.Samples)
+	assert.InDelta(t, 4.0/7*100, stats.AvailabilityPercent, 0.001)
+	assert.Equal(t, 4, stats.Transitions)
+	assert.InDelta(t, 40, stats.TransitionsPerHour, 0.001)
+	assert.True(t, stats.Flapping)
+
+	assert.False(t, history.stats(5).Flapping)
+	assert.Equal(t, PeerStats{}, newPeerHistory(10).stats(4))
+}
+
+func TestRefresh_PeerHistory(t *testing.T) {
+	activePubkey := solanago.NewWallet().PublicKey()
+	passivePubkey := solanago.NewWallet().PublicKey()
+	activeGossipAddress := "192.168.1.2:8001"
+	passiveGossipAddress := "192.168.1.3:8001"
+	activeNode := &solanagorpc.GetClusterNodesResult{Pubkey: activePubkey, Gossip: &activeGossipAddress}
+	passiveNode := &solanagorpc.GetClusterNodesResult{Pubkey: passivePubkey, Gossip: &passiveGossipAddress}
+
+	clusterRPC := &testClusterRPC{}
+	state := NewState(Options{
+		ClusterRPC:   clusterRPC,
+		ActivePubkey: activePubkey.String(),
+		ConfigPeers: map[string]config.Peer{
+			"peer1": {IP: "192.168.1.2", Name: "peer1"},
+			"peer2": {IP: "192.168.1.3", Name: "peer2"},
+		},
+		HistorySize:              4,
+		FlapTransitionsThreshold: 2,
+		DialFunc: func(network, address string) (net.Conn, error) {
+			conn, _ := net.Pipe()
+			return conn, nil
+		},
+	})
+
+	// peer2 drops in and out of gossip every other sample while peer1 stays active
+	for i := range 5 {
+		clusterRPC.nodes = []*solanagorpc.GetClusterNodesResult{activeNode}
+		if i%2 == 0 {
+			clusterRPC.nodes = append(clusterRPC.nodes, passiveNode)
+		}
+		state.Refresh()
+	}
+
+	snapshot := state.Snapshot()
+	require.Len(t, snapshot.PeerSamples["peer1"], 4)
+	for _, sample := range snapshot.PeerSamples["peer1"] {
+		assert.Equal(t, PeerSample{SampledAt: sample.SampledAt, Present: true, Alive: true, Active: true, Voting: true}, sample)
+	}
+	assert.Equal(t, float64(100), snapshot.PeerStats["peer1"].AvailabilityPercent)
+	assert.False(t, snapshot.PeerStats["peer1"].Flapping)
+
+	require.Len(t, snapshot.PeerSamples["peer2"], 4)
+	assert.False(t, snapshot.PeerSamples["peer2"][0].Present)
+	assert.True(t, snapshot.PeerSamples["peer2"][1].Available())
+	assert.Equal(t, float64(50), snapshot.PeerStats["peer2"].AvailabilityPercent)
+	assert.Equal(t, 3, snapshot.PeerStats["peer2"].Transitions)
+	assert.True(t, snapshot.PeerStats["peer2"].Flapping)
+
+	// snapshots are copies
+	snapshot.PeerSamples["peer2"][0].Present = true
+	assert.False(t, state.Snapshot().PeerSamples["peer2"][0].Present)
+}
@@ -0,0 +1,126 @@
+package gossip
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	solanago "github.com/gagliardetto/solana-go"
+)
+
+const (
+	// protocolPingMessage and protocolPongMessage are the bincode enum variant indexes of the ping and pong
+	// messages in the solana gossip Protocol enum
+	protocolPingMessage uint32 = 4
+	protocolPongMessage uint32 = 5
+
+	// pingTokenSize is the size of the random token a ping carries
+	pingTokenSize = 32
+	// pingPacketSize and pongPacketSize are the variant index followed by from (32), token or hash (32)
+	// and signature (64)
+	pingPacketSize = 4 + solanago.PublicKeyLength + pingTokenSize + solanago.SignatureLength
+	pongPacketSize = 4 + solanago.PublicKeyLength + sha256.Size + solanago.SignatureLength
+	// maxPacketSize is the largest packet gossip sends - anything read is at most this long
+	maxPacketSize = 1232
+)
+
+// pingPongHashPrefix is prepended to the ping token to get the hash a pong signs
+var pingPongHashPrefix = []byte("SOLANA_PING_PONG")
+
+// newPingPacket returns a gossip ping message carrying token, signed by keyPair
+func newPingPacket(keyPair solanago.PrivateKey, token [pingTokenSize]byte) ([]byte, error) {
+	signature, err := keyPair.Sign(token[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign ping token: %w", err)
+	}
+
+	packet := make([]byte, 0, pingPacketSize)
+	packet = binary.LittleEndian.AppendUint32(packet, protocolPingMessage)
+	packet = append(packet, keyPair.PublicKey().Bytes()...)
+	packet = append(packet, token[:]...)
+	packet = append(packet, signature[:]...)
+	return packet, nil
+}
+
+// pongHash returns the hash a pong to a ping carrying token must sign
+func pongHash(token [pingTokenSize]byte) [sha256.Size]byte {
+	return sha256.Sum256(append(bytes.Clone(pingPongHashPrefix), token[:]...))
+}
+
+// verifyPongPacket returns an error unless packet is a pong from the node with pubkey to a ping carrying token
+func verifyPongPacket(packet []byte, pubkey solanago.PublicKey, token [pingTokenSize]byte) error {
+	if len(packet) != pongPacketSize {
+		return fmt.Errorf("unexpected packet size %d", len(packet))
+	}
+	if variant := binary.LittleEndian.Uint32(packet); variant != protocolPongMessage {
+		return fmt.Errorf("unexpected message variant %d", variant)
+	}
+
+	from := solanago.PublicKeyFromBytes(packet[4 : 4+solanago.PublicKeyLength])
+	if !from.Equals(pubkey) {
+		return fmt.Errorf("pong is from %s", from)
+	}
+
+	hash := packet[4+solanago.PublicKeyLength : 4+solanago.PublicKeyLength+sha256.Size]
+	expectedHash := pongHash(token)
+	if !bytes.Equal(hash, expectedHash[:]) {
+		return errors.New("pong is not for our ping")
+	}
+
+	signature := solanago.SignatureFromBytes(packet[4+solanago.PublicKeyLength+sha256.Size:])
+	if !signature.Verify(pubkey, hash) {
+		return errors.New("pong signature is invalid")
+	}
+
+	return nil
+}
+
+// ping sends a gossip ping signed by keyPair to the node with pubkey at address and waits up to timeout for
+// its pong. Other packets arriving in the meantime are ignored. A pong may never come, so timeout must be
+// positive
+func ping(address string, pubkey solanago.PublicKey, keyPair solanago.PrivateKey, timeout time.Duration) error {
+	if timeout <= 0 {
+		return fmt.Errorf("udp-ping needs a probe timeout, got %s", timeout)
+	}
+
+	var token [pingTokenSize]byte
+	if _, err := rand.Read(token[:]); err != nil {
+		return fmt.Errorf("failed to generate ping token: %w", err)
+	}
+
+	packet, err := newPingPacket(keyPair, token)
+	if err != nil {
+		return err
+	}
+
+	// a connected socket only receives packets from the address pinged
+	conn, err := net.DialTimeout("udp", address, timeout)
+	if err != nil {
+		return fmt.Errorf("failed to dial %s: %w", address, err)
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return fmt.Errorf("failed to set deadline: %w", err)
+	}
+
+	if _, err := conn.Write(packet); err != nil {
+		return fmt.Errorf("failed to send ping: %w", err)
+	}
+
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return fmt.Errorf("no pong received: %w", err)
+		}
+		if verifyPongPacket(buf[:n], pubkey, token) == nil {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package gossip
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	solanago "github.com/gagliardetto/solana-go"
+	solanagorpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPongPacket returns the pong keyPair's node answers a ping carrying token with
+func newPongPacket(t *testing.T, keyPair solanago.PrivateKey, token [pingTokenSize]byte) []byte {
+	t.Helper()
+
+	hash := pongHash(token)
+	signature, err := keyPair.Sign(hash[:])
+	require.NoError(t, err)
+
+	packet := binary.LittleEndian.AppendUint32(nil, protocolPongMessage)
+	packet = append(packet, keyPair.PublicKey().Bytes()...)
+	packet = append(packet, hash[:]...)
+	return append(packet, signature[:]...)
+}
+
+// startGossipNode answers valid pings on a loopback udp address with a pong signed by keyPair, as a
+// solana node would on its gossip socket, and returns the address
+func startGossipNode(t *testing.T, keyPair solanago.PrivateKey) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, maxPacketSize)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if n != pingPacketSize || binary.LittleEndian.Uint32(buf) != protocolPingMessage {
+				continue
+			}
+
+			from := solanago.PublicKeyFromBytes(buf[4 : 4+solanago.PublicKeyLength])
+			var token [pingTokenSize]byte
+			copy(token[:], buf[4+solanago.PublicKeyLength:])
+			signature := solanago.SignatureFromBytes(buf[4+solanago.PublicKeyLength+pingTokenSize : n])
+			if !signature.Verify(from, token[:]) {
+				continue
+			}
+
+			// some noise first, which the prober must skip
+			_, _ = conn.WriteTo([]byte("noise"), addr)
+			_, _ = conn.WriteTo(newPongPacket(t, keyPair, token), addr)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func TestNewPingPacket(t *testing.T) {
+	keyPair := solanago.NewWallet().PrivateKey
+	token := [pingTokenSize]byte{1, 2, 3}
+
+	packet, err := newPingPacket(keyPair, token)
+	require.NoError(t, err)
+	require.Len(t, packet, pingPacketSize)
+
+	assert.Equal(t, protocolPingMessage, binary.LittleEndian.Uint32(packet))
+	assert.Equal(t, keyPair.PublicKey().Bytes(), packet[4:36])
+	assert.Equal(t, token[:], packet[36:68])
+	signature := solanago.SignatureFromBytes(packet[68:])
+	assert.True(t, signature.Verify(keyPair.PublicKey(), token[:]))
+}
+
+func TestVerifyPongPacket(t *testing.T) {
+	nodeKeyPair := solanago.NewWallet().PrivateKey
+	token := [pingTokenSize]byte{1, 2, 3}
+	pong := newPongPacket(t, nodeKeyPair, token)
+
+	assert.NoError(t, verifyPongPacket(pong, nodeKeyPair.PublicKey(), token))
+
+	// from another node
+	assert.ErrorContains(t, verifyPongPacket(pong, solanago.NewWallet().PublicKey(), token), "pong is from")
+
+	// to another ping
+	assert.EqualError(t, verifyPongPacket(pong, nodeKeyPair.PublicKey(), [pingTokenSize]byte{4}), "pong is not for our ping")
+
+	// not a pong
+	ping, err := newPingPacket(nodeKeyPair, token)
+	require.NoError(t, err)
+	assert.EqualError(t, verifyPongPacket(ping, nodeKeyPair.PublicKey(), token), "unexpected message variant 4")
+	assert.EqualError(t, verifyPongPacket(pong[:10], nodeKeyPair.PublicKey(), token), "unexpected packet size 10")
+
+	// tampered signature
+	tampered := append([]byte{}, pong...)
+	tampered[len(tampered)-1] ^= 0xff
+	assert.EqualError(t, verifyPongPacket(tampered, nodeKeyPair.PublicKey(), token), "pong signature is invalid")
+}
+
+func TestPing(t *testing.T) {
+	nodeKeyPair := solanago.NewWallet().PrivateKey
+	keyPair := solanago.NewWallet().PrivateKey
+	address := startGossipNode(t, nodeKeyPair)
+
+	assert.NoError(t, ping(address, nodeKeyPair.PublicKey(), keyPair, time.Second))
+
+	// a node answering with another identity is not the node we are looking for
+	assert.ErrorContains(t, ping(address, solanago.NewWallet().PublicKey(), keyPair, 100*time.Millisecond), "no pong received")
+
+	// nothing listening
+	silent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer silent.Close()
+	assert.ErrorContains(t, ping(silent.LocalAddr().String(), nodeKeyPair.PublicKey(), keyPair, 100*time.Millisecond), "no pong received")
+
+	// without a timeout we would wait forever for a pong that may never come
+	assert.EqualError(t, ping(address, nodeKeyPair.PublicKey(), keyPair, 0), "udp-ping needs a probe timeout, got 0s")
+}
+
+func TestRefresh_ProbeStrategies(t *testing.T) {
+	activeKeyPair := solanago.NewWallet().PrivateKey
+	aliveAddress := startGossipNode(t, activeKeyPair)
+
+	silent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer silent.Close()
+	deadAddress := silent.LocalAddr().String()
+
+	newState := func(strategy, gossipAddress string) *State {
+		return NewState(Options{
+			ClusterRPC:    &testClusterRPC{nodes: []*solanagorpc.GetClusterNodesResult{{Pubkey: activeKeyPair.PublicKey(), Gossip: &gossipAddress}}},
+			ActivePubkey:  activeKeyPair.PublicKey().String(),
+			ConfigPeers:   map[string]config.Peer{"peer1": {IP: "127.0.0.1", Name: "peer1"}},
+			ProbeStrategy: strategy,
+			ProbeTimeout:  100 * time.Millisecond,
+			DialFunc: func(network, address string) (net.Conn, error) {
+				return nil, &net.OpError{Op: "dial", Net: network}
+			},
+		})
+	}
+
+	tests := []struct {
+		name          string
+		strategy      string
+		gossipAddress string
+		wantAlive     bool
+	}{
+		{name: "udp-ping answered", strategy: config.GossipProbeStrategyUDPPing, gossipAddress: aliveAddress, wantAlive: true},
+		{name: "udp-ping unanswered", strategy: config.GossipProbeStrategyUDPPing, gossipAddress: deadAddress},
+		{name: "tcp refused", strategy: config.GossipProbeStrategyTCP, gossipAddress: aliveAddress},
+		{name: "none", strategy: config.GossipProbeStrategyNone, gossipAddress: deadAddress, wantAlive: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := newState(tt.strategy, tt.gossipAddress)
+			state.Refresh()
+			assert.Equal(t, tt.wantAlive, state.Snapshot().HasActivePeer())
+		})
+	}
+}
@@ -2,41 +2,145 @@ package gossip
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"net"
+	"net/url"
 	"slices"
-	"strings"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/log"
+	solanago "github.com/gagliardetto/solana-go"
 	solanagorpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
 	"github.com/sol-strategies/solana-validator-ha/internal/config"
 	"github.com/sol-strategies/solana-validator-ha/internal/rpc"
 )
 
-// State represents the state of the peers as seen by the solana network
+// ClusterRPC is the cluster RPC gossip state is refreshed from
+type ClusterRPC interface {
+	GetClusterNodes(ctx context.Context) ([]*solanagorpc.GetClusterNodesResult, error)
+	GetSlot(ctx context.Context) (uint64, error)
+	GetVoteAccounts(ctx context.Context) (*solanagorpc.GetVoteAccountsResult, error)
+	GetBalance(ctx context.Context, pubkey solanago.PublicKey) (*solanagorpc.GetBalanceResult, error)
+	GetMinimumBalanceForRentExemption(ctx context.Context, dataSize uint64) (uint64, error)
+}
+
+// EndpointsClusterRPC is a cluster RPC that can get cluster nodes from each of its endpoints, as needed to
+// reach RPC consensus on whether the cluster is leaderless
+type EndpointsClusterRPC interface {
+	GetClusterNodesFromEach(ctx context.Context) []rpc.EndpointResult[[]*solanagorpc.GetClusterNodesResult]
+}
+
+// State represents the state of the peers as seen by the solana network. It is safe for concurrent use -
+// refreshes are serialized and the state is read through snapshots
 type State struct {
-	// PeerStatesRefreshedAt is the last time the peer states were refreshed
-	PeerStatesRefreshedAt time.Time
+	configPeers  config.Peers
+	activePubkey string
+	selfIP       string
+	clusterRPC   ClusterRPC
+	dial         func(network, address string) (net.Conn, error)
+	probe        string
+	probeTimeout time.Duration
+	pingKeyPair  solanago.PrivateKey
+	// endpointsRPC and rpcQuorum are set when a sample only counts as leaderless if rpcQuorum endpoints agree
+	endpointsRPC EndpointsClusterRPC
+	rpcQuorum    int
+	// maxVoteLagSlots and voteLagSamplesThreshold are how far and for how many samples the active identity's
+	// votes may lag behind the cluster before it is considered not voting - zero maxVoteLagSlots to disable
+	maxVoteLagSlots         uint64
+	voteLagSamplesThreshold int
+	// historySize is how many samples are kept per peer, flapTransitionsThreshold how many availability
+	// changes within them make a peer flapping
+	historySize              int
+	flapTransitionsThreshold int
+	clock                    clock.Clock
+	logger                   *log.Logger
+
+	// refreshMu serializes refreshes - a refresh reads the sampled state below without mu as it is the only writer
+	refreshMu sync.Mutex
+	// mu guards the sampled state below
+	mu sync.RWMutex
+	// peerStatesRefreshedAt is the last time the peer states were refreshed
+	peerStatesRefreshedAt time.Time
+	// clusterSampledAt is the last time a refresh got cluster nodes from the cluster RPC
+	clusterSampledAt time.Time
 	// peerStatesByName are the peers that are currently in the solana network, keyed by their name
-	peerStatesByName       map[string]PeerState // these are the peers that are currently in the solana network, keyed by their name
-	configPeers            config.Peers
-	activePubkey           string
-	selfIP                 string
-	clusterRPC             *rpc.Client
-	logger                 *log.Logger
+	peerStatesByName       map[string]PeerState
 	missingGossipIPs       []string
 	lastActivePeer         PeerState
+	activePeerNames        []string // all peers presenting the active identity in the last sample
 	activePeerLastSeenAt   time.Time
+	leaderlessSamplesCount int
+	endpointViews          []EndpointView
+	voteLagSlotsByName     map[string]uint64
+	// voteLagSamplesByPubkey are the consecutive samples each node's votes lagged more than maxVoteLagSlots
+	voteLagSamplesByPubkey map[string]int
+	// unexpectedIdentitiesByName are the pubkeys of nodes rejected at each config peer's addresses in the last
+	// sample for presenting neither its pubkey nor the active identity
+	unexpectedIdentitiesByName map[string][]string
+	// peerHistories are the last historySize samples of each config peer and peerStatsByName the statistics
+	// derived from them, keyed by peer name
+	peerHistories   map[string]*peerHistory
+	peerStatsByName map[string]PeerStats
+}
+
+// Snapshot is a deep copy of the gossip state at a point in time, safe to keep and read without locking
+type Snapshot struct {
+	// TakenAt is when the snapshot was taken
+	TakenAt time.Time
+	// RefreshedAt is the last time the peer states were refreshed
+	RefreshedAt time.Time
+	// ClusterSampledAt is the last time a refresh got cluster nodes from the cluster RPC
+	ClusterSampledAt time.Time
+	// LeaderlessSamplesCount is the number of consecutive samples without an active peer
 	LeaderlessSamplesCount int
+	// PeerStates are the peers in gossip at the last refresh, keyed by their name
+	PeerStates map[string]PeerState
+	// MissingGossipIPs are the IP addresses of the config peers not in gossip at the last refresh
+	MissingGossipIPs []string
+	// ActivePeerNames are all peers presenting the active identity at the last refresh, including those
+	// excluded from PeerStates for not voting
+	ActivePeerNames []string
+	// LastActivePeer is the peer last seen active and voting, which may no longer be in PeerStates
+	LastActivePeer PeerState
+	// ActivePeerLastSeenAt is the last time an active peer was seen
+	ActivePeerLastSeenAt time.Time
+	// EndpointViews are what each cluster RPC endpoint showed at the last refresh - only set with RPC consensus
+	EndpointViews []EndpointView
+	// VoteLagSlots are how many slots the last vote of each peer presenting the active identity lagged behind
+	// the cluster at the last refresh, keyed by peer name
+	VoteLagSlots map[string]uint64
+	// UnexpectedIdentities are the pubkeys of nodes rejected at each config peer's addresses at the last refresh
+	// for presenting neither the peer's pubkey nor the active identity, keyed by peer name
+	UnexpectedIdentities map[string][]string
+	// PeerSamples are the recent samples of each config peer, oldest first, keyed by peer name
+	PeerSamples map[string][]PeerSample
+	// PeerStats are the statistics derived from PeerSamples, keyed by peer name
+	PeerStats map[string]PeerStats
+}
+
+// EndpointView is what one cluster RPC endpoint showed in gossip at a refresh with RPC consensus
+type EndpointView struct {
+	// Endpoint is the host of the endpoint's URL - the rest of it may hold credentials
+	Endpoint string
+	// Err is set if the endpoint failed to answer, in which case it has no say in the sample
+	Err error
+	// Leaderless is true if the endpoint showed no peer presenting the active identity alive and voting
+	Leaderless bool
 }
 
 // PeerState represents the state of a peer as seen by the solana network
 type PeerState struct {
 	// Name is the vanity name of the peer
 	Name string
-	// IP is the IP address of the peer
+	// IP is the configured IP address of the peer
 	IP string
+	// GossipAddress is the gossip address the peer was seen at, which may be any of its addresses
+	GossipAddress string
 	// Pubkey is the public key of the peer
 	Pubkey string
 	// LastSeenAt is the last time the peer was seen by the solana network
@@ -49,39 +153,140 @@ type PeerState struct {
 
 // Options are the options for peers state
 type Options struct {
-	ClusterRPC   *rpc.Client
+	ClusterRPC   ClusterRPC
 	ActivePubkey string
 	SelfIP       string
 	ConfigPeers  config.Peers
 	LogPrefix    string
+	// ProbeStrategy is how nodes are confirmed alive on their gossip address, one of the
+	// config.GossipProbeStrategy* strategies - defaults to tcp
+	ProbeStrategy string
+	// ProbeTimeout bounds each gossip probe. Zero means no timeout for the tcp strategy, but udp-ping needs
+	// one so its probes fail without it
+	ProbeTimeout time.Duration
+	// PingKeyPair signs udp-ping probes - defaults to a key pair generated for the state
+	PingKeyPair *solanago.PrivateKey
+	// RPCQuorum is the number of cluster RPC endpoints that must agree for a sample to count as leaderless -
+	// zero to go by the first endpoint to answer. Needs ClusterRPC to be an EndpointsClusterRPC
+	RPCQuorum int
+	// MaxVoteLagSlots is how many slots the active identity's last vote may lag behind the cluster for
+	// VoteLagSamplesThreshold consecutive samples before it is considered not voting - zero to only go by
+	// delinquency
+	MaxVoteLagSlots         uint64
+	VoteLagSamplesThreshold int
+	// HistorySize is how many samples are kept per peer - defaults to 120
+	HistorySize int
+	// FlapTransitionsThreshold is how many availability changes within a peer's samples make it flapping -
+	// defaults to 4
+	FlapTransitionsThreshold int
+	// DialFunc probes gossip addresses for liveness with the tcp strategy - defaults to net.DialTimeout
+	// with ProbeTimeout
+	DialFunc func(network, address string) (net.Conn, error)
+	// Clock stamps samples and snapshots - defaults to the system clock
+	Clock clock.Clock
 }
 
 // NewState creates a new gossip state
 func NewState(opts Options) *State {
+	dial := opts.DialFunc
+	if dial == nil {
+		dial = func(network, address string) (net.Conn, error) {
+			return net.DialTimeout(network, address, opts.ProbeTimeout)
+		}
+	}
+
+	probe := opts.ProbeStrategy
+	if probe == "" {
+		probe = config.GossipProbeStrategyTCP
+	}
+
+	// pings only need signing by some identity - witnesses have none of their own
+	var pingKeyPair solanago.PrivateKey
+	if opts.PingKeyPair != nil {
+		pingKeyPair = *opts.PingKeyPair
+	} else if probe == config.GossipProbeStrategyUDPPing {
+		pingKeyPair = solanago.NewWallet().PrivateKey
+	}
+
+	historySize := opts.HistorySize
+	if historySize == 0 {
+		historySize = defaultHistorySize
+	}
+	flapTransitionsThreshold := opts.FlapTransitionsThreshold
+	if flapTransitionsThreshold == 0 {
+		flapTransitionsThreshold = defaultFlapTransitionsThreshold
+	}
+
+	stateClock := opts.Clock
+	if stateClock == nil {
+		stateClock = clock.New()
+	}
+
+	logger := log.WithPrefix(fmt.Sprintf("[%s gossip_state]", opts.LogPrefix))
+
+	var endpointsRPC EndpointsClusterRPC
+	if opts.RPCQuorum > 0 {
+		var ok bool
+		if endpointsRPC, ok = opts.ClusterRPC.(EndpointsClusterRPC); !ok {
+			logger.Warn("cluster rpc cannot query each endpoint - going by the first to answer")
+		}
+	}
+
+	// without a pubkey a peer can only be told apart by its address
+	for name, peer := range opts.ConfigPeers {
+		if peer.Pubkey == "" {
+			logger.Warn("peer declares no pubkey - any node at its addresses will be taken for it", "peer_name", name)
+		}
+	}
+
 	return &State{
-		logger:           log.WithPrefix(fmt.Sprintf("[%s gossip_state]", opts.LogPrefix)),
-		clusterRPC:       opts.ClusterRPC,
-		activePubkey:     opts.ActivePubkey,
-		selfIP:           opts.SelfIP,
-		configPeers:      opts.ConfigPeers,
-		peerStatesByName: make(map[string]PeerState),
+		dial:                     dial,
+		endpointsRPC:             endpointsRPC,
+		rpcQuorum:                opts.RPCQuorum,
+		maxVoteLagSlots:          opts.MaxVoteLagSlots,
+		voteLagSamplesThreshold:  opts.VoteLagSamplesThreshold,
+		historySize:              historySize,
+		flapTransitionsThreshold: flapTransitionsThreshold,
+		clock:                    stateClock,
+		probe:                    probe,
+		probeTimeout:             opts.ProbeTimeout,
+		pingKeyPair:              pingKeyPair,
+		logger:                   logger,
+		clusterRPC:               opts.ClusterRPC,
+		activePubkey:             opts.ActivePubkey,
+		selfIP:                   opts.SelfIP,
+		configPeers:              opts.ConfigPeers,
+		peerStatesByName:         make(map[string]PeerState),
+		peerHistories:            make(map[string]*peerHistory),
+		peerStatsByName:          make(map[string]PeerStats),
 	}
 }
 
 // Refresh the state of peers as seen by the solana network
 func (p *State) Refresh() {
+	p.refreshMu.Lock()
+	defer p.refreshMu.Unlock()
+
 	p.logger.Debug("refreshing peers state")
 	latestPeerStatesByName := make(map[string]PeerState)
 
 	// get cluster nodes - if this fails we return an empty state, which should cause its consumer
 	// to check for failovers
-	clusterNodes, err := p.clusterRPC.GetClusterNodes(context.Background())
+	clusterNodes, endpointResults, err := p.getClusterNodes()
 	if err != nil {
+		p.mu.Lock()
 		p.peerStatesByName = latestPeerStatesByName
-		p.PeerStatesRefreshedAt = time.Now().UTC()
+		p.activePeerNames = nil
+		p.endpointViews = p.sampleEndpointViews(endpointResults, nil)
+		p.voteLagSlotsByName = nil
+		p.voteLagSamplesByPubkey = nil
+		p.unexpectedIdentitiesByName = nil
+		p.peerStatesRefreshedAt = p.clock.Now().UTC()
+		p.mu.Unlock()
 		p.logger.Error("failed to get cluster nodes", "error", err)
 		return
 	}
+	clusterSampledAt := p.clock.Now().UTC()
 
 	p.logger.Debug("looking for peers in gossip",
 		"cluster_nodes_count", len(clusterNodes),
@@ -90,42 +295,78 @@ func (p *State) Refresh() {
 		"active_pubkey", p.activePubkey,
 	)
 
-	// look through all the returned gossip nodes, looking for the ones that are in the config
+	// look through all the returned gossip nodes, looking for the ones that are in the config - all of them, as
+	// a node spoofing a config peer may come after the peer itself
+	probes := newNodeProbes()
 	isLeaderlessSample := true
+	latestActivePeerNames := []string{}
+	latestVoteLagSlotsByName := map[string]uint64{}
+	latestPeerSamples := map[string]*PeerSample{}
+	latestUnexpectedIdentities := map[string][]string{}
+	lastActivePeer, activePeerLastSeenAt := p.lastActivePeer, p.activePeerLastSeenAt
 	for _, node := range clusterNodes {
-		nodeIP := strings.Split(*node.Gossip, ":")[0]
-
-		// if the peer is not the config, keep looking
-		if !p.hasConfigPeerWithIP(nodeIP) {
+		// if the node is not a config peer, keep looking
+		peerName, peer, err := p.configPeerForNode(node)
+		if errors.Is(err, errNotConfigPeer) {
 			continue
 		}
-
-		// get the peer name from configPeers
-		peerName, ok := p.peerNameFromIP(nodeIP)
-		if !ok {
-			p.logger.Warn("peer not found in config", "ip", nodeIP)
+		// something else claiming a peer's address may be spoofing it - it must not be taken for the peer
+		if errors.Is(err, errUnexpectedIdentity) {
+			p.logger.Error("‼️ node at a peer address presents an unexpected identity - rejecting it, the peer may be spoofed",
+				"peer_name", peerName,
+				"gossip_address", *node.Gossip,
+				"pubkey", node.Pubkey.String(),
+				"expected_pubkey", p.configPeers[peerName].Pubkey,
+			)
+			latestUnexpectedIdentities[peerName] = append(latestUnexpectedIdentities[peerName], node.Pubkey.String())
+			continue
+		}
+		if err != nil {
+			p.logger.Warn("node is not taken for a peer - excluding from state", "pubkey", node.Pubkey.String(), "error", err)
 			continue
 		}
 
-		// if the node is not alive (can dial its gossip address) it's dead to us - gossip response is stale
-		if !p.isNodeGossipAlive(*node) {
+		// peers are known by their configured IP, whichever of their addresses they appear at
+		peerIP := peer.IP
+
+		// sample what we see of the peer as we go
+		peerSample := &PeerSample{SampledAt: clusterSampledAt, Present: true}
+		latestPeerSamples[peerName] = peerSample
+
+		// if the node is not alive (does not answer its gossip probe) it's dead to us - gossip response is stale
+		if !p.probeNodeGossipAlive(probes, *node) {
 			p.logger.Debug("node gossip address not alive - excluding from state",
 				"peer_name", peerName,
-				"ip", nodeIP,
+				"ip", peerIP,
 				"gossip_address", *node.Gossip,
 				"pubkey", node.Pubkey.String(),
 			)
 			continue
 		}
+		peerSample.Alive = true
 
 		// lastSeenActive
 		isActivePeer := node.Pubkey.String() == p.activePubkey
+		peerSample.Active = isActivePeer
+
+		// track every peer presenting the active identity, voting or not, so that split-brain can be detected
+		if isActivePeer {
+			latestActivePeerNames = append(latestActivePeerNames, peerName)
+		}
 
 		// a borked active peer might appear in gossip but not actually be voting
 		// so we need to check for that and only proceed to add it to the state if it is not voting still
-		if isActivePeer && !p.isNodeActiveAndVoting(*node) {
-			p.logger.Warn("active peer appears in gossip but is not voting - excluding from state", "ip", nodeIP, "pubkey", node.Pubkey.String())
-			continue
+		if isActivePeer {
+			isVoting := p.probeNodeActiveAndVoting(probes, *node)
+			peerSample.Voting = isVoting
+			if voteLagSlots, ok := probes.voteLagSlots[node.Pubkey.String()]; ok {
+				latestVoteLagSlotsByName[peerName] = voteLagSlots
+				peerSample.VoteLagSlots = voteLagSlots
+			}
+			if !isVoting {
+				p.logger.Warn("active peer appears in gossip but is not voting - excluding from state", "ip", peerIP, "pubkey", node.Pubkey.String())
+				continue
+			}
 		}
 
 		// now we know the peer is alive and voting (if it is an active node) - so we can add it to the state
@@ -133,11 +374,12 @@ func (p *State) Refresh() {
 		// add the peer to the peerEntries
 		peerState := PeerState{
 			Name:               peerName,
-			IP:                 nodeIP,
-			LastSeenAtUTC:      time.Now().UTC(),
+			IP:                 peerIP,
+			GossipAddress:      *node.Gossip,
+			LastSeenAtUTC:      p.clock.Now().UTC(),
 			Pubkey:             node.Pubkey.String(),
 			LastSeenActive:     isActivePeer,
-			IsRecentlyInGossip: slices.Contains(p.missingGossipIPs, nodeIP),
+			IsRecentlyInGossip: slices.Contains(p.missingGossipIPs, peerIP),
 		}
 
 		// register the peer state
@@ -145,15 +387,15 @@ func (p *State) Refresh() {
 
 		// update state's activePeerLastSeenAt
 		if peerState.LastSeenActive {
-			p.activePeerLastSeenAt = peerState.LastSeenAtUTC
+			activePeerLastSeenAt = peerState.LastSeenAtUTC
 			isLeaderlessSample = false
 		}
 
 		// log if is change of active peer
-		if peerState.LastSeenActive && p.lastActivePeer.IP != "" && p.lastActivePeer.IP != peerState.IP {
+		if peerState.LastSeenActive && lastActivePeer.IP != "" && lastActivePeer.IP != peerState.IP {
 			p.logger.Warn(fmt.Sprintf("active peer changed: %s (%s) -> %s (%s)",
-				p.lastActivePeer.IP,
-				p.lastActivePeer.Name,
+				lastActivePeer.IP,
+				lastActivePeer.Name,
 				peerState.IP,
 				peerState.Name,
 			))
@@ -161,7 +403,7 @@ func (p *State) Refresh() {
 
 		// register the peer if active
 		if peerState.LastSeenActive {
-			p.lastActivePeer = peerState
+			lastActivePeer = peerState
 			p.logger.Debug("active peer found",
 				"name", peerState.Name,
 				"ip", peerState.IP,
@@ -173,7 +415,7 @@ func (p *State) Refresh() {
 
 		// tell us what we found
 		// state didn't have this peer last time but now it does - so we need to log that
-		if !p.HasIP(peerState.IP) {
+		if !p.hasIP(peerState.IP) {
 			p.logger.Info("peer discovered in gossip",
 				"name", peerState.Name,
 				"ip", peerState.IP,
@@ -182,11 +424,6 @@ func (p *State) Refresh() {
 				"last_seen_at", peerState.LastSeenAtString(),
 			)
 		}
-
-		// if all peers from configPeers are in the peerEntries, we can stop looking
-		if len(p.configPeers) == len(latestPeerStatesByName) {
-			break
-		}
 	}
 
 	// warn if any of the config peers are not in the peerEntries
@@ -205,7 +442,7 @@ func (p *State) Refresh() {
 		}
 
 		// warn if peer was in the old state but is now missing
-		if p.HasIP(ip) {
+		if p.hasIP(ip) {
 			p.logger.Warn("peer lost from gossip", "name", name, "ip", ip)
 			continue
 		}
@@ -220,22 +457,269 @@ func (p *State) Refresh() {
 		p.logger.Debug("peer still missing from gossip", "name", name, "ip", ip)
 	}
 
+	// config peers not seen at all are sampled as not present
+	for name := range p.configPeers {
+		if _, ok := latestPeerSamples[name]; !ok {
+			latestPeerSamples[name] = &PeerSample{SampledAt: clusterSampledAt}
+		}
+	}
+
+	// more than one peer presenting the active identity is a split-brain
+	if len(latestActivePeerNames) > 1 {
+		p.logger.Error("‼️ multiple peers present the active identity in gossip",
+			"peers", latestActivePeerNames,
+			"active_pubkey", p.activePubkey,
+		)
+	}
+
+	// with RPC consensus, a single lagging or forked endpoint must not make the cluster look leaderless
+	latestEndpointViews := p.sampleEndpointViews(endpointResults, probes)
+	if latestEndpointViews != nil {
+		hasLeaderlessRPCQuorum := p.hasLeaderlessRPCQuorum(latestEndpointViews)
+		if isLeaderlessSample && !hasLeaderlessRPCQuorum {
+			p.logger.Warn("no active peer found but too few cluster rpc endpoints agree - not counting the sample as leaderless",
+				"rpc_quorum", p.rpcQuorum,
+			)
+			isLeaderlessSample = false
+		}
+	}
+
 	// update state
+	p.mu.Lock()
 	if isLeaderlessSample {
-		p.LeaderlessSamplesCount++
+		p.leaderlessSamplesCount++
 		p.logger.Warn("no active peer found",
-			"leaderless_samples_count", p.LeaderlessSamplesCount)
+			"leaderless_samples_count", p.leaderlessSamplesCount)
 	} else {
-		p.LeaderlessSamplesCount = 0
+		p.leaderlessSamplesCount = 0
 	}
+	p.clusterSampledAt = clusterSampledAt
+	p.lastActivePeer = lastActivePeer
+	p.activePeerLastSeenAt = activePeerLastSeenAt
 	p.missingGossipIPs = latestMissingGossipIPs
+	p.activePeerNames = latestActivePeerNames
+	p.endpointViews = latestEndpointViews
+	p.voteLagSlotsByName = latestVoteLagSlotsByName
+	p.voteLagSamplesByPubkey = probes.voteLagSamples
+	p.unexpectedIdentitiesByName = latestUnexpectedIdentities
+	p.recordPeerSamples(latestPeerSamples)
 	p.peerStatesByName = latestPeerStatesByName
-	p.PeerStatesRefreshedAt = time.Now().UTC()
-	p.logger.Debug("peers state refreshed", "peer_count", len(p.peerStatesByName))
+	p.peerStatesRefreshedAt = p.clock.Now().UTC()
+	p.mu.Unlock()
+	p.logger.Debug("peers state refreshed", "peer_count", len(latestPeerStatesByName))
+}
+
+// Snapshot returns a deep copy of the state as of the last refresh
+func (p *State) Snapshot() Snapshot {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	peerStates := make(map[string]PeerState, len(p.peerStatesByName))
+	for name, peerState := range p.peerStatesByName {
+		peerStates[name] = peerState
+	}
+
+	unexpectedIdentities := make(map[string][]string, len(p.unexpectedIdentitiesByName))
+	for name, pubkeys := range p.unexpectedIdentitiesByName {
+		unexpectedIdentities[name] = slices.Clone(pubkeys)
+	}
+
+	peerSamples := make(map[string][]PeerSample, len(p.peerHistories))
+	for name, history := range p.peerHistories {
+		peerSamples[name] = history.all()
+	}
+
+	return Snapshot{
+		TakenAt:                p.clock.Now().UTC(),
+		RefreshedAt:            p.peerStatesRefreshedAt,
+		ClusterSampledAt:       p.clusterSampledAt,
+		LeaderlessSamplesCount: p.leaderlessSamplesCount,
+		PeerStates:             peerStates,
+		MissingGossipIPs:       slices.Clone(p.missingGossipIPs),
+		ActivePeerNames:        slices.Clone(p.activePeerNames),
+		LastActivePeer:         p.lastActivePeer,
+		ActivePeerLastSeenAt:   p.activePeerLastSeenAt,
+		EndpointViews:          slices.Clone(p.endpointViews),
+		VoteLagSlots:           maps.Clone(p.voteLagSlotsByName),
+		UnexpectedIdentities:   unexpectedIdentities,
+		PeerSamples:            peerSamples,
+		PeerStats:              maps.Clone(p.peerStatsByName),
+	}
+}
+
+// recordPeerSamples adds the samples of a refresh to the peers' histories and updates their statistics, logging
+// peers that start or stop flapping. Callers hold mu
+func (p *State) recordPeerSamples(peerSamples map[string]*PeerSample) {
+	for name, peerSample := range peerSamples {
+		history, ok := p.peerHistories[name]
+		if !ok {
+			history = newPeerHistory(p.historySize)
+			p.peerHistories[name] = history
+		}
+		history.add(*peerSample)
+
+		stats := history.stats(p.flapTransitionsThreshold)
+		wasFlapping := p.peerStatsByName[name].Flapping
+		p.peerStatsByName[name] = stats
+
+		switch {
+		case stats.Flapping && !wasFlapping:
+			p.logger.Warn("peer is flapping in and out of availability",
+				"name", name,
+				"transitions", stats.Transitions,
+				"samples", stats.Samples,
+				"transitions_per_hour", fmt.Sprintf("%.1f", stats.TransitionsPerHour),
+				"availability_percent", fmt.Sprintf("%.1f", stats.AvailabilityPercent),
+			)
+		case !stats.Flapping && wasFlapping:
+			p.logger.Info("peer is no longer flapping",
+				"name", name,
+				"transitions", stats.Transitions,
+				"samples", stats.Samples,
+			)
+		}
+	}
+}
+
+// getClusterNodes returns the cluster nodes to build the state from. With RPC consensus these are the first
+// successful endpoint's in cluster.rpc_urls order, returned along with what every endpoint answered
+func (p *State) getClusterNodes() ([]*solanagorpc.GetClusterNodesResult, []rpc.EndpointResult[[]*solanagorpc.GetClusterNodesResult], error) {
+	if p.endpointsRPC == nil {
+		clusterNodes, err := p.clusterRPC.GetClusterNodes(context.Background())
+		return clusterNodes, nil, err
+	}
+
+	endpointResults := p.endpointsRPC.GetClusterNodesFromEach(context.Background())
+	errs := []error{}
+	for _, endpointResult := range endpointResults {
+		if endpointResult.Err == nil {
+			return endpointResult.Result, endpointResults, nil
+		}
+		errs = append(errs, endpointResult.Err)
+	}
+
+	return nil, endpointResults, fmt.Errorf("method call failed on all RPC endpoints method: GetClusterNodes, errors: %w", errors.Join(errs...))
+}
+
+// sampleEndpointViews returns what each endpoint showed in gossip, probing nodes not already in probes - nil without
+// RPC consensus
+func (p *State) sampleEndpointViews(endpointResults []rpc.EndpointResult[[]*solanagorpc.GetClusterNodesResult], probes *nodeProbes) []EndpointView {
+	if endpointResults == nil {
+		return nil
+	}
+
+	endpointViews := make([]EndpointView, 0, len(endpointResults))
+	for _, endpointResult := range endpointResults {
+		endpointView := EndpointView{
+			Endpoint: endpointName(endpointResult.URL),
+			Err:      endpointResult.Err,
+		}
+		if endpointResult.Err == nil {
+			endpointView.Leaderless = !p.showsActivePeer(probes, endpointResult.Result)
+		}
+		endpointViews = append(endpointViews, endpointView)
+	}
+
+	return endpointViews
+}
+
+// showsActivePeer returns true if clusterNodes has a config peer presenting the active identity that is alive
+// and voting
+func (p *State) showsActivePeer(probes *nodeProbes, clusterNodes []*solanagorpc.GetClusterNodesResult) bool {
+	for _, node := range clusterNodes {
+		if node.Pubkey.String() != p.activePubkey {
+			continue
+		}
+		if _, _, err := p.configPeerForNode(node); err != nil {
+			continue
+		}
+		if p.probeNodeGossipAlive(probes, *node) && p.probeNodeActiveAndVoting(probes, *node) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasLeaderlessRPCQuorum returns true if at least rpcQuorum endpoints showed the cluster leaderless, logging
+// any disagreement between the endpoints that answered
+func (p *State) hasLeaderlessRPCQuorum(endpointViews []EndpointView) bool {
+	leaderlessEndpoints, activePeerEndpoints, failedEndpoints := []string{}, []string{}, []string{}
+	for _, endpointView := range endpointViews {
+		switch {
+		case endpointView.Err != nil:
+			failedEndpoints = append(failedEndpoints, endpointView.Endpoint)
+		case endpointView.Leaderless:
+			leaderlessEndpoints = append(leaderlessEndpoints, endpointView.Endpoint)
+		default:
+			activePeerEndpoints = append(activePeerEndpoints, endpointView.Endpoint)
+		}
+	}
+
+	if len(leaderlessEndpoints) > 0 && len(activePeerEndpoints) > 0 {
+		p.logger.Warn("cluster rpc endpoints disagree on the active peer",
+			"leaderless_endpoints", leaderlessEndpoints,
+			"active_peer_endpoints", activePeerEndpoints,
+			"failed_endpoints", failedEndpoints,
+		)
+	}
+
+	return len(leaderlessEndpoints) >= p.rpcQuorum
+}
+
+// endpointName returns the host of an RPC URL, leaving out any credentials in its path or query
+func endpointName(rpcURL string) string {
+	parsedURL, err := url.Parse(rpcURL)
+	if err != nil || parsedURL.Host == "" {
+		return "unknown"
+	}
+	return parsedURL.Host
 }
 
-// isNodeActiveAndVoting returns true if the node is active and voting
-func (p *State) isNodeActiveAndVoting(node solanagorpc.GetClusterNodesResult) bool {
+// nodeProbes caches the result of probing nodes during a refresh, so that nodes listed by several endpoints
+// are only probed once
+type nodeProbes struct {
+	// alive is keyed by gossip address
+	alive map[string]bool
+	// activeAndVoting is keyed by pubkey
+	activeAndVoting map[string]bool
+	// voteLagSlots are how far behind each node's last vote was, keyed by pubkey
+	voteLagSlots map[string]uint64
+	// voteLagSamples are the consecutive samples each node's votes lagged more than maxVoteLagSlots, keyed by
+	// pubkey
+	voteLagSamples map[string]int
+}
+
+func newNodeProbes() *nodeProbes {
+	return &nodeProbes{
+		alive:           make(map[string]bool),
+		activeAndVoting: make(map[string]bool),
+		voteLagSlots:    make(map[string]uint64),
+		voteLagSamples:  make(map[string]int),
+	}
+}
+
+// probeNodeGossipAlive returns isNodeGossipAlive, probing the node only if it is not in probes
+func (p *State) probeNodeGossipAlive(probes *nodeProbes, node solanagorpc.GetClusterNodesResult) bool {
+	alive, probed := probes.alive[*node.Gossip]
+	if !probed {
+		alive = p.isNodeGossipAlive(node)
+		probes.alive[*node.Gossip] = alive
+	}
+	return alive
+}
+
+// probeNodeActiveAndVoting returns isNodeActiveAndVoting, checking the node only if it is not in probes
+func (p *State) probeNodeActiveAndVoting(probes *nodeProbes, node solanagorpc.GetClusterNodesResult) bool {
+	activeAndVoting, probed := probes.activeAndVoting[node.Pubkey.String()]
+	if !probed {
+		activeAndVoting = p.isNodeActiveAndVoting(probes, node)
+		probes.activeAndVoting[node.Pubkey.String()] = activeAndVoting
+	}
+	return activeAndVoting
+}
+
+// isNodeActiveAndVoting returns true if the node is active and voting, recording its vote lag in probes
+func (p *State) isNodeActiveAndVoting(probes *nodeProbes, node solanagorpc.GetClusterNodesResult) bool {
 	// get the current slot
 	currentSlot, err := p.clusterRPC.GetSlot(context.Background())
 	if err != nil {
@@ -258,19 +742,26 @@ func (p *State) isNodeActiveAndVoting(node solanagorpc.GetClusterNodesResult) bo
 			continue
 		}
 
+		probes.voteLagSlots[node.Pubkey.String()] = voteLagSlots(currentSlot, delinquentVoteAccount.LastVote)
+
 		// ok we might be legit delinquent but let's check if the node's identity balance is below the rent-exempt balance
 		balance, err := p.clusterRPC.GetBalance(context.Background(), delinquentVoteAccount.NodePubkey)
 		if err != nil {
 			p.logger.Error("failed to get balance", "error", err)
 			return true // forgive rpc error and assume innocence lest we trigger a false-positive failover
 		}
-		// rent exempt min is 890880 lamports
-		if balance.Value <= 890880 {
+		rentExemptMinimum, err := p.clusterRPC.GetMinimumBalanceForRentExemption(context.Background(), 0)
+		if err != nil {
+			p.logger.Error("failed to get rent-exempt minimum", "error", err)
+			return true // forgive rpc error and assume innocence lest we trigger a false-positive failover
+		}
+		if balance.Value <= rentExemptMinimum {
 			p.logger.Error("‼️ node is delinquent from balance being below rent-exempt minimum - assuming still active to not trigger a false-positive failover - FIX balance pronto!",
 				"gossip_address", *node.Gossip,
 				"pubkey", node.Pubkey.String(),
 				"current_slot", currentSlot,
 				"balance", balance.Value,
+				"rent_exempt_minimum", rentExemptMinimum,
 			)
 			return true
 		}
@@ -320,21 +811,78 @@ func (p *State) isNodeActiveAndVoting(node solanagorpc.GetClusterNodesResult) bo
 		"current_slot", currentSlot,
 	)
 
-	return true
+	// the RPC only marks a node delinquent ~128 slots after its last vote - lagging votes give it away sooner
+	return p.isNodeVoteLagWithinMax(probes, node, currentSlot, nodeVoteAccount.LastVote)
+}
+
+// isNodeVoteLagWithinMax records how far the node's last vote lags behind currentSlot in probes, returning
+// false once it has lagged more than maxVoteLagSlots for voteLagSamplesThreshold consecutive samples
+func (p *State) isNodeVoteLagWithinMax(probes *nodeProbes, node solanagorpc.GetClusterNodesResult, currentSlot, lastVote uint64) bool {
+	voteLagSlots := voteLagSlots(currentSlot, lastVote)
+	probes.voteLagSlots[node.Pubkey.String()] = voteLagSlots
+
+	if p.maxVoteLagSlots == 0 || voteLagSlots <= p.maxVoteLagSlots {
+		return true
+	}
+
+	voteLagSamples := p.voteLagSamplesByPubkey[node.Pubkey.String()] + 1
+	probes.voteLagSamples[node.Pubkey.String()] = voteLagSamples
+	if voteLagSamples < p.voteLagSamplesThreshold {
+		p.logger.Warn("node votes are lagging",
+			"gossip_address", *node.Gossip,
+			"pubkey", node.Pubkey.String(),
+			"vote_lag_slots", voteLagSlots,
+			"max_vote_lag_slots", p.maxVoteLagSlots,
+			"vote_lag_samples", voteLagSamples,
+		)
+		return true
+	}
+
+	p.logger.Error("‼️ node votes have lagged too far for too long - not voting",
+		"gossip_address", *node.Gossip,
+		"pubkey", node.Pubkey.String(),
+		"vote_lag_slots", voteLagSlots,
+		"max_vote_lag_slots", p.maxVoteLagSlots,
+		"vote_lag_samples", voteLagSamples,
+	)
+	return false
+}
+
+// voteLagSlots returns how many slots lastVote is behind currentSlot
+func voteLagSlots(currentSlot, lastVote uint64) uint64 {
+	if lastVote >= currentSlot {
+		return 0
+	}
+	return currentSlot - lastVote
 }
 
 // isNodeGossipAlive returns true if the node's gossip address is alive
 // Note: We use Gossip port instead of TPU because TPU ports are often firewalled
 // and not reliable indicators of node liveness, while Gossip is more accessible
 func (p *State) isNodeGossipAlive(node solanagorpc.GetClusterNodesResult) bool {
-	// try to dial the gossip address
+	if p.probe == config.GossipProbeStrategyNone {
+		return true
+	}
+
 	p.logger.Debug("probing for node liveness on gossip address",
 		"gossip_address", *node.Gossip,
 		"pubkey", node.Pubkey.String(),
+		"strategy", p.probe,
 	)
 
+	// gossip itself is udp - a pong back from the node is the surest sign it is alive, as firewalls may
+	// treat tcp and udp differently
+	if p.probe == config.GossipProbeStrategyUDPPing {
+		err := ping(*node.Gossip, node.Pubkey, p.pingKeyPair, p.probeTimeout)
+		if err != nil {
+			p.logger.Debug("gossip ping failed", "gossip_address", *node.Gossip, "error", err)
+			return false
+		}
+		return true
+	}
+
 	// if we can dial the gossip address, the node is alive
-	conn, err := net.Dial("tcp", *node.Gossip)
+	conn, err := p.dial("tcp", *node.Gossip)
 	if err == nil {
 		conn.Close()
 		return true
@@ -343,30 +891,41 @@ func (p *State) isNodeGossipAlive(node solanagorpc.GetClusterNodesResult) bool {
 	return false
 }
 
-// HasActivePeer returns true if any of the peers are the active validator
-func (p *State) HasActivePeer() bool {
-	for name, peer := range p.peerStatesByName {
-		if peer.LastSeenActive {
-			p.logger.Debug(fmt.Sprintf("active peer found - last seen at %s", peer.LastSeenAtString()), "name", name, "ip", peer.IP, "pubkey", peer.Pubkey)
+// hasIP returns true if the IP is in the peers gossip state - only for use by Refresh, which owns the state
+func (p *State) hasIP(ip string) bool {
+	for _, peer := range p.peerStatesByName {
+		if peer.IP == ip {
 			return true
 		}
 	}
 	return false
 }
 
+// HasClusterSample returns true once a refresh has got cluster nodes from the cluster RPC - until then an
+// empty state says nothing about the cluster
+func (s Snapshot) HasClusterSample() bool {
+	return !s.ClusterSampledAt.IsZero()
+}
+
+// HasActivePeer returns true if any of the peers are the active validator
+func (s Snapshot) HasActivePeer() bool {
+	_, err := s.GetActivePeer()
+	return err == nil
+}
+
 // LeaderlessSamplesExceedsThreshold allows for up to n samples without an active peer before declaring leaderless
-func (p *State) LeaderlessSamplesExceedsThreshold(n int) bool {
-	return p.LeaderlessSamplesCount >= n
+func (s Snapshot) LeaderlessSamplesExceedsThreshold(n int) bool {
+	return s.LeaderlessSamplesCount >= n
 }
 
 // LeaderlessSamplesBelowThreshold allows for up to n samples without an active peer before declaring leaderless
-func (p *State) LeaderlessSamplesBelowThreshold(n int) bool {
-	return p.LeaderlessSamplesCount < n
+func (s Snapshot) LeaderlessSamplesBelowThreshold(n int) bool {
+	return s.LeaderlessSamplesCount < n
 }
 
 // HasIP returns true if the IP is in the peers gossip state
-func (p *State) HasIP(ip string) bool {
-	for _, peer := range p.peerStatesByName {
+func (s Snapshot) HasIP(ip string) bool {
+	for _, peer := range s.PeerStates {
 		if peer.IP == ip {
 			return true
 		}
@@ -375,8 +934,8 @@ func (p *State) HasIP(ip string) bool {
 }
 
 // GetActivePeer returns the active peer state
-func (p *State) GetActivePeer() (state PeerState, err error) {
-	for _, state := range p.peerStatesByName {
+func (s Snapshot) GetActivePeer() (state PeerState, err error) {
+	for _, state := range s.PeerStates {
 		if state.LastSeenActive {
 			return state, nil
 		}
@@ -386,9 +945,8 @@ func (p *State) GetActivePeer() (state PeerState, err error) {
 
 // HasPeers returns true if the IP has any peers in the gossip state
 // that is, any peers in that state that are not the passed IP address
-func (p *State) HasPeers(ip string) bool {
-	// if the self IP is in the gossip state, we have peers
-	for _, peer := range p.peerStatesByName {
+func (s Snapshot) HasPeers(ip string) bool {
+	for _, peer := range s.PeerStates {
 		if peer.IP != ip {
 			return true
 		}
@@ -396,9 +954,37 @@ func (p *State) HasPeers(ip string) bool {
 	return false
 }
 
-// GetPeerStates returns the current peer states
-func (p *State) GetPeerStates() map[string]PeerState {
-	return p.peerStatesByName
+// GetLastActivePeer returns the peer last seen active and voting, which may no longer be in the state
+func (s Snapshot) GetLastActivePeer() (state PeerState, ok bool) {
+	return s.LastActivePeer, s.LastActivePeer.IP != ""
+}
+
+// GetPassivePeers returns the peers in the gossip state that are not active, excluding the passed IP address
+func (s Snapshot) GetPassivePeers(excludeIP string) (passivePeers []PeerState) {
+	for _, peer := range s.PeerStates {
+		if peer.IP == excludeIP || peer.LastSeenActive {
+			continue
+		}
+		passivePeers = append(passivePeers, peer)
+	}
+	return passivePeers
+}
+
+// EndpointsDisagree returns true if, of the cluster RPC endpoints that answered, some showed an active peer and
+// others the cluster leaderless
+func (s Snapshot) EndpointsDisagree() bool {
+	var leaderless, activePeer bool
+	for _, endpointView := range s.EndpointViews {
+		if endpointView.Err != nil {
+			continue
+		}
+		if endpointView.Leaderless {
+			leaderless = true
+		} else {
+			activePeer = true
+		}
+	}
+	return leaderless && activePeer
 }
 
 // LastSeenAtString returns the last seen at time as a string
@@ -415,15 +1001,73 @@ func (p *State) peerNameFromIP(ip string) (string, bool) {
 	return "", false
 }
 
-func (p *State) hasConfigPeerWithIP(ip string) bool {
+var (
+	// errNotConfigPeer is returned by configPeerForNode for nodes at none of the config peers' addresses
+	errNotConfigPeer = errors.New("node is not a config peer")
+	// errUnexpectedIdentity is returned by configPeerForNode for nodes at a config peer's address presenting
+	// neither its pubkey nor the active identity
+	errUnexpectedIdentity = errors.New("node presents an unexpected identity")
+)
+
+// configPeerForNode returns the name and config of the peer a cluster node is - the one whose addresses and
+// gossip port match the node's gossip address, as long as the node presents the peer's pubkey (if it declares
+// one) or the active identity. Otherwise errUnexpectedIdentity is returned along with the peer's name. Nodes
+// without usable contact info are never a peer, which is only worth an error when they present an identity we know
+func (p *State) configPeerForNode(node *solanagorpc.GetClusterNodesResult) (name string, peer config.Peer, err error) {
+	pubkey := node.Pubkey.String()
+
+	ip, port, err := nodeGossipAddress(node)
+	if err != nil {
+		if pubkey == p.activePubkey || p.hasConfigPeerWithPubkey(pubkey) {
+			return "", config.Peer{}, fmt.Errorf("node presenting a known identity has no usable contact info: %w", err)
+		}
+		return "", config.Peer{}, errNotConfigPeer
+	}
+
+	for name, peer := range p.configPeers {
+		if !peer.MatchesGossipAddress(ip, port) {
+			continue
+		}
+		if peer.Pubkey != "" && pubkey != peer.Pubkey && pubkey != p.activePubkey {
+			return name, config.Peer{}, fmt.Errorf("%w: node at %s presents neither peer %s's pubkey %s nor the active identity",
+				errUnexpectedIdentity, *node.Gossip, name, peer.Pubkey)
+		}
+		return name, peer, nil
+	}
+
+	return "", config.Peer{}, errNotConfigPeer
+}
+
+// hasConfigPeerWithPubkey returns true if a config peer declares pubkey as its pubkey
+func (p *State) hasConfigPeerWithPubkey(pubkey string) bool {
 	for _, peer := range p.configPeers {
-		if peer.IP == ip {
+		if peer.Pubkey == pubkey {
 			return true
 		}
 	}
 	return false
 }
 
+// nodeGossipAddress returns the IP address and port of a node's gossip address - IPv4 or IPv6
+func nodeGossipAddress(node *solanagorpc.GetClusterNodesResult) (ip net.IP, port int, err error) {
+	if node.Gossip == nil {
+		return nil, 0, errors.New("no gossip address")
+	}
+
+	host, portString, err := net.SplitHostPort(*node.Gossip)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid gossip address %s: %w", *node.Gossip, err)
+	}
+	if ip = net.ParseIP(host); ip == nil {
+		return nil, 0, fmt.Errorf("invalid gossip address %s: %s is not an IP address", *node.Gossip, host)
+	}
+	if port, err = strconv.Atoi(portString); err != nil {
+		return nil, 0, fmt.Errorf("invalid gossip address %s: %w", *node.Gossip, err)
+	}
+
+	return ip, port, nil
+}
+
 // IPEquals returns true if the IP is equal to the peer's IP
 func (p *PeerState) IPEquals(ip string) bool {
 	return p.IP == ip
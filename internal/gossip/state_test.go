@@ -1,9 +1,16 @@
 package gossip
 
 import (
+	"context"
+	"errors"
+	"net"
+	"sync"
 	"testing"
 	"time"
 
+	solanago "github.com/gagliardetto/solana-go"
+	solanagorpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
 	"github.com/sol-strategies/solana-validator-ha/internal/config"
 	"github.com/sol-strategies/solana-validator-ha/internal/rpc"
 	"github.com/stretchr/testify/assert"
@@ -46,7 +53,7 @@ func TestHasIP(t *testing.T) {
 	state := NewState(opts)
 
 	// Test with empty state
-	assert.False(t, state.HasIP("192.168.1.1"))
+	assert.False(t, state.Snapshot().HasIP("192.168.1.1"))
 
 	// Test with populated state
 	state.peerStatesByName = map[string]PeerState{
@@ -54,9 +61,9 @@ func TestHasIP(t *testing.T) {
 		"peer2": {IP: "192.168.1.3", Pubkey: "pubkey2", LastSeenAtUTC: time.Now().UTC(), LastSeenActive: true},
 	}
 
-	assert.True(t, state.HasIP("192.168.1.2"))
-	assert.True(t, state.HasIP("192.168.1.3"))
-	assert.False(t, state.HasIP("192.168.1.4"))
+	assert.True(t, state.Snapshot().HasIP("192.168.1.2"))
+	assert.True(t, state.Snapshot().HasIP("192.168.1.3"))
+	assert.False(t, state.Snapshot().HasIP("192.168.1.4"))
 }
 
 func TestHasActivePeer(t *testing.T) {
@@ -77,7 +84,7 @@ func TestHasActivePeer(t *testing.T) {
 		"peer2": {IP: "192.168.1.3", Pubkey: "pubkey2", LastSeenAtUTC: time.Now().UTC(), LastSeenActive: false},
 	}
 
-	assert.False(t, state.HasActivePeer())
+	assert.False(t, state.Snapshot().HasActivePeer())
 
 	// Test with active peer
 	state.peerStatesByName["peer3"] = PeerState{
@@ -87,7 +94,7 @@ func TestHasActivePeer(t *testing.T) {
 		LastSeenActive: true,
 	}
 
-	assert.True(t, state.HasActivePeer())
+	assert.True(t, state.Snapshot().HasActivePeer())
 }
 
 func TestHasActivePeerInTheLastNSamples(t *testing.T) {
@@ -106,13 +113,13 @@ func TestHasActivePeerInTheLastNSamples(t *testing.T) {
 	state.peerStatesByName = map[string]PeerState{
 		"peer1": {IP: "192.168.1.2", Pubkey: "pubkey1", LastSeenAtUTC: time.Now().UTC(), LastSeenActive: false},
 	}
-	state.LeaderlessSamplesCount = 5 // Set count to 5
+	state.leaderlessSamplesCount = 5 // Set count to 5
 
 	// With threshold of 3, count of 5 should fail (5 >= 3)
-	assert.False(t, state.LeaderlessSamplesBelowThreshold(3))
+	assert.False(t, state.Snapshot().LeaderlessSamplesBelowThreshold(3))
 
 	// With threshold of 10, count of 5 should pass (5 < 10)
-	assert.True(t, state.LeaderlessSamplesBelowThreshold(10))
+	assert.True(t, state.Snapshot().LeaderlessSamplesBelowThreshold(10))
 
 	// Test with active peer found (leaderlessSamplesCount should be reset)
 	state.peerStatesByName["peer2"] = PeerState{
@@ -121,15 +128,15 @@ func TestHasActivePeerInTheLastNSamples(t *testing.T) {
 		LastSeenAtUTC:  time.Now().UTC(),
 		LastSeenActive: true,
 	}
-	state.LeaderlessSamplesCount = 0 // Reset count when active peer found
+	state.leaderlessSamplesCount = 0 // Reset count when active peer found
 
 	// With threshold of 3, count of 0 should pass (0 < 3)
-	assert.True(t, state.LeaderlessSamplesBelowThreshold(3))
+	assert.True(t, state.Snapshot().LeaderlessSamplesBelowThreshold(3))
 
 	// Test with count at threshold boundary
-	state.LeaderlessSamplesCount = 3
-	assert.False(t, state.LeaderlessSamplesBelowThreshold(3)) // 3 >= 3, should fail
-	assert.True(t, state.LeaderlessSamplesBelowThreshold(4))  // 3 < 4, should pass
+	state.leaderlessSamplesCount = 3
+	assert.False(t, state.Snapshot().LeaderlessSamplesBelowThreshold(3)) // 3 >= 3, should fail
+	assert.True(t, state.Snapshot().LeaderlessSamplesBelowThreshold(4))  // 3 < 4, should pass
 }
 
 func TestGetActivePeer(t *testing.T) {
@@ -145,7 +152,7 @@ func TestGetActivePeer(t *testing.T) {
 	state := NewState(opts)
 
 	// Test with no active peers
-	_, err := state.GetActivePeer()
+	_, err := state.Snapshot().GetActivePeer()
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "no active peer found")
 
@@ -158,7 +165,7 @@ func TestGetActivePeer(t *testing.T) {
 	}
 	state.peerStatesByName["peer1"] = activePeer
 
-	peerState, err := state.GetActivePeer()
+	peerState, err := state.Snapshot().GetActivePeer()
 	assert.NoError(t, err)
 	assert.Equal(t, activePeer.IP, peerState.IP)
 	assert.Equal(t, activePeer.Pubkey, peerState.Pubkey)
@@ -178,14 +185,14 @@ func TestHasPeers(t *testing.T) {
 	state := NewState(opts)
 
 	// Test with no peers
-	assert.False(t, state.HasPeers("192.168.1.1"))
+	assert.False(t, state.Snapshot().HasPeers("192.168.1.1"))
 
 	// Test with only self IP
 	state.peerStatesByName = map[string]PeerState{
 		"peer1": {IP: "192.168.1.1", Pubkey: "pubkey1", LastSeenAtUTC: time.Now().UTC(), LastSeenActive: false},
 	}
 
-	assert.False(t, state.HasPeers("192.168.1.1"))
+	assert.False(t, state.Snapshot().HasPeers("192.168.1.1"))
 
 	// Test with other peers
 	state.peerStatesByName["peer2"] = PeerState{
@@ -195,11 +202,11 @@ func TestHasPeers(t *testing.T) {
 		LastSeenActive: false,
 	}
 
-	assert.True(t, state.HasPeers("192.168.1.1"))
-	assert.True(t, state.HasPeers("192.168.1.2"))
+	assert.True(t, state.Snapshot().HasPeers("192.168.1.1"))
+	assert.True(t, state.Snapshot().HasPeers("192.168.1.2"))
 }
 
-func TestGetPeerStates(t *testing.T) {
+func TestSnapshot(t *testing.T) {
 	realRPC := rpc.NewClient("test", "https://api.mainnet-beta.solana.com")
 
 	opts := Options{
@@ -212,8 +219,10 @@ func TestGetPeerStates(t *testing.T) {
 	state := NewState(opts)
 
 	// Test with empty state
-	peerStates := state.GetPeerStates()
-	assert.Empty(t, peerStates)
+	snapshot := state.Snapshot()
+	assert.Empty(t, snapshot.PeerStates)
+	assert.False(t, snapshot.TakenAt.IsZero())
+	assert.False(t, snapshot.HasClusterSample())
 
 	// Test with populated state
 	expectedStates := map[string]PeerState{
@@ -221,9 +230,15 @@ func TestGetPeerStates(t *testing.T) {
 		"peer2": {IP: "192.168.1.3", Pubkey: "pubkey2", LastSeenAtUTC: time.Now().UTC(), LastSeenActive: true},
 	}
 	state.peerStatesByName = expectedStates
+	state.leaderlessSamplesCount = 2
+
+	snapshot = state.Snapshot()
+	assert.Equal(t, expectedStates, snapshot.PeerStates)
+	assert.Equal(t, 2, snapshot.LeaderlessSamplesCount)
 
-	peerStates = state.GetPeerStates()
-	assert.Equal(t, expectedStates, peerStates)
+	// Test the snapshot is a copy
+	delete(snapshot.PeerStates, "peer1")
+	assert.Len(t, state.Snapshot().PeerStates, 2)
 }
 
 func TestPeerState_LastSeenAtString(t *testing.T) {
@@ -264,8 +279,11 @@ func TestRefresh_WithRPCError(t *testing.T) {
 	state.Refresh()
 
 	// Verify the state was cleared
-	assert.False(t, state.PeerStatesRefreshedAt.IsZero())
-	assert.Empty(t, state.GetPeerStates())
+	assert.False(t, state.Snapshot().RefreshedAt.IsZero())
+	assert.Empty(t, state.Snapshot().PeerStates)
+
+	// a failed refresh is not a cluster sample
+	assert.False(t, state.Snapshot().HasClusterSample())
 }
 
 func TestRefresh_WithValidRPC(t *testing.T) {
@@ -288,7 +306,7 @@ func TestRefresh_WithValidRPC(t *testing.T) {
 	state.Refresh()
 
 	// Verify the state was updated (timestamp should be set)
-	assert.False(t, state.PeerStatesRefreshedAt.IsZero())
+	assert.False(t, state.Snapshot().RefreshedAt.IsZero())
 
 	// The actual peer states will depend on the RPC response, but we can verify the method completed
 	// without panicking and updated the timestamp
@@ -313,10 +331,10 @@ func TestState_EdgeCases(t *testing.T) {
 	}
 
 	// Should find at least one active peer
-	assert.True(t, state.HasActivePeer())
+	assert.True(t, state.Snapshot().HasActivePeer())
 
 	// GetActivePeer should return the first one it finds
-	peerState, err := state.GetActivePeer()
+	peerState, err := state.Snapshot().GetActivePeer()
 	assert.NoError(t, err)
 	assert.True(t, peerState.LastSeenActive)
 }
@@ -334,16 +352,16 @@ func TestState_EmptyConfigPeers(t *testing.T) {
 	state := NewState(opts)
 
 	// Test all methods with empty config
-	assert.False(t, state.HasActivePeer())
-	state.LeaderlessSamplesCount = 5 // Set count high
-	assert.False(t, state.LeaderlessSamplesBelowThreshold(3))
-	assert.False(t, state.HasIP("192.168.1.1"))
-	assert.False(t, state.HasPeers("192.168.1.1"))
+	assert.False(t, state.Snapshot().HasActivePeer())
+	state.leaderlessSamplesCount = 5 // Set count high
+	assert.False(t, state.Snapshot().LeaderlessSamplesBelowThreshold(3))
+	assert.False(t, state.Snapshot().HasIP("192.168.1.1"))
+	assert.False(t, state.Snapshot().HasPeers("192.168.1.1"))
 
-	_, err := state.GetActivePeer()
+	_, err := state.Snapshot().GetActivePeer()
 	assert.Error(t, err)
 
-	peerStates := state.GetPeerStates()
+	peerStates := state.Snapshot().PeerStates
 	assert.Empty(t, peerStates)
 }
 
@@ -369,26 +387,109 @@ func TestState_SampleBasedLogic(t *testing.T) {
 			LastSeenActive: true,
 		},
 	}
-	state.LeaderlessSamplesCount = 0 // Reset when active peer found
+	state.leaderlessSamplesCount = 0 // Reset when active peer found
 
 	// Should pass with threshold of 3 (0 < 3)
-	assert.True(t, state.LeaderlessSamplesBelowThreshold(3))
+	assert.True(t, state.Snapshot().LeaderlessSamplesBelowThreshold(3))
 
 	// Should pass with threshold of 1 (0 < 1)
-	assert.True(t, state.LeaderlessSamplesBelowThreshold(1))
+	assert.True(t, state.Snapshot().LeaderlessSamplesBelowThreshold(1))
 
 	// Test with no active peer (LeaderlessSamplesCount increments)
-	state.LeaderlessSamplesCount = 5
+	state.leaderlessSamplesCount = 5
 	delete(state.peerStatesByName, "peer1") // Remove active peer
 
 	// Should fail with threshold of 3 (5 >= 3)
-	assert.False(t, state.LeaderlessSamplesBelowThreshold(3))
+	assert.False(t, state.Snapshot().LeaderlessSamplesBelowThreshold(3))
 
 	// Should pass with threshold of 10 (5 < 10)
-	assert.True(t, state.LeaderlessSamplesBelowThreshold(10))
+	assert.True(t, state.Snapshot().LeaderlessSamplesBelowThreshold(10))
+}
+
+// testClusterRPC serves a fixed set of cluster nodes with the active identity voting, voteLagSlots behind
+type testClusterRPC struct {
+	nodes        []*solanagorpc.GetClusterNodesResult
+	voteLagSlots uint64
+	// delinquent lists the nodes' vote accounts as delinquent, with their identities holding balance lamports
+	delinquent bool
+	balance    uint64
+}
+
+func (r *testClusterRPC) GetClusterNodes(ctx context.Context) ([]*solanagorpc.GetClusterNodesResult, error) {
+	return r.nodes, nil
+}
+
+func (r *testClusterRPC) GetSlot(ctx context.Context) (uint64, error) {
+	return 100 + r.voteLagSlots, nil
+}
+
+func (r *testClusterRPC) GetVoteAccounts(ctx context.Context) (*solanagorpc.GetVoteAccountsResult, error) {
+	voteAccounts := &solanagorpc.GetVoteAccountsResult{}
+	for _, node := range r.nodes {
+		voteAccount := solanagorpc.VoteAccountsResult{NodePubkey: node.Pubkey, LastVote: 100}
+		if r.delinquent {
+			voteAccounts.Delinquent = append(voteAccounts.Delinquent, voteAccount)
+			continue
+		}
+		voteAccounts.Current = append(voteAccounts.Current, voteAccount)
+	}
+	return voteAccounts, nil
+}
+
+func (r *testClusterRPC) GetBalance(ctx context.Context, pubkey solanago.PublicKey) (*solanagorpc.GetBalanceResult, error) {
+	return &solanagorpc.GetBalanceResult{Value: r.balance}, nil
+}
+
+func (r *testClusterRPC) GetMinimumBalanceForRentExemption(ctx context.Context, dataSize uint64) (uint64, error) {
+	return 890880, nil
 }
 
 func TestState_ConcurrentAccess(t *testing.T) {
+	activePubkey := solanago.NewWallet().PublicKey()
+	gossipAddress := "192.168.1.2:8001"
+
+	state := NewState(Options{
+		ClusterRPC:   &testClusterRPC{nodes: []*solanagorpc.GetClusterNodesResult{{Pubkey: activePubkey, Gossip: &gossipAddress}}},
+		ActivePubkey: activePubkey.String(),
+		SelfIP:       "192.168.1.1",
+		ConfigPeers: map[string]config.Peer{
+			"peer1": {IP: "192.168.1.2", Name: "peer1"},
+			"peer2": {IP: "192.168.1.3", Name: "peer2"},
+		},
+		DialFunc: func(network, address string) (net.Conn, error) {
+			conn, _ := net.Pipe()
+			return conn, nil
+		},
+	})
+
+	// refresh while other goroutines read and scribble over their snapshots
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			state.Refresh()
+		}()
+		go func() {
+			defer wg.Done()
+			snapshot := state.Snapshot()
+			snapshot.HasActivePeer()
+			snapshot.HasIP("192.168.1.1")
+			snapshot.HasPeers("192.168.1.1")
+			snapshot.PeerStates["peer2"] = PeerState{Name: "peer2", IP: "192.168.1.3"}
+			snapshot.MissingGossipIPs = append(snapshot.MissingGossipIPs[:0], "changed")
+		}()
+	}
+	wg.Wait()
+
+	snapshot := state.Snapshot()
+	assert.Len(t, snapshot.PeerStates, 1)
+	assert.Equal(t, []string{"192.168.1.3"}, snapshot.MissingGossipIPs)
+	assert.Equal(t, []string{"peer1"}, snapshot.ActivePeerNames)
+	assert.Equal(t, 0, snapshot.LeaderlessSamplesCount)
+}
+
+func TestGetPassivePeers(t *testing.T) {
 	realRPC := rpc.NewClient("test", "https://api.mainnet-beta.solana.com")
 
 	opts := Options{
@@ -400,25 +501,374 @@ func TestState_ConcurrentAccess(t *testing.T) {
 
 	state := NewState(opts)
 
-	// Test concurrent access to state methods
-	// This is mainly to ensure the methods are thread-safe
-	done := make(chan bool, 10)
+	// Test with empty state
+	assert.Empty(t, state.Snapshot().GetPassivePeers("192.168.1.1"))
 
-	for i := 0; i < 10; i++ {
-		go func() {
-			state.HasActivePeer()
-			state.HasIP("192.168.1.1")
-			state.HasPeers("192.168.1.1")
-			state.GetPeerStates()
-			done <- true
-		}()
+	// Test with populated state
+	state.peerStatesByName = map[string]PeerState{
+		"self":  {Name: "self", IP: "192.168.1.1", Pubkey: "test-active-pubkey", LastSeenActive: true},
+		"peer1": {Name: "peer1", IP: "192.168.1.2", Pubkey: "pubkey1", LastSeenActive: false},
+		"peer2": {Name: "peer2", IP: "192.168.1.3", Pubkey: "pubkey2", LastSeenActive: false},
 	}
 
-	// Wait for all goroutines to complete
-	for i := 0; i < 10; i++ {
-		<-done
+	passivePeers := state.Snapshot().GetPassivePeers("192.168.1.1")
+	assert.Len(t, passivePeers, 2)
+	for _, peer := range passivePeers {
+		assert.False(t, peer.LastSeenActive)
+		assert.NotEqual(t, "192.168.1.1", peer.IP)
+	}
+
+	// Test excluding a passive peer
+	assert.Len(t, state.Snapshot().GetPassivePeers("192.168.1.2"), 1)
+}
+
+func TestGetActivePeerNames(t *testing.T) {
+	realRPC := rpc.NewClient("test", "https://api.mainnet-beta.solana.com")
+
+	opts := Options{
+		ClusterRPC:   realRPC,
+		ActivePubkey: "test-active-pubkey",
+		SelfIP:       "192.168.1.1",
+		ConfigPeers:  map[string]config.Peer{},
 	}
 
-	// If we get here without panicking, the methods are thread-safe
-	assert.True(t, true)
+	state := NewState(opts)
+
+	// Test with empty state
+	assert.Empty(t, state.Snapshot().ActivePeerNames)
+
+	// Test with multiple claimants
+	state.activePeerNames = []string{"peer1", "peer2"}
+	activePeerNames := state.Snapshot().ActivePeerNames
+	assert.Equal(t, []string{"peer1", "peer2"}, activePeerNames)
+
+	// Test the returned slice is a copy
+	activePeerNames[0] = "changed"
+	assert.Equal(t, []string{"peer1", "peer2"}, state.Snapshot().ActivePeerNames)
+}
+
+// testEndpointsClusterRPC serves each endpoint's cluster nodes, with the active identity voting
+type testEndpointsClusterRPC struct {
+	testClusterRPC
+	endpointResults []rpc.EndpointResult[[]*solanagorpc.GetClusterNodesResult]
+}
+
+func (r *testEndpointsClusterRPC) GetClusterNodesFromEach(ctx context.Context) []rpc.EndpointResult[[]*solanagorpc.GetClusterNodesResult] {
+	return r.endpointResults
+}
+
+func TestRefresh_RPCConsensus(t *testing.T) {
+	activePubkey := solanago.NewWallet().PublicKey()
+	passivePubkey := solanago.NewWallet().PublicKey()
+	activeGossip, passiveGossip := "192.168.1.2:8001", "192.168.1.3:8001"
+	leaderless := []*solanagorpc.GetClusterNodesResult{{Pubkey: passivePubkey, Gossip: &passiveGossip}}
+	withActivePeer := []*solanagorpc.GetClusterNodesResult{
+		{Pubkey: activePubkey, Gossip: &activeGossip},
+		{Pubkey: passivePubkey, Gossip: &passiveGossip},
+	}
+
+	clusterRPC := &testEndpointsClusterRPC{testClusterRPC: testClusterRPC{nodes: withActivePeer}}
+	state := NewState(Options{
+		ClusterRPC:   clusterRPC,
+		ActivePubkey: activePubkey.String(),
+		ConfigPeers: map[string]config.Peer{
+			"peer1": {IP: "192.168.1.2", Name: "peer1"},
+			"peer2": {IP: "192.168.1.3", Name: "peer2"},
+		},
+		RPCQuorum: 2,
+		DialFunc: func(network, address string) (net.Conn, error) {
+			conn, _ := net.Pipe()
+			return conn, nil
+		},
+	})
+
+	// a single lagging endpoint showing the cluster leaderless is outvoted
+	clusterRPC.endpointResults = []rpc.EndpointResult[[]*solanagorpc.GetClusterNodesResult]{
+		{URL: "https://rpc-1.example.com/secret-token", Result: leaderless},
+		{URL: "https://rpc-2.example.com", Result: withActivePeer},
+		{URL: "https://rpc-3.example.com", Err: errors.New("timeout")},
+	}
+	state.Refresh()
+	snapshot := state.Snapshot()
+	assert.False(t, snapshot.HasActivePeer())
+	assert.Equal(t, 0, snapshot.LeaderlessSamplesCount)
+	assert.True(t, snapshot.EndpointsDisagree())
+	require.Len(t, snapshot.EndpointViews, 3)
+	assert.Equal(t, EndpointView{Endpoint: "rpc-1.example.com", Leaderless: true}, snapshot.EndpointViews[0])
+	assert.Equal(t, EndpointView{Endpoint: "rpc-2.example.com"}, snapshot.EndpointViews[1])
+	assert.Error(t, snapshot.EndpointViews[2].Err)
+
+	// a quorum of endpoints showing the cluster leaderless counts
+	clusterRPC.endpointResults[2] = rpc.EndpointResult[[]*solanagorpc.GetClusterNodesResult]{URL: "https://rpc-3.example.com", Result: leaderless}
+	clusterRPC.endpointResults[1].Result = leaderless
+	state.Refresh()
+	snapshot = state.Snapshot()
+	assert.Equal(t, 1, snapshot.LeaderlessSamplesCount)
+	assert.False(t, snapshot.EndpointsDisagree())
+
+	// the first endpoint to answer is the one the state is built from
+	clusterRPC.endpointResults[0] = rpc.EndpointResult[[]*solanagorpc.GetClusterNodesResult]{URL: "https://rpc-1.example.com", Err: errors.New("timeout")}
+	clusterRPC.endpointResults[1].Result = withActivePeer
+	state.Refresh()
+	snapshot = state.Snapshot()
+	assert.True(t, snapshot.HasActivePeer())
+	assert.Equal(t, 0, snapshot.LeaderlessSamplesCount)
+	assert.True(t, snapshot.EndpointsDisagree())
+
+	// all endpoints failing is as any failure to get cluster nodes
+	clusterRPC.endpointResults[1].Err = errors.New("timeout")
+	clusterRPC.endpointResults[2].Err = errors.New("timeout")
+	state.Refresh()
+	snapshot = state.Snapshot()
+	assert.False(t, snapshot.HasPeers(""))
+	assert.Len(t, snapshot.EndpointViews, 3)
+}
+
+func TestNewState_RPCConsensusUnsupported(t *testing.T) {
+	// cluster rpcs that cannot query each endpoint go by the first to answer
+	state := NewState(Options{ClusterRPC: &testClusterRPC{}, RPCQuorum: 2})
+	assert.Nil(t, state.endpointsRPC)
+
+	state = NewState(Options{ClusterRPC: &testEndpointsClusterRPC{}, RPCQuorum: 2})
+	assert.NotNil(t, state.endpointsRPC)
+}
+
+func TestRefresh_VoteLag(t *testing.T) {
+	activePubkey := solanago.NewWallet().PublicKey()
+	gossipAddress := "192.168.1.2:8001"
+
+	clusterRPC := &testClusterRPC{nodes: []*solanagorpc.GetClusterNodesResult{{Pubkey: activePubkey, Gossip: &gossipAddress}}}
+	newState := func(maxVoteLagSlots uint64) *State {
+		return NewState(Options{
+			ClusterRPC:              clusterRPC,
+			ActivePubkey:            activePubkey.String(),
+			ConfigPeers:             map[string]config.Peer{"peer1": {IP: "192.168.1.2", Name: "peer1"}},
+			MaxVoteLagSlots:         maxVoteLagSlots,
+			VoteLagSamplesThreshold: 2,
+			DialFunc: func(network, address string) (net.Conn, error) {
+				conn, _ := net.Pipe()
+				return conn, nil
+			},
+		})
+	}
+
+	// without max_vote_lag_slots the lag is only reported
+	clusterRPC.voteLagSlots = 100
+	state := newState(0)
+	for range 3 {
+		state.Refresh()
+		assert.True(t, state.Snapshot().HasActivePeer())
+	}
+	assert.Equal(t, map[string]uint64{"peer1": 100}, state.Snapshot().VoteLagSlots)
+
+	// with it, the active peer is not voting once it has lagged too far for vote_lag_samples_threshold samples
+	state = newState(32)
+	state.Refresh()
+	assert.True(t, state.Snapshot().HasActivePeer())
+	state.Refresh()
+	snapshot := state.Snapshot()
+	assert.False(t, snapshot.HasActivePeer())
+	assert.Equal(t, 1, snapshot.LeaderlessSamplesCount)
+	assert.Equal(t, map[string]uint64{"peer1": 100}, snapshot.VoteLagSlots)
+
+	// catching up resets the count
+	clusterRPC.voteLagSlots = 2
+	state.Refresh()
+	assert.True(t, state.Snapshot().HasActivePeer())
+	assert.Equal(t, map[string]uint64{"peer1": 2}, state.Snapshot().VoteLagSlots)
+
+	clusterRPC.voteLagSlots = 100
+	state.Refresh()
+	assert.True(t, state.Snapshot().HasActivePeer())
+}
+
+func TestRefresh_DelinquentBelowRentExemptMinimum(t *testing.T) {
+	activePubkey := solanago.NewWallet().PublicKey()
+	gossipAddress := "192.168.1.2:8001"
+
+	tests := []struct {
+		name       string
+		balance    uint64
+		wantVoting bool
+	}{
+		// failing over would not fix a delinquency the active identity's balance causes
+		{name: "below rent-exempt minimum", balance: 890_000, wantVoting: true},
+		{name: "above rent-exempt minimum", balance: 1_000_000_000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := NewState(Options{
+				ClusterRPC: &testClusterRPC{
+					nodes:      []*solanagorpc.GetClusterNodesResult{{Pubkey: activePubkey, Gossip: &gossipAddress}},
+					delinquent: true,
+					balance:    tt.balance,
+				},
+				ActivePubkey:  activePubkey.String(),
+				ConfigPeers:   map[string]config.Peer{"peer1": {IP: "192.168.1.2", Name: "peer1"}},
+				ProbeStrategy: config.GossipProbeStrategyNone,
+			})
+			state.Refresh()
+			assert.Equal(t, tt.wantVoting, state.Snapshot().HasActivePeer())
+		})
+	}
+}
+
+func TestNodeGossipAddress(t *testing.T) {
+	address := func(gossip string) *solanagorpc.GetClusterNodesResult {
+		return &solanagorpc.GetClusterNodesResult{Gossip: &gossip}
+	}
+
+	ip, port, err := nodeGossipAddress(address("192.168.1.2:8001"))
+	require.NoError(t, err)
+	assert.Equal(t, "192.168.1.2", ip.String())
+	assert.Equal(t, 8001, port)
+
+	ip, port, err = nodeGossipAddress(address("[2001:db8::2]:8001"))
+	require.NoError(t, err)
+	assert.Equal(t, "2001:db8::2", ip.String())
+	assert.Equal(t, 8001, port)
+
+	_, _, err = nodeGossipAddress(&solanagorpc.GetClusterNodesResult{})
+	assert.EqualError(t, err, "no gossip address")
+	_, _, err = nodeGossipAddress(address("192.168.1.2"))
+	assert.ErrorContains(t, err, "invalid gossip address 192.168.1.2")
+	_, _, err = nodeGossipAddress(address("validator.example.com:8001"))
+	assert.EqualError(t, err, "invalid gossip address validator.example.com:8001: validator.example.com is not an IP address")
+}
+
+func TestRefresh_PeerMatching(t *testing.T) {
+	activePubkey := solanago.NewWallet().PublicKey()
+	passivePubkey := solanago.NewWallet().PublicKey()
+	node := func(pubkey solanago.PublicKey, gossip string) *solanagorpc.GetClusterNodesResult {
+		return &solanagorpc.GetClusterNodesResult{Pubkey: pubkey, Gossip: &gossip}
+	}
+
+	tests := []struct {
+		name              string
+		peer              config.Peer
+		nodes             []*solanagorpc.GetClusterNodesResult
+		wantPeer          bool
+		wantActive        bool
+		wantGossipAddress string
+	}{
+		{
+			name:              "active at ipv4 address",
+			peer:              config.Peer{IP: "192.168.1.2"},
+			nodes:             []*solanagorpc.GetClusterNodesResult{node(activePubkey, "192.168.1.2:8001")},
+			wantPeer:          true,
+			wantActive:        true,
+			wantGossipAddress: "192.168.1.2:8001",
+		},
+		{
+			name:              "active at further ipv6 address",
+			peer:              config.Peer{IP: "192.168.1.2", IPs: []string{"2001:db8::2"}},
+			nodes:             []*solanagorpc.GetClusterNodesResult{node(activePubkey, "[2001:db8:0::2]:8001")},
+			wantPeer:          true,
+			wantActive:        true,
+			wantGossipAddress: "[2001:db8:0::2]:8001",
+		},
+		{
+			name:  "other gossip port",
+			peer:  config.Peer{IP: "192.168.1.2", GossipPort: 8001},
+			nodes: []*solanagorpc.GetClusterNodesResult{node(activePubkey, "192.168.1.2:9001")},
+		},
+		{
+			name:              "passive with its pubkey",
+			peer:              config.Peer{IP: "192.168.1.2", Pubkey: passivePubkey.String()},
+			nodes:             []*solanagorpc.GetClusterNodesResult{node(passivePubkey, "192.168.1.2:8001")},
+			wantPeer:          true,
+			wantGossipAddress: "192.168.1.2:8001",
+		},
+		{
+			name:  "other pubkey at peer address",
+			peer:  config.Peer{IP: "192.168.1.2", Pubkey: passivePubkey.String()},
+			nodes: []*solanagorpc.GetClusterNodesResult{node(solanago.NewWallet().PublicKey(), "192.168.1.2:8001")},
+		},
+		{
+			name:  "active identity without contact info",
+			peer:  config.Peer{IP: "192.168.1.2"},
+			nodes: []*solanagorpc.GetClusterNodesResult{{Pubkey: activePubkey}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.peer.Name = "peer1"
+			state := NewState(Options{
+				ClusterRPC:    &testClusterRPC{nodes: tt.nodes},
+				ActivePubkey:  activePubkey.String(),
+				ConfigPeers:   map[string]config.Peer{"peer1": tt.peer},
+				ProbeStrategy: config.GossipProbeStrategyNone,
+			})
+			state.Refresh()
+
+			snapshot := state.Snapshot()
+			peerState, ok := snapshot.PeerStates["peer1"]
+			require.Equal(t, tt.wantPeer, ok)
+			assert.Equal(t, tt.wantActive, snapshot.HasActivePeer())
+			if tt.wantPeer {
+				// peers are known by their configured IP whichever address they appear at
+				assert.Equal(t, "192.168.1.2", peerState.IP)
+				assert.Equal(t, tt.wantGossipAddress, peerState.GossipAddress)
+			}
+		})
+	}
+}
+
+func TestRefresh_StampsWithClock(t *testing.T) {
+	activePubkey := solanago.NewWallet().PublicKey()
+	gossip := "192.168.1.2:8001"
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	virtualClock := clock.NewVirtual(now)
+
+	state := NewState(Options{
+		ClusterRPC:    &testClusterRPC{nodes: []*solanagorpc.GetClusterNodesResult{{Pubkey: activePubkey, Gossip: &gossip}}},
+		ActivePubkey:  activePubkey.String(),
+		ConfigPeers:   map[string]config.Peer{"peer1": {Name: "peer1", IP: "192.168.1.2"}},
+		ProbeStrategy: config.GossipProbeStrategyNone,
+		Clock:         virtualClock,
+	})
+	state.Refresh()
+	virtualClock.Advance(time.Minute)
+
+	snapshot := state.Snapshot()
+	assert.Equal(t, now, snapshot.RefreshedAt)
+	assert.Equal(t, now, snapshot.ClusterSampledAt)
+	assert.Equal(t, now, snapshot.PeerStates["peer1"].LastSeenAtUTC)
+	assert.Equal(t, now.Add(time.Minute), snapshot.TakenAt)
+}
+
+func TestRefresh_RejectsUnexpectedIdentity(t *testing.T) {
+	activePubkey := solanago.NewWallet().PublicKey()
+	passivePubkey := solanago.NewWallet().PublicKey()
+	spoofPubkey := solanago.NewWallet().PublicKey()
+	genuineGossip := "192.168.1.2:8001"
+	spoofGossip := "[2001:db8::2]:8001"
+
+	state := NewState(Options{
+		ClusterRPC: &testClusterRPC{nodes: []*solanagorpc.GetClusterNodesResult{
+			{Pubkey: passivePubkey, Gossip: &genuineGossip},
+			{Pubkey: spoofPubkey, Gossip: &spoofGossip},
+		}},
+		ActivePubkey: activePubkey.String(),
+		ConfigPeers: map[string]config.Peer{"peer1": {
+			Name:   "peer1",
+			IP:     "192.168.1.2",
+			IPs:    []string{"2001:db8::2"},
+			Pubkey: passivePubkey.String(),
+		}},
+		ProbeStrategy: config.GossipProbeStrategyNone,
+	})
+	state.Refresh()
+
+	// the genuine peer is kept while the node spoofing its other address is rejected and reported
+	snapshot := state.Snapshot()
+	require.Contains(t, snapshot.PeerStates, "peer1")
+	assert.Equal(t, passivePubkey.String(), snapshot.PeerStates["peer1"].Pubkey)
+	assert.Equal(t, map[string][]string{"peer1": {spoofPubkey.String()}}, snapshot.UnexpectedIdentities)
+
+	// snapshots are copies
+	snapshot.UnexpectedIdentities["peer1"][0] = "changed"
+	assert.Equal(t, spoofPubkey.String(), state.Snapshot().UnexpectedIdentities["peer1"][0])
 }
@@ -0,0 +1,182 @@
+package ha
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	solanago "github.com/gagliardetto/solana-go"
+	solanagorpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/rpc"
+)
+
+// verifyActivation confirms we are voting after taking over as active - local getIdentity must report the
+// active identity and then our LastVote in the cluster's getVoteAccounts must advance within
+// failover.activation_verify_timeout_duration. On failure we roll back if failover.on_activation_failure
+// is rollback and the verification error is returned
+func (m *Manager) verifyActivation() error {
+	timeout := m.cfg.Failover.ActivationVerifyTimeoutDuration
+	if timeout <= 0 {
+		return nil
+	}
+
+	if m.cfg.Failover.DryRun {
+		m.logger.Debug("dry run - skipping activation verification")
+		return nil
+	}
+
+	m.logger.Info("verifying activation", "activation_verify_timeout", timeout)
+
+	ctx, cancel := m.clock.WithTimeout(m.ctx, timeout)
+	defer cancel()
+
+	err := m.waitForVoting(ctx)
+	if err == nil {
+		m.logger.Info("activation verified - we are voting")
+		return nil
+	}
+
+	m.logger.Error("‼️ activation verification failed", "error", err, "on_activation_failure", m.cfg.Failover.OnActivationFailure)
+	if m.cfg.Failover.OnActivationFailure != config.FailoverOnActivationFailureRollback {
+		return fmt.Errorf("activation verification failed: %w", err)
+	}
+
+	m.rollbackActivation()
+	return fmt.Errorf("activation verification failed and was rolled back: %w", err)
+}
+
+// waitForVoting polls local getIdentity until it reports the active identity, then the cluster's
+// getVoteAccounts until our LastVote advances past what it was when we became active, or ctx is done. Cluster
+// RPC endpoints can be slots apart, so each endpoint's LastVote is only compared with its own baseline
+func (m *Manager) waitForVoting(ctx context.Context) error {
+	for !m.isSelfActive() {
+		m.logger.Debug("waiting for local rpc to report the active identity")
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("local rpc does not report the active identity: %w", ctx.Err())
+		case <-m.clock.After(m.cfg.Failover.PollIntervalDuration):
+		}
+	}
+
+	// the previous active's last vote (if any) on each endpoint is the baseline we must advance past there
+	baselineLastVotes, err := m.getActiveLastVotes(ctx)
+	for err != nil {
+		m.logger.Warn("failed to get baseline last vote", "error", err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("unable to get baseline last vote: %w", err)
+		case <-m.clock.After(m.cfg.Failover.PollIntervalDuration):
+		}
+		baselineLastVotes, err = m.getActiveLastVotes(ctx)
+	}
+	m.logger.Debug("waiting for last vote to advance", "baseline_last_votes", baselineLastVotes)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("last vote did not advance past slot %d: %w", slices.Max(slices.Collect(maps.Values(baselineLastVotes))), ctx.Err())
+		case <-m.clock.After(m.cfg.Failover.PollIntervalDuration):
+		}
+
+		lastVotes, err := m.getActiveLastVotes(ctx)
+		if err != nil {
+			m.logger.Warn("failed to get last vote", "error", err)
+			continue
+		}
+
+		for url, lastVote := range lastVotes {
+			baselineLastVote, ok := baselineLastVotes[url]
+			if !ok {
+				// an endpoint that did not answer before starts its own baseline now
+				baselineLastVotes[url] = lastVote
+				continue
+			}
+			if lastVote > baselineLastVote {
+				m.logger.Debug("last vote advanced", "baseline_last_vote", baselineLastVote, "last_vote", lastVote)
+				return nil
+			}
+		}
+	}
+}
+
+// EndpointsVoteRPC is a cluster RPC that can get slots and vote accounts from each of its endpoints, so that
+// votes are only ever followed within one endpoint's view
+type EndpointsVoteRPC interface {
+	GetSlotFromEach(ctx context.Context) []rpc.EndpointResult[uint64]
+	GetVoteAccountsFromEach(ctx context.Context) []rpc.EndpointResult[*solanagorpc.GetVoteAccountsResult]
+}
+
+// getVoteAccountsFromEach returns the vote accounts from each cluster RPC endpoint - a cluster RPC that cannot
+// query each endpoint counts as a single one
+func (m *Manager) getVoteAccountsFromEach(ctx context.Context) []rpc.EndpointResult[*solanagorpc.GetVoteAccountsResult] {
+	if endpointsRPC, ok := m.clusterRPC.(EndpointsVoteRPC); ok {
+		return endpointsRPC.GetVoteAccountsFromEach(ctx)
+	}
+	voteAccounts, err := m.clusterRPC.GetVoteAccounts(ctx)
+	return []rpc.EndpointResult[*solanagorpc.GetVoteAccountsResult]{{Result: voteAccounts, Err: err}}
+}
+
+// getActiveLastVotes returns the LastVote of the active identity's vote account as reported by each cluster
+// RPC endpoint, keyed by URL. It fails only if no endpoint reported one
+func (m *Manager) getActiveLastVotes(ctx context.Context) (lastVotes map[string]uint64, err error) {
+	activePubkey := m.cfg.Validator.Identities.ActivePublicKey()
+	lastVotes = map[string]uint64{}
+	var errs []error
+	for _, result := range m.getVoteAccountsFromEach(ctx) {
+		if result.Err != nil {
+			errs = append(errs, result.Err)
+			continue
+		}
+		lastVote, ok := activeLastVote(result.Result, activePubkey)
+		if !ok {
+			errs = append(errs, fmt.Errorf("no vote account found for active identity %s", activePubkey))
+			continue
+		}
+		lastVotes[result.URL] = lastVote
+	}
+
+	if len(lastVotes) == 0 {
+		return nil, errors.Join(errs...)
+	}
+	return lastVotes, nil
+}
+
+// activeLastVote returns the LastVote of activePubkey's vote account among voteAccounts, if it has one
+func activeLastVote(voteAccounts *solanagorpc.GetVoteAccountsResult, activePubkey solanago.PublicKey) (lastVote uint64, ok bool) {
+	for _, voteAccount := range slices.Concat(voteAccounts.Current, voteAccounts.Delinquent) {
+		if voteAccount.NodePubkey.Equals(activePubkey) {
+			return voteAccount.LastVote, true
+		}
+	}
+	return 0, false
+}
+
+// rollbackActivation undoes a failed activation by running the passive command and the active rollback hooks,
+// then sits out takeovers long enough for the next-ranked peer to detect the leaderless cluster and try
+func (m *Manager) rollbackActivation() {
+	m.logger.Warn("rolling back activation")
+	m.ensurePassive()
+
+	if len(m.cfg.Failover.Active.Hooks.Rollback) > 0 {
+		m.logger.Debug("running active rollback hooks")
+		m.cfg.Failover.Active.Hooks.RunRollback(config.HooksRunOptions{
+			Ctx:          m.commandCtx,
+			DryRun:       m.cfg.Failover.DryRun,
+			LoggerPrefix: m.logPrefix,
+			LoggerArgs: []any{
+				"failover_stage", "rollback-active",
+			},
+			OnHookRun: m.recordHookRun,
+			Runner:    m.commandRunner,
+		})
+	}
+
+	backoff := m.cfg.Failover.ActivationVerifyTimeoutDuration +
+		time.Duration(m.cfg.Failover.LeaderlessSamplesThreshold)*m.cfg.Failover.PollIntervalDuration
+	m.takeoverBackoffUntil = m.clock.Now().Add(backoff)
+	m.logger.Warn("activation rolled back - leaving takeover to the next-ranked peer", "takeover_backoff", backoff)
+}
@@ -0,0 +1,155 @@
+package ha
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockRPCServer creates a mock Solana JSON-RPC server calling the result func registered for each method
+func mockRPCServer(t *testing.T, results map[string]func() any) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Method string `json:"method"`
+			ID     any    `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		response := map[string]any{"jsonrpc": "2.0", "id": request.ID}
+		if result, ok := results[request.Method]; ok {
+			response["result"] = result()
+		} else {
+			response["error"] = map[string]any{"code": -32601, "message": "Method not found"}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// createActivationTestManager returns an initialized manager whose local RPC reports us as active and whose
+// cluster RPC has an endpoint for each of lastVotes, reporting the active identity's last vote it returns
+func createActivationTestManager(t *testing.T, lastVotes ...func() uint64) *Manager {
+	cfg := createTestConfig()
+	cfg.Failover.DryRun = false
+	cfg.Failover.PollIntervalDuration = 10 * time.Millisecond
+	cfg.Failover.ActivationVerifyTimeoutDuration = 200 * time.Millisecond
+	activePubkey := cfg.Validator.Identities.ActiveKeyPair.PublicKey().String()
+
+	urls := []string{}
+	for _, lastVote := range lastVotes {
+		server := mockRPCServer(t, map[string]func() any{
+			"getIdentity": func() any { return map[string]any{"identity": activePubkey} },
+			"getVoteAccounts": func() any {
+				return map[string]any{
+					"current": []map[string]any{{
+						"votePubkey":       createTestPrivateKey("vote").PublicKey().String(),
+						"nodePubkey":       activePubkey,
+						"activatedStake":   1,
+						"epochVoteAccount": true,
+						"commission":       0,
+						"lastVote":         lastVote(),
+						"epochCredits":     [][]uint64{},
+						"rootSlot":         0,
+					}},
+					"delinquent": []map[string]any{},
+				}
+			},
+		})
+		urls = append(urls, server.URL)
+	}
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+		LocalRPC:        rpc.NewClient("local", urls[0]),
+		ClusterRPC:      rpc.NewClient("cluster", urls...),
+	})
+	require.NoError(t, manager.initialize())
+
+	return manager
+}
+
+func TestManager_VerifyActivation_Voting(t *testing.T) {
+	var lastVote atomic.Uint64
+	lastVote.Store(100)
+	manager := createActivationTestManager(t, func() uint64 { return lastVote.Add(1) })
+	manager.cfg.Failover.OnActivationFailure = config.FailoverOnActivationFailureRollback
+
+	assert.NoError(t, manager.verifyActivation())
+	assert.True(t, manager.takeoverBackoffUntil.IsZero())
+}
+
+func TestManager_VerifyActivation_NotVoting(t *testing.T) {
+	manager := createActivationTestManager(t, func() uint64 { return 100 })
+	manager.cfg.Failover.OnActivationFailure = config.FailoverOnActivationFailureNone
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := manager.waitForVoting(ctx)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "last vote did not advance past slot 100")
+
+	// no rollback without the rollback policy
+	assert.Error(t, manager.verifyActivation())
+	assert.True(t, manager.takeoverBackoffUntil.IsZero())
+}
+
+func TestManager_VerifyActivation_EndpointsApart(t *testing.T) {
+	// neither endpoint sees our votes advance - one being ahead of the other is no sign of voting
+	manager := createActivationTestManager(t, func() uint64 { return 100 }, func() uint64 { return 150 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.ErrorContains(t, manager.waitForVoting(ctx), "last vote did not advance past slot 150")
+
+	// both endpoints see our votes advance, the second well behind the first - that is voting
+	var lastVote atomic.Uint64
+	lastVote.Store(200)
+	manager = createActivationTestManager(t,
+		func() uint64 { return lastVote.Add(1) },
+		func() uint64 { return lastVote.Load() - 50 },
+	)
+	assert.NoError(t, manager.waitForVoting(context.Background()))
+}
+
+func TestManager_VerifyActivation_Rollback(t *testing.T) {
+	manager := createActivationTestManager(t, func() uint64 { return 100 })
+	manager.cfg.Failover.OnActivationFailure = config.FailoverOnActivationFailureRollback
+	manager.cfg.Failover.Active.Hooks.Rollback = []config.Hook{{Name: "rollback", Command: "true"}}
+
+	err := manager.verifyActivation()
+	assert.ErrorContains(t, err, "rolled back")
+	assert.True(t, manager.takeoverBackoffUntil.After(time.Now()))
+}
+
+func TestManager_VerifyActivation_DryRun(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Failover.ActivationVerifyTimeoutDuration = time.Minute
+	cfg.Failover.OnActivationFailure = config.FailoverOnActivationFailureRollback
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+
+	// returns immediately without verifying or rolling back
+	assert.NoError(t, manager.verifyActivation())
+	assert.True(t, manager.takeoverBackoffUntil.IsZero())
+}
@@ -0,0 +1,244 @@
+package ha
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+
+	solanago "github.com/gagliardetto/solana-go"
+	"github.com/sol-strategies/solana-validator-ha/internal/cache"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+)
+
+const (
+	// balanceAccountActiveIdentity, balanceAccountActiveVoteAccount and balanceAccountPassiveIdentity are the
+	// accounts the balance monitor watches
+	balanceAccountActiveIdentity    = "active_identity"
+	balanceAccountActiveVoteAccount = "active_vote_account"
+	balanceAccountPassiveIdentity   = "passive_identity"
+
+	// voteAccountDataSize is the size of a vote account's data, which its rent-exempt minimum is for
+	voteAccountDataSize = 3762
+)
+
+// monitoredAccount is an account the balance monitor watches and the balances below which it is warn or critical
+type monitoredAccount struct {
+	name             string
+	pubkey           solanago.PublicKey
+	warnLamports     uint64
+	criticalLamports uint64
+	// rentExemptDataSize is the data size the account must stay rent-exempt for, or nil when it need not
+	rentExemptDataSize *uint64
+}
+
+// balanceMonitorLoop checks the watched account balances every validator.balance_monitor.poll_interval_duration
+// until ctx is done. It runs apart from the HA monitor loop so that slow balance checks never hold up HA state
+// evaluation
+func (m *Manager) balanceMonitorLoop(ctx context.Context) {
+	interval := m.cfg.Validator.BalanceMonitor.PollIntervalDuration
+	m.logger.Info("monitoring balances", "poll_interval", interval)
+
+	for {
+		m.checkBalances(ctx)
+
+		select {
+		case <-ctx.Done():
+			m.logger.Debug("balance monitor loop done")
+			return
+		case <-m.clock.After(interval):
+		}
+	}
+}
+
+// checkBalances samples the balance of each watched account, logging those below their thresholds and running
+// validator.balance_monitor.hooks for each whose level changed since the last check
+func (m *Manager) checkBalances(ctx context.Context) {
+	accounts, accountsErr := m.monitoredAccounts(ctx)
+	if accountsErr != nil {
+		m.logger.Warn("failed to get accounts to monitor balances of", "error", accountsErr)
+	}
+
+	previousBalances := m.accountBalances()
+	// rent-exempt minimums are fetched once per check, falling back to the last one fetched
+	rentExemptMinimumsFetched := map[uint64]bool{}
+	if m.rentExemptMinimums == nil {
+		m.rentExemptMinimums = map[uint64]uint64{}
+	}
+	balances := make(map[string]cache.AccountBalance, len(accounts))
+	for _, account := range accounts {
+		result, err := m.clusterRPC.GetBalance(ctx, account.pubkey)
+		if err != nil {
+			m.logger.Warn("failed to get balance", "account", account.name, "pubkey", account.pubkey, "error", err)
+			continue
+		}
+		lamports := result.Value
+
+		criticalLamports := account.criticalLamports
+		if account.rentExemptDataSize != nil {
+			dataSize := *account.rentExemptDataSize
+			if !rentExemptMinimumsFetched[dataSize] {
+				rentExemptMinimumsFetched[dataSize] = true
+				rentExemptMinimum, err := m.clusterRPC.GetMinimumBalanceForRentExemption(ctx, dataSize)
+				if err != nil {
+					m.logger.Warn("failed to get rent-exempt minimum", "account", account.name, "error", err)
+				} else {
+					m.rentExemptMinimums[dataSize] = rentExemptMinimum
+				}
+			}
+			// without a rent-exempt minimum the level is unknown, so the account keeps its last sample
+			rentExemptMinimum, ok := m.rentExemptMinimums[dataSize]
+			if !ok {
+				m.logger.Warn("no rent-exempt minimum known yet - not checking balance", "account", account.name)
+				continue
+			}
+			criticalLamports = max(criticalLamports, rentExemptMinimum)
+		}
+
+		level := constants.BalanceLevelOK
+		thresholdLamports := account.warnLamports
+		switch {
+		case lamports < criticalLamports:
+			level = constants.BalanceLevelCritical
+			thresholdLamports = criticalLamports
+		case lamports < account.warnLamports:
+			level = constants.BalanceLevelWarn
+		}
+
+		balances[account.name] = cache.AccountBalance{
+			Pubkey:   account.pubkey.String(),
+			Lamports: lamports,
+			Level:    level,
+		}
+
+		logArgs := []any{
+			"account", account.name,
+			"pubkey", account.pubkey,
+			"balance_sol", lamportsToSOL(lamports),
+			"threshold_sol", lamportsToSOL(thresholdLamports),
+		}
+		switch level {
+		case constants.BalanceLevelCritical:
+			m.logger.Error("🪫 balance is critical - top up before it causes delinquency", logArgs...)
+		case constants.BalanceLevelWarn:
+			m.logger.Warn("balance is low", logArgs...)
+		default:
+			m.logger.Debug("balance is ok", logArgs...)
+		}
+
+		// an account is ok until sampled otherwise, so that starting up with a low balance runs the hooks
+		previousLevel := constants.BalanceLevelOK
+		if previous, ok := previousBalances[account.name]; ok {
+			previousLevel = previous.Level
+		}
+		if level != previousLevel {
+			m.runBalanceHooks(ctx, config.BalanceTemplateData{
+				Account:       account.name,
+				Pubkey:        account.pubkey.String(),
+				Level:         level,
+				PreviousLevel: previousLevel,
+				BalanceSOL:    lamportsToSOL(lamports),
+				ThresholdSOL:  lamportsToSOL(thresholdLamports),
+			})
+		}
+	}
+
+	// accounts whose balance could not be sampled keep their last sample, as does the vote account when the
+	// vote accounts could not be fetched to find it
+	for name, previous := range previousBalances {
+		_, sampled := balances[name]
+		monitored := accountsErr != nil || slices.ContainsFunc(accounts, func(account monitoredAccount) bool {
+			return account.name == name
+		})
+		if !sampled && monitored {
+			balances[name] = previous
+		}
+	}
+
+	m.balancesMu.Lock()
+	defer m.balancesMu.Unlock()
+	m.balances = balances
+}
+
+// monitoredAccounts returns the accounts the balance monitor watches: the active identity, its vote account
+// when it has one and the passive identity, which pays no vote fees so only has thresholds when configured
+func (m *Manager) monitoredAccounts(ctx context.Context) (accounts []monitoredAccount, err error) {
+	balanceMonitor := m.cfg.Validator.BalanceMonitor
+	activePubkey := m.cfg.Validator.Identities.ActivePublicKey()
+	identityDataSize := uint64(0)
+
+	accounts = append(accounts, monitoredAccount{
+		name:               balanceAccountActiveIdentity,
+		pubkey:             activePubkey,
+		warnLamports:       config.SOLToLamports(balanceMonitor.IdentityWarnSOL),
+		criticalLamports:   config.SOLToLamports(balanceMonitor.IdentityCriticalSOL),
+		rentExemptDataSize: &identityDataSize,
+	})
+
+	if !m.isWitness() {
+		accounts = append(accounts, monitoredAccount{
+			name:             balanceAccountPassiveIdentity,
+			pubkey:           m.cfg.Validator.Identities.PassiveKeyPair.PublicKey(),
+			warnLamports:     config.SOLToLamports(balanceMonitor.PassiveIdentityWarnSOL),
+			criticalLamports: config.SOLToLamports(balanceMonitor.PassiveIdentityCriticalSOL),
+		})
+	}
+
+	voteAccounts, err := m.clusterRPC.GetVoteAccounts(ctx)
+	if err != nil {
+		return accounts, fmt.Errorf("failed to get vote accounts: %w", err)
+	}
+
+	voteDataSize := uint64(voteAccountDataSize)
+	for _, voteAccount := range slices.Concat(voteAccounts.Current, voteAccounts.Delinquent) {
+		if voteAccount.NodePubkey.Equals(activePubkey) {
+			accounts = append(accounts, monitoredAccount{
+				name:               balanceAccountActiveVoteAccount,
+				pubkey:             voteAccount.VotePubkey,
+				warnLamports:       config.SOLToLamports(balanceMonitor.VoteAccountWarnSOL),
+				criticalLamports:   config.SOLToLamports(balanceMonitor.VoteAccountCriticalSOL),
+				rentExemptDataSize: &voteDataSize,
+			})
+			break
+		}
+	}
+
+	return accounts, nil
+}
+
+// runBalanceHooks runs validator.balance_monitor.hooks for an account whose balance level changed. They are
+// alerts rather than failover actions so run regardless of failover.dry_run, and failures are only logged
+func (m *Manager) runBalanceHooks(ctx context.Context, data config.BalanceTemplateData) {
+	for _, hook := range m.cfg.Validator.BalanceMonitor.Hooks {
+		rendered, err := config.RenderBalanceHook(hook, data)
+		if err != nil {
+			m.logger.Error("failed to render balance hook", "hook_name", hook.Name, "error", err)
+			continue
+		}
+
+		err = rendered.Run(config.HookRunOptions{
+			Ctx:          ctx,
+			HookType:     constants.HookTypeBalance,
+			LoggerPrefix: m.logPrefix,
+			LoggerArgs:   []any{"account", data.Account, "level", data.Level},
+			Runner:       m.commandRunner,
+		})
+		if err != nil {
+			m.logger.Error("balance hook failed", "hook_name", hook.Name, "account", data.Account, "error", err)
+		}
+	}
+}
+
+// accountBalances returns a copy of the last sampled balances of the watched accounts
+func (m *Manager) accountBalances() map[string]cache.AccountBalance {
+	m.balancesMu.Lock()
+	defer m.balancesMu.Unlock()
+	return maps.Clone(m.balances)
+}
+
+// lamportsToSOL formats lamports as SOL
+func lamportsToSOL(lamports uint64) string {
+	return strconv.FormatFloat(float64(lamports)/float64(solanago.LAMPORTS_PER_SOL), 'f', -1, 64)
+}
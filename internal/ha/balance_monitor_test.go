@@ -0,0 +1,200 @@
+package ha
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createBalanceMonitorTestManager returns an initialized manager whose cluster RPC reports every account holding
+// the lamports in balance, with hooks run by runner
+func createBalanceMonitorTestManager(t *testing.T, runner *fenceTestRunner, balance *atomic.Uint64) *Manager {
+	t.Helper()
+
+	cfg := createTestConfig()
+	cfg.Validator.BalanceMonitor = config.BalanceMonitor{
+		IdentityWarnSOL:     5,
+		IdentityCriticalSOL: 1,
+		Hooks: []config.Hook{{
+			Name:    "alert",
+			Command: "alert",
+			Args:    []string{"{{ .Account }}", "{{ .PreviousLevel }}", "{{ .Level }}", "{{ .BalanceSOL }}"},
+		}},
+	}
+
+	rpcClient := createBalanceMonitorTestRPC(t, cfg, balance, true)
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+		LocalRPC:        rpcClient,
+		ClusterRPC:      rpcClient,
+		CommandRunner:   runner,
+	})
+	require.NoError(t, manager.initialize())
+
+	return manager
+}
+
+// createBalanceMonitorTestRPC returns an RPC client whose every account holds balance and that finds the vote
+// account of cfg's active identity - failing getMinimumBalanceForRentExemption unless rentExempt
+func createBalanceMonitorTestRPC(t *testing.T, cfg *config.Config, balance *atomic.Uint64, rentExempt bool) *rpc.Client {
+	t.Helper()
+
+	activePubkey := cfg.Validator.Identities.ActiveKeyPair.PublicKey().String()
+	results := map[string]func() any{
+		"getBalance": func() any {
+			return map[string]any{"context": map[string]any{"slot": 100}, "value": balance.Load()}
+		},
+		"getVoteAccounts": func() any {
+			return map[string]any{
+				"current": []map[string]any{{
+					"votePubkey":       createTestPrivateKey("vote").PublicKey().String(),
+					"nodePubkey":       activePubkey,
+					"activatedStake":   1,
+					"epochVoteAccount": true,
+					"commission":       0,
+					"lastVote":         100,
+					"epochCredits":     [][]uint64{},
+					"rootSlot":         0,
+				}},
+				"delinquent": []map[string]any{},
+			}
+		},
+	}
+	if rentExempt {
+		results["getMinimumBalanceForRentExemption"] = func() any { return 27_074_400 }
+	}
+
+	return rpc.NewClient("test", mockRPCServer(t, results).URL)
+}
+
+func TestManager_CheckBalances(t *testing.T) {
+	runner := &fenceTestRunner{}
+	var balance atomic.Uint64
+	balance.Store(10_000_000_000)
+	manager := createBalanceMonitorTestManager(t, runner, &balance)
+
+	levels := func() map[string]string {
+		levels := map[string]string{}
+		for account, accountBalance := range manager.accountBalances() {
+			levels[account] = accountBalance.Level
+		}
+		return levels
+	}
+	hookArgs := func() (args [][]string) {
+		for _, run := range runner.runs {
+			args = append(args, run.Args)
+		}
+		return args
+	}
+
+	// plenty of funds
+	manager.checkBalances(context.Background())
+	assert.Equal(t, map[string]string{
+		balanceAccountActiveIdentity:    constants.BalanceLevelOK,
+		balanceAccountActiveVoteAccount: constants.BalanceLevelOK,
+		balanceAccountPassiveIdentity:   constants.BalanceLevelOK,
+	}, levels())
+	assert.Equal(t, uint64(10_000_000_000), manager.accountBalances()[balanceAccountActiveIdentity].Lamports)
+	assert.Empty(t, runner.runs)
+
+	// the active identity falls below its warn threshold - the vote account has no thresholds of its own
+	balance.Store(3_000_000_000)
+	manager.checkBalances(context.Background())
+	assert.Equal(t, constants.BalanceLevelWarn, levels()[balanceAccountActiveIdentity])
+	assert.Equal(t, constants.BalanceLevelOK, levels()[balanceAccountActiveVoteAccount])
+	assert.Equal(t, [][]string{{balanceAccountActiveIdentity, "ok", "warn", "3"}}, hookArgs())
+
+	// hooks only run when the level changes
+	manager.checkBalances(context.Background())
+	assert.Len(t, runner.runs, 1)
+
+	// below the vote account's rent-exempt minimum it is critical too, while the passive identity pays no
+	// vote fees and has no thresholds unless configured
+	balance.Store(10_000_000)
+	manager.checkBalances(context.Background())
+	assert.Equal(t, map[string]string{
+		balanceAccountActiveIdentity:    constants.BalanceLevelCritical,
+		balanceAccountActiveVoteAccount: constants.BalanceLevelCritical,
+		balanceAccountPassiveIdentity:   constants.BalanceLevelOK,
+	}, levels())
+	assert.ElementsMatch(t, [][]string{
+		{balanceAccountActiveIdentity, "ok", "warn", "3"},
+		{balanceAccountActiveIdentity, "warn", "critical", "0.01"},
+		{balanceAccountActiveVoteAccount, "ok", "critical", "0.01"},
+	}, hookArgs())
+
+	// recovery runs the hooks again
+	balance.Store(10_000_000_000)
+	manager.checkBalances(context.Background())
+	assert.Len(t, runner.runs, 5)
+	assert.Equal(t, constants.BalanceLevelOK, levels()[balanceAccountActiveIdentity])
+	assert.Equal(t, "balance-hook alert", runner.runs[4].Name)
+}
+
+func TestManager_CheckBalances_KeepsLastSampleOnError(t *testing.T) {
+	runner := &fenceTestRunner{}
+	var balance atomic.Uint64
+	balance.Store(3_000_000_000)
+	manager := createBalanceMonitorTestManager(t, runner, &balance)
+
+	manager.checkBalances(context.Background())
+	require.Equal(t, constants.BalanceLevelWarn, manager.accountBalances()[balanceAccountActiveIdentity].Level)
+
+	// a cluster RPC that does not answer leaves the last sampled balances in place
+	manager.clusterRPC = rpc.NewClient("test", "http://127.0.0.1:1")
+	manager.checkBalances(context.Background())
+	assert.Equal(t, constants.BalanceLevelWarn, manager.accountBalances()[balanceAccountActiveIdentity].Level)
+	assert.Contains(t, manager.accountBalances(), balanceAccountActiveVoteAccount)
+	assert.Len(t, runner.runs, 1)
+}
+
+func TestManager_CheckBalances_RentExemptMinimumUnknown(t *testing.T) {
+	runner := &fenceTestRunner{}
+	var balance atomic.Uint64
+	balance.Store(10_000_000)
+	manager := createBalanceMonitorTestManager(t, runner, &balance)
+	failingRPC := createBalanceMonitorTestRPC(t, manager.cfg, &balance, false)
+
+	// a level cannot be told before any rent-exempt minimum is known, so accounts that need one are not sampled
+	clusterRPC := manager.clusterRPC
+	manager.clusterRPC = failingRPC
+	manager.checkBalances(context.Background())
+	assert.NotContains(t, manager.accountBalances(), balanceAccountActiveVoteAccount)
+	assert.NotContains(t, manager.accountBalances(), balanceAccountActiveIdentity)
+	assert.Contains(t, manager.accountBalances(), balanceAccountPassiveIdentity)
+	assert.Empty(t, runner.runs)
+
+	// once known, the last rent-exempt minimum stands in for one that could not be fetched
+	manager.clusterRPC = clusterRPC
+	manager.checkBalances(context.Background())
+	require.Equal(t, constants.BalanceLevelCritical, manager.accountBalances()[balanceAccountActiveVoteAccount].Level)
+	runs := len(runner.runs)
+
+	manager.clusterRPC = failingRPC
+	manager.checkBalances(context.Background())
+	assert.Equal(t, constants.BalanceLevelCritical, manager.accountBalances()[balanceAccountActiveVoteAccount].Level)
+	assert.Len(t, runner.runs, runs)
+}
+
+func TestManager_CheckBalances_PassiveIdentityThresholds(t *testing.T) {
+	runner := &fenceTestRunner{}
+	var balance atomic.Uint64
+	balance.Store(3_000_000_000)
+	manager := createBalanceMonitorTestManager(t, runner, &balance)
+	manager.cfg.Validator.BalanceMonitor.PassiveIdentityWarnSOL = 5
+	manager.cfg.Validator.BalanceMonitor.PassiveIdentityCriticalSOL = 1
+
+	manager.checkBalances(context.Background())
+	assert.Equal(t, constants.BalanceLevelWarn, manager.accountBalances()[balanceAccountPassiveIdentity].Level)
+
+	balance.Store(10_000_000)
+	manager.checkBalances(context.Background())
+	assert.Equal(t, constants.BalanceLevelCritical, manager.accountBalances()[balanceAccountPassiveIdentity].Level)
+}
@@ -0,0 +1,157 @@
+package ha
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/charmbracelet/log"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/prometheus"
+)
+
+// NewDaemonOptions are the options for creating a daemon
+type NewDaemonOptions struct {
+	Cfg             *config.Config
+	GetPublicIPFunc func() (string, error)
+}
+
+// Daemon runs an independent manager for each of the configured groups. The managers share one metrics
+// server, with metrics labelled by group, and one health check server serving each group's HA API
+// under /groups/<name>
+type Daemon struct {
+	cfg      *config.Config
+	logger   *log.Logger
+	managers []*Manager
+
+	serversMu     sync.Mutex
+	metricsServer *http.Server
+	healthServer  *http.Server
+}
+
+// NewDaemon creates a manager for each group in opts.Cfg
+func NewDaemon(opts NewDaemonOptions) *Daemon {
+	d := &Daemon{
+		cfg:    opts.Cfg,
+		logger: log.WithPrefix("[daemon]"),
+	}
+
+	for _, groupConfig := range opts.Cfg.GroupConfigs() {
+		d.managers = append(d.managers, NewManager(NewManagerOptions{
+			Cfg:             groupConfig,
+			GetPublicIPFunc: opts.GetPublicIPFunc,
+		}))
+	}
+
+	return d
+}
+
+// Run initializes every group's manager, starts the shared servers and runs the managers' monitor loops
+// until they are stopped
+func (d *Daemon) Run() error {
+	for _, manager := range d.managers {
+		if err := manager.initialize(); err != nil {
+			return fmt.Errorf("group %s: %w", manager.cfg.Group, err)
+		}
+	}
+
+	d.startServers()
+
+	d.logger.Info("running groups", "count", len(d.managers))
+	errs := make(chan error, len(d.managers))
+	for _, manager := range d.managers {
+		go func() {
+			if err := manager.monitor(); err != nil {
+				errs <- fmt.Errorf("group %s: %w", manager.cfg.Group, err)
+				return
+			}
+			errs <- nil
+		}()
+	}
+
+	var runErrs []error
+	for range d.managers {
+		runErrs = append(runErrs, <-errs)
+	}
+	return errors.Join(runErrs...)
+}
+
+// Stop stops every group's manager concurrently, each applying its own failover.on_shutdown policy, and
+// then drains the shared servers
+func (d *Daemon) Stop(ctx context.Context) error {
+	var wg sync.WaitGroup
+	stopErrs := make([]error, len(d.managers))
+	for i, manager := range d.managers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := manager.Stop(ctx); err != nil {
+				stopErrs[i] = fmt.Errorf("group %s: %w", manager.cfg.Group, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	d.serversMu.Lock()
+	servers := []*http.Server{d.metricsServer, d.healthServer}
+	d.serversMu.Unlock()
+
+	for _, server := range servers {
+		if server == nil {
+			continue
+		}
+		if err := server.Shutdown(ctx); err != nil {
+			stopErrs = append(stopErrs, fmt.Errorf("failed to shutdown server on %s: %w", server.Addr, err))
+		}
+	}
+
+	return errors.Join(stopErrs...)
+}
+
+// Handler returns the health check server handler with the HA API of every group
+func (d *Daemon) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("healthy"))
+	})
+	for _, manager := range d.managers {
+		manager.registerAPIHandlers(mux)
+	}
+	return mux
+}
+
+// startServers starts the metrics server for all groups and the health check server for all groups' HA APIs
+func (d *Daemon) startServers() {
+	var metrics []*prometheus.Metrics
+	for _, manager := range d.managers {
+		metrics = append(metrics, manager.metrics)
+	}
+
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/metrics", prometheus.Handler(metrics...))
+
+	d.serversMu.Lock()
+	d.metricsServer = &http.Server{
+		Addr:    ":" + strconv.Itoa(d.cfg.Prometheus.Port),
+		Handler: metricsMux,
+	}
+	d.healthServer = &http.Server{
+		Addr:    ":" + strconv.Itoa(d.cfg.Prometheus.HealthCheckPort()),
+		Handler: d.Handler(),
+	}
+	servers := []*http.Server{d.metricsServer, d.healthServer}
+	d.serversMu.Unlock()
+
+	for _, server := range servers {
+		go func() {
+			d.logger.Debug("starting server", "addr", server.Addr)
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				d.logger.Error("server error", "addr", server.Addr, "error", err)
+			}
+		}()
+	}
+}
@@ -0,0 +1,132 @@
+package ha
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	solanago "github.com/gagliardetto/solana-go"
+	"github.com/sol-strategies/solana-validator-ha/internal/api"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTestDaemon creates a daemon with an initialized manager for each of groups, each with its own
+// active identity
+func createTestDaemon(t *testing.T, groups ...string) *Daemon {
+	t.Helper()
+
+	daemon := &Daemon{}
+	for _, group := range groups {
+		cfg := createTestConfig()
+		cfg.Group = group
+		cfg.Validator.Name = group + "-validator"
+		cfg.Validator.Identities.ActiveKeyPair = createTestPrivateKey("active")
+
+		manager := NewManager(NewManagerOptions{
+			Cfg:             cfg,
+			GetPublicIPFunc: mockPublicIPFunc,
+		})
+		require.NoError(t, manager.initialize())
+		daemon.managers = append(daemon.managers, manager)
+	}
+	return daemon
+}
+
+func TestDaemon_HandlerRoutesGroups(t *testing.T) {
+	daemon := createTestDaemon(t, "mainnet", "testnet")
+	mainnet, testnet := daemon.managers[0], daemon.managers[1]
+
+	server := httptest.NewServer(daemon.Handler())
+	defer server.Close()
+
+	// a vote for the mainnet group only reaches the mainnet manager
+	client := api.NewClient(*mainnet.cfg.Validator.Identities.ActiveKeyPair)
+	_, err := client.Post(context.Background(), server.URL+"/groups/mainnet"+api.PathVote, mainnet.cfg.Validator.Name, api.VoteRequest{Candidate: "peer1"})
+	require.NoError(t, err)
+	assert.Equal(t, "peer1", mainnet.votedFor)
+	assert.Empty(t, testnet.votedFor)
+
+	// groups authenticate with their own active identity
+	_, err = client.Post(context.Background(), server.URL+"/groups/testnet"+api.PathVote, testnet.cfg.Validator.Name, api.VoteRequest{Candidate: "peer1"})
+	assert.ErrorContains(t, err, "401")
+	assert.Empty(t, testnet.votedFor)
+
+	// ungrouped paths are not served
+	_, err = client.Post(context.Background(), server.URL+api.PathVote, mainnet.cfg.Validator.Name, api.VoteRequest{Candidate: "peer1"})
+	assert.ErrorContains(t, err, "404")
+
+	response, err := http.Get(server.URL + "/health")
+	require.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+}
+
+func TestDaemon_PeerAPIURL(t *testing.T) {
+	daemon := createTestDaemon(t, "mainnet")
+	manager := daemon.managers[0]
+
+	url := manager.peerAPIURL(config.Peer{Name: "peer1", IP: "192.168.1.101"}, api.PathVote)
+	assert.Equal(t, "http://192.168.1.101:9091/groups/mainnet"+api.PathVote, url)
+}
+
+func TestDaemon_Stop(t *testing.T) {
+	daemon := createTestDaemon(t, "mainnet", "testnet")
+
+	require.NoError(t, daemon.Stop(context.Background()))
+	for _, manager := range daemon.managers {
+		assert.True(t, manager.stopping.Load())
+	}
+}
+
+func TestDaemon_Run_MonitorsBalances(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Prometheus.Port = 9490
+	daemon := &Daemon{cfg: cfg, logger: log.WithPrefix("[daemon]")}
+
+	for _, group := range []string{"mainnet", "testnet"} {
+		groupCfg := createTestConfig()
+		groupCfg.Group = group
+		groupCfg.Validator.Name = group + "-validator"
+		groupCfg.Validator.BalanceMonitor.IdentityWarnSOL = 5
+		groupCfg.Validator.BalanceMonitor.IdentityCriticalSOL = 1
+
+		server := mockRPCServer(t, map[string]func() any{
+			"getBalance": func() any {
+				return map[string]any{"context": map[string]any{"slot": 100}, "value": 10 * solanago.LAMPORTS_PER_SOL}
+			},
+			"getMinimumBalanceForRentExemption": func() any { return 27_074_400 },
+			"getClusterNodes":                   func() any { return []any{} },
+			"getSlot":                           func() any { return 100 },
+			"getVoteAccounts":                   func() any { return map[string]any{"current": []any{}, "delinquent": []any{}} },
+			"getIdentity": func() any {
+				return map[string]any{"identity": groupCfg.Validator.Identities.PassiveKeyPair.PublicKey().String()}
+			},
+		})
+		rpcClient := rpc.NewClient("test", server.URL)
+		daemon.managers = append(daemon.managers, NewManager(NewManagerOptions{
+			Cfg:             groupCfg,
+			GetPublicIPFunc: mockPublicIPFunc,
+			LocalRPC:        rpcClient,
+			ClusterRPC:      rpcClient,
+		}))
+	}
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- daemon.Run() }()
+
+	// every group samples its balances alongside its HA monitor loop
+	for _, manager := range daemon.managers {
+		assert.Eventually(t, func() bool {
+			return len(manager.accountBalances()) > 0
+		}, 5*time.Second, 10*time.Millisecond, "group %s did not sample balances", manager.cfg.Group)
+	}
+
+	require.NoError(t, daemon.Stop(context.Background()))
+	require.NoError(t, <-runErr)
+}
@@ -0,0 +1,143 @@
+package ha
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	solanagorpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/sol-strategies/solana-validator-ha/internal/rpc"
+)
+
+// doubleVoteGuardPollInterval is how often the active identity's vote account is sampled by the double
+// vote guard - about one slot
+const doubleVoteGuardPollInterval = 400 * time.Millisecond
+
+// errActiveIdentityVoting is returned when the double vote guard sees the active identity still voting
+var errActiveIdentityVoting = errors.New("active identity is still voting")
+
+// checkActiveIdentityNotVoting confirms on-chain that nobody is voting with the active identity before we
+// start voting with it - gossip showing the cluster leaderless does not rule out the previous active still
+// voting from somewhere, and two validators voting with one identity is a double vote. The active identity's
+// vote account is sampled on each cluster RPC endpoint until the cluster has advanced
+// failover.double_vote_guard_slots slots in any endpoint's view, failing with errActiveIdentityVoting if its
+// LastVote advanced at any point in any endpoint's view, or a refusal if that could not be confirmed within
+// failover.double_vote_guard_timeout_duration
+func (m *Manager) checkActiveIdentityNotVoting() (err error) {
+	slots := uint64(m.cfg.Failover.DoubleVoteGuardSlots)
+	if slots == 0 {
+		return nil
+	}
+
+	defer func() {
+		m.activeIdentityVoting = errors.Is(err, errActiveIdentityVoting)
+	}()
+
+	ctx, cancel := m.clock.WithTimeout(m.ctx, m.cfg.Failover.DoubleVoteGuardTimeoutDuration)
+	defer cancel()
+
+	m.logger.Info("confirming the active identity is not voting", "double_vote_guard_slots", slots)
+
+	// each endpoint's first sample is the baseline the active identity's last vote must not advance past in
+	// that endpoint's view - endpoints lag each other, so samples are never compared across them
+	baselines := map[string]activeVoteSample{}
+	for {
+		samples, sampleErr := m.sampleActiveVoteAccounts(ctx)
+		if sampleErr != nil {
+			m.logger.Warn("failed to sample the active identity's vote account", "error", sampleErr)
+		}
+
+		var (
+			confirmed                        bool
+			slotsObserved, confirmedLastVote uint64
+		)
+		for url, sample := range samples {
+			baseline, ok := baselines[url]
+			switch {
+			case !ok:
+				baselines[url] = sample
+				m.logger.Debug("double vote guard baseline", "rpc_url", url, "slot", sample.slot, "last_vote", sample.lastVote)
+			case sample.lastVote > baseline.lastVote:
+				m.logger.Error("‼️ the active identity is still voting - aborting takeover to avoid a double vote",
+					"rpc_url", url,
+					"baseline_last_vote", baseline.lastVote,
+					"last_vote", sample.lastVote,
+					"slot", sample.slot,
+				)
+				return refuse("%w: last vote advanced from slot %d to %d", errActiveIdentityVoting, baseline.lastVote, sample.lastVote)
+			case sample.slot >= baseline.slot+slots:
+				confirmed, slotsObserved, confirmedLastVote = true, sample.slot-baseline.slot, baseline.lastVote
+			}
+		}
+		// only once every endpoint's sample was checked for an advancing vote
+		if confirmed {
+			m.logger.Info("the active identity is not voting", "last_vote", confirmedLastVote, "slots_observed", slotsObserved)
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if m.ctx.Err() != nil {
+				return m.ctx.Err()
+			}
+			return refuse("unable to confirm the active identity is not voting within %s", m.cfg.Failover.DoubleVoteGuardTimeoutDuration)
+		case <-m.clock.After(doubleVoteGuardPollInterval):
+		}
+	}
+}
+
+// activeVoteSample is one endpoint's view of the current slot and the active identity's LastVote
+type activeVoteSample struct {
+	slot, lastVote uint64
+}
+
+// sampleActiveVoteAccounts returns each cluster RPC endpoint's current slot and the active identity's LastVote,
+// keyed by URL - LastVote is zero when it has no vote account, which is as good as not voting. Slots and vote
+// accounts are paired by endpoint, and an endpoint missing either is left out. It fails only if every
+// endpoint did
+func (m *Manager) sampleActiveVoteAccounts(ctx context.Context) (samples map[string]activeVoteSample, err error) {
+	var (
+		slotResults        []rpc.EndpointResult[uint64]
+		voteAccountResults []rpc.EndpointResult[*solanagorpc.GetVoteAccountsResult]
+	)
+	if endpointsRPC, ok := m.clusterRPC.(EndpointsVoteRPC); ok {
+		slotResults = endpointsRPC.GetSlotFromEach(ctx)
+		voteAccountResults = endpointsRPC.GetVoteAccountsFromEach(ctx)
+	} else {
+		slot, slotErr := m.clusterRPC.GetSlot(ctx)
+		slotResults = []rpc.EndpointResult[uint64]{{Result: slot, Err: slotErr}}
+		voteAccounts, voteAccountsErr := m.clusterRPC.GetVoteAccounts(ctx)
+		voteAccountResults = []rpc.EndpointResult[*solanagorpc.GetVoteAccountsResult]{{Result: voteAccounts, Err: voteAccountsErr}}
+	}
+
+	var errs []error
+	slotsByURL := map[string]uint64{}
+	for _, result := range slotResults {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("failed to get slot: %w", result.Err))
+			continue
+		}
+		slotsByURL[result.URL] = result.Result
+	}
+
+	activePubkey := m.cfg.Validator.Identities.ActivePublicKey()
+	samples = map[string]activeVoteSample{}
+	for _, result := range voteAccountResults {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("failed to get vote accounts: %w", result.Err))
+			continue
+		}
+		slot, ok := slotsByURL[result.URL]
+		if !ok {
+			continue
+		}
+		lastVote, _ := activeLastVote(result.Result, activePubkey)
+		samples[result.URL] = activeVoteSample{slot: slot, lastVote: lastVote}
+	}
+
+	if len(samples) == 0 {
+		return nil, errors.Join(errs...)
+	}
+	return samples, nil
+}
@@ -0,0 +1,136 @@
+package ha
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/journal"
+	"github.com/sol-strategies/solana-validator-ha/internal/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createDoubleVoteGuardTestManager returns an initialized manager with a cluster RPC endpoint per lastVotes,
+// each advancing 10 slots per getSlot and reporting the active identity's last vote from its lastVote, or no
+// vote account if it is nil
+func createDoubleVoteGuardTestManager(t *testing.T, lastVotes ...func() uint64) *Manager {
+	t.Helper()
+
+	cfg := createTestConfig()
+	cfg.Failover.DoubleVoteGuardSlots = 8
+	cfg.Failover.DoubleVoteGuardTimeoutDuration = 5 * time.Second
+	activePubkey := cfg.Validator.Identities.ActiveKeyPair.PublicKey().String()
+
+	var urls []string
+	for _, lastVote := range lastVotes {
+		var slot atomic.Uint64
+		server := mockRPCServer(t, map[string]func() any{
+			"getSlot": func() any { return slot.Add(10) },
+			"getVoteAccounts": func() any {
+				current := []map[string]any{}
+				if lastVote != nil {
+					current = append(current, map[string]any{
+						"votePubkey":       createTestPrivateKey("vote").PublicKey().String(),
+						"nodePubkey":       activePubkey,
+						"activatedStake":   1,
+						"epochVoteAccount": true,
+						"commission":       0,
+						"lastVote":         lastVote(),
+						"epochCredits":     [][]uint64{},
+						"rootSlot":         0,
+					})
+				}
+				return map[string]any{"current": current, "delinquent": []map[string]any{}}
+			},
+		})
+		urls = append(urls, server.URL)
+	}
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+		ClusterRPC:      rpc.NewClient("test", urls...),
+	})
+	require.NoError(t, manager.initialize())
+
+	return manager
+}
+
+func TestManager_CheckActiveIdentityNotVoting_NotVoting(t *testing.T) {
+	manager := createDoubleVoteGuardTestManager(t, func() uint64 { return 100 })
+	manager.activeIdentityVoting = true
+
+	assert.NoError(t, manager.checkActiveIdentityNotVoting())
+	assert.False(t, manager.activeIdentityVoting)
+}
+
+func TestManager_CheckActiveIdentityNotVoting_NoVoteAccount(t *testing.T) {
+	manager := createDoubleVoteGuardTestManager(t, nil)
+
+	assert.NoError(t, manager.checkActiveIdentityNotVoting())
+}
+
+func TestManager_CheckActiveIdentityNotVoting_Voting(t *testing.T) {
+	var lastVote atomic.Uint64
+	lastVote.Store(100)
+	manager := createDoubleVoteGuardTestManager(t, func() uint64 { return lastVote.Add(1) })
+
+	err := manager.checkActiveIdentityNotVoting()
+	assert.True(t, errors.Is(err, errActiveIdentityVoting))
+	assert.ErrorContains(t, err, "last vote advanced from slot 101 to 102")
+	outcome, _ := outcomeFromError(err)
+	assert.Equal(t, journal.OutcomeRefused, outcome)
+	assert.True(t, manager.activeIdentityVoting)
+
+	// exported with the next sample
+	manager.refreshMetrics()
+	assert.True(t, manager.cache.GetState().DoubleVoteGuardTripped)
+}
+
+func TestManager_CheckActiveIdentityNotVoting_EndpointsApart(t *testing.T) {
+	// endpoints agreeing the active identity is not voting but lagging each other's last vote by 50 slots
+	// must not be taken for it voting
+	manager := createDoubleVoteGuardTestManager(t,
+		func() uint64 { return 150 },
+		func() uint64 { return 100 },
+	)
+	assert.NoError(t, manager.checkActiveIdentityNotVoting())
+
+	// and an endpoint seeing the active identity vote must not be hidden by another one that is stuck ahead
+	var lastVote atomic.Uint64
+	lastVote.Store(100)
+	manager = createDoubleVoteGuardTestManager(t,
+		func() uint64 { return lastVote.Add(1) },
+		func() uint64 { return 150 },
+	)
+	err := manager.checkActiveIdentityNotVoting()
+	assert.True(t, errors.Is(err, errActiveIdentityVoting))
+	assert.ErrorContains(t, err, "last vote advanced from slot 101 to 102")
+}
+
+func TestManager_CheckActiveIdentityNotVoting_Unconfirmed(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Cluster.RPCURLs = []string{"http://127.0.0.1:1"}
+	cfg.Failover.DoubleVoteGuardSlots = 8
+	cfg.Failover.DoubleVoteGuardTimeoutDuration = 100 * time.Millisecond
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+
+	err := manager.checkActiveIdentityNotVoting()
+	assert.ErrorContains(t, err, "unable to confirm the active identity is not voting within 100ms")
+	assert.False(t, errors.Is(err, errActiveIdentityVoting))
+	assert.False(t, manager.activeIdentityVoting)
+}
+
+func TestManager_CheckActiveIdentityNotVoting_Disabled(t *testing.T) {
+	manager := createDoubleVoteGuardTestManager(t, func() uint64 { panic("must not be sampled") })
+	manager.cfg.Failover.DoubleVoteGuardSlots = 0
+
+	assert.NoError(t, manager.checkActiveIdentityNotVoting())
+}
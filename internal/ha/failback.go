@@ -0,0 +1,105 @@
+package ha
+
+import (
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/journal"
+)
+
+// failbackToPreferredPeer hands the active role to the highest priority peer with a planned switchover
+// when failover.failback is priority, we are active, and that peer has been passive in gossip for
+// failover.failback_soak_duration. The peer refuses the promotion if it is unhealthy, in which case the
+// switchover is rolled back and the soak period starts over
+func (m *Manager) failbackToPreferredPeer() (failedBack bool) {
+	if m.cfg.Failover.Failback != config.FailoverFailbackPriority {
+		return false
+	}
+
+	rankedPeers := m.cfg.Failover.Peers.GetRankedPeers()
+	if len(rankedPeers) == 0 || !m.isSelfActive() {
+		m.resetFailbackSoak()
+		return false
+	}
+
+	// nothing to do if we are the preferred peer
+	preferredPeer := rankedPeers[0]
+	if preferredPeer.Name == m.peerSelf.Name {
+		m.resetFailbackSoak()
+		return false
+	}
+
+	if !m.isPeerPassiveInGossip(preferredPeer) {
+		if m.failbackPeerName != "" {
+			m.logger.Info("preferred peer no longer passive in gossip - failback soak reset", "name", preferredPeer.Name, "ip", preferredPeer.IP)
+		}
+		m.resetFailbackSoak()
+		return false
+	}
+
+	if m.failbackPeerName != preferredPeer.Name {
+		m.failbackPeerName = preferredPeer.Name
+		m.failbackPeerSeenSince = m.clock.Now()
+		m.logger.Info("preferred peer is passive in gossip - failing back after soak",
+			"name", preferredPeer.Name,
+			"ip", preferredPeer.IP,
+			"failback_soak_duration", m.cfg.Failover.FailbackSoakDuration,
+		)
+		return false
+	}
+
+	soaked := m.clock.Since(m.failbackPeerSeenSince)
+	if soaked < m.cfg.Failover.FailbackSoakDuration {
+		m.logger.Debug("waiting for preferred peer to soak before failback",
+			"name", preferredPeer.Name,
+			"soaked", soaked.Round(time.Second),
+			"failback_soak_duration", m.cfg.Failover.FailbackSoakDuration,
+		)
+		return false
+	}
+
+	m.beginEvent(journal.EventTypeFailback)
+	m.setEventDetail("to %s", preferredPeer.Name)
+
+	if m.isInMaintenance() {
+		m.logger.Warn("we are in maintenance mode - skipping failback", "name", preferredPeer.Name)
+		m.endRecurringEvent(journal.OutcomeRefused, "we are in maintenance mode")
+		return false
+	}
+
+	// flap damping
+	if err := m.checkTransitionAllowed(); err != nil {
+		m.logger.Warn("refusing to fail back to preferred peer", "name", preferredPeer.Name, "reason", err)
+		m.endRecurringEvent(journal.OutcomeRefused, err.Error())
+		return false
+	}
+
+	m.logger.Warn("failing back to preferred peer", "name", preferredPeer.Name, "ip", preferredPeer.IP, "priority", preferredPeer.Priority)
+
+	// start the soak over so that a refused failback is not retried every sample
+	m.failbackPeerSeenSince = m.clock.Now()
+	err := m.switchover(preferredPeer.Name)
+	if err != nil {
+		m.logger.Error("failback to preferred peer failed", "name", preferredPeer.Name, "error", err)
+	}
+	m.endRecurringEvent(outcomeFromError(err))
+
+	return true
+}
+
+// isPeerPassiveInGossip returns true if peer appears in gossip with a passive identity
+func (m *Manager) isPeerPassiveInGossip(peer config.Peer) bool {
+	for _, passivePeer := range m.gossipState.Snapshot().GetPassivePeers(m.peerSelf.IP) {
+		if passivePeer.IP == peer.IP {
+			return true
+		}
+	}
+	return false
+}
+
+// resetFailbackSoak forgets the preferred peer being soaked
+func (m *Manager) resetFailbackSoak() {
+	m.failbackPeerName = ""
+	m.failbackPeerSeenSince = time.Time{}
+	m.clearRecurringEvent(journal.EventTypeFailback)
+}
@@ -0,0 +1,63 @@
+package ha
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_FailbackToPreferredPeer_Disabled(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Failover.Failback = config.FailoverFailbackNone
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+
+	assert.False(t, manager.failbackToPreferredPeer())
+	assert.Empty(t, manager.cache.GetState().FailoverStatus)
+}
+
+func TestManager_FailbackToPreferredPeer_NotActive(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Failover.Failback = config.FailoverFailbackPriority
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+
+	// local rpc is unreachable so we are not active - any soak in progress is forgotten
+	manager.failbackPeerName = "peer1"
+	manager.failbackPeerSeenSince = time.Now().Add(-time.Hour)
+	assert.False(t, manager.failbackToPreferredPeer())
+	assert.Empty(t, manager.failbackPeerName)
+	assert.True(t, manager.failbackPeerSeenSince.IsZero())
+	assert.Empty(t, manager.cache.GetState().FailoverStatus)
+}
+
+func TestManager_Initialize_SelfPriority(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Failover.Priority = 2
+	cfg.Failover.Peers["peer2"] = config.Peer{IP: "192.168.1.102", Name: "peer2", Priority: 1}
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+
+	// priority wins over IP order and peers without a priority rank last
+	assert.Equal(t, 2, manager.peerSelf.Priority)
+	assert.Equal(t, map[string]int{
+		"192.168.1.102": 1,
+		"192.168.1.100": 2,
+		"192.168.1.101": 3,
+	}, cfg.Failover.Peers.GetRankedIPs())
+}
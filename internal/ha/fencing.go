@@ -0,0 +1,209 @@
+package ha
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/command"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/rpc"
+)
+
+// fencePreviousActive makes sure the peer last seen active has stopped signing with the active identity
+// before we take over - it vanishing from gossip or being delinquent does not prove that. The peer is
+// fenced once its validator RPC (when it has an rpc_url) reports another identity or any of
+// failover.fencing.agents succeeds, retrying every poll interval until failover.fencing.timeout_duration
+// when failover.fencing.on_timeout decides whether to take over regardless. With no active peer seen since
+// starting any other validator peer may still be signing, so every one of them is fenced instead
+func (m *Manager) fencePreviousActive() error {
+	fencing := m.cfg.Failover.Fencing
+	if !fencing.Enabled(m.cfg.Failover.Peers) {
+		return nil
+	}
+
+	previousActive, ok := m.gossipState.Snapshot().GetLastActivePeer()
+	if !ok {
+		m.logger.Warn("no active peer seen since starting - fencing every other validator peer")
+		return m.fenceOtherPeers()
+	}
+	if previousActive.IPEquals(m.peerSelf.IP) {
+		m.logger.Debug("we were the last active peer - nothing to fence")
+		return nil
+	}
+
+	peer, ok := m.cfg.Failover.Peers[previousActive.Name]
+	if !ok {
+		return fmt.Errorf("previous active peer %s is not in failover.peers", previousActive.Name)
+	}
+
+	return m.fence(peer, "previous active peer")
+}
+
+// fenceOtherPeers fences every validator peer but ourselves in rank order, any of them failing to be fenced
+// failing the lot
+func (m *Manager) fenceOtherPeers() error {
+	for _, peer := range m.cfg.Failover.Peers.GetRankedPeers() {
+		if peer.Name == m.peerSelf.Name {
+			continue
+		}
+		if err := m.fence(peer, "possibly active peer"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fence makes sure peer - described by role in logs and errors - has stopped signing with the active
+// identity before we take over, retrying every poll interval until failover.fencing.timeout_duration when
+// failover.fencing.on_timeout decides whether to take over regardless
+func (m *Manager) fence(peer config.Peer, role string) error {
+	fencing := m.cfg.Failover.Fencing
+	m.logger.Warn(fmt.Sprintf("fencing %s before taking over", role), "name", peer.Name, "ip", peer.IP,
+		"timeout", fencing.TimeoutDuration,
+	)
+	m.setEventDetail("fencing %s (%s)", peer.Name, peer.IP)
+
+	ctx, cancel := m.clock.WithTimeout(m.ctx, fencing.TimeoutDuration)
+	defer cancel()
+
+	for {
+		if m.fencePeer(ctx, peer) {
+			m.logger.Info(role+" fenced", "name", peer.Name, "ip", peer.IP)
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if m.ctx.Err() != nil {
+				return m.ctx.Err()
+			}
+			if fencing.OnTimeout == config.FencingOnTimeoutProceed {
+				m.logger.Error(fmt.Sprintf("‼️ failed to fence %s in time - taking over regardless", role),
+					"name", peer.Name, "ip", peer.IP, "on_timeout", fencing.OnTimeout,
+				)
+				return nil
+			}
+			return refuse("failed to fence %s %s within %s", role, peer.Name, fencing.TimeoutDuration)
+		case <-m.clock.After(m.cfg.Failover.PollIntervalDuration):
+		}
+	}
+}
+
+// fencePeer returns true if peer's validator RPC reports another identity than the active one or any of
+// the fence agents succeeds, tried in the order they are declared
+func (m *Manager) fencePeer(ctx context.Context, peer config.Peer) (fenced bool) {
+	activePubkey := m.cfg.Validator.Identities.ActivePublicKey().String()
+
+	if peer.RPCURL != "" {
+		started := m.clock.Now()
+		identity, err := rpc.NewClient(m.logPrefix, peer.RPCURL).GetIdentity(ctx)
+		if err == nil && identity.Identity.String() == activePubkey {
+			err = fmt.Errorf("still reports the active identity %s", activePubkey)
+		}
+		m.recordCommand("fence-rpc getIdentity", peer.RPCURL, nil, m.clock.Since(started), err)
+		if err == nil {
+			m.logger.Info("peer reports another identity", "name", peer.Name, "identity", identity.Identity.String())
+			return true
+		}
+		m.logger.Warn("peer rpc does not show it fenced", "name", peer.Name, "rpc_url", peer.RPCURL, "error", err)
+	}
+
+	data := config.FenceTemplateData{
+		PeerName:             peer.Name,
+		PeerIP:               peer.IP,
+		ActiveIdentityPubkey: activePubkey,
+		SelfName:             m.peerSelf.Name,
+	}
+	for _, agent := range m.cfg.Failover.Fencing.Agents {
+		if ctx.Err() != nil {
+			return false
+		}
+
+		rendered, err := agent.Render(data)
+		if err != nil {
+			m.logger.Error("failed to render fence agent", "agent", agent.Name, "error", err)
+			continue
+		}
+
+		if err := m.runFenceAgent(ctx, rendered, peer); err != nil {
+			m.logger.Warn("fence agent failed", "agent", agent.Name, "type", agent.Type, "name", peer.Name, "error", err)
+			continue
+		}
+		m.logger.Info("fence agent succeeded", "agent", agent.Name, "type", agent.Type, "name", peer.Name)
+		return true
+	}
+
+	return false
+}
+
+// runFenceAgent runs a rendered fence agent against peer and journals its result
+func (m *Manager) runFenceAgent(ctx context.Context, agent config.FenceAgent, peer config.Peer) (err error) {
+	name := fmt.Sprintf("fence-agent %s", agent.Name)
+	started := m.clock.Now()
+
+	switch agent.Type {
+	case config.FenceAgentTypeHTTP:
+		err = m.runFenceAgentHTTP(ctx, agent)
+		m.recordCommand(name, agent.Method+" "+agent.URL, nil, m.clock.Since(started), err)
+		return err
+	case config.FenceAgentTypeSSH:
+		args := agent.SSHArgs(peer.IP)
+		err = m.commandRunner.Run(command.RunOptions{
+			Ctx:          ctx,
+			Name:         name,
+			Command:      "ssh",
+			Args:         args,
+			Env:          agent.Env,
+			DryRun:       m.cfg.Failover.DryRun,
+			LoggerPrefix: m.logPrefix,
+			StreamOutput: true,
+		})
+		m.recordCommand(name, "ssh", args, m.clock.Since(started), err)
+		return err
+	default:
+		err = m.commandRunner.Run(command.RunOptions{
+			Ctx:          ctx,
+			Name:         name,
+			Command:      agent.Command,
+			Args:         agent.Args,
+			Env:          agent.Env,
+			DryRun:       m.cfg.Failover.DryRun,
+			LoggerPrefix: m.logPrefix,
+			StreamOutput: true,
+		})
+		m.recordCommand(name, agent.Command, agent.Args, m.clock.Since(started), err)
+		return err
+	}
+}
+
+// runFenceAgentHTTP makes an http agent's request, which succeeds on a 2xx response
+func (m *Manager) runFenceAgentHTTP(ctx context.Context, agent config.FenceAgent) error {
+	m.logger.Info("fence agent request", "agent", agent.Name, "method", agent.Method, "url", agent.URL, "dry_run", m.cfg.Failover.DryRun)
+	if m.cfg.Failover.DryRun {
+		return nil
+	}
+
+	request, err := http.NewRequestWithContext(ctx, agent.Method, agent.URL, strings.NewReader(agent.Body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, value := range agent.Headers {
+		request.Header.Set(key, value)
+	}
+
+	client := &http.Client{Transport: m.peerTransport}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("unexpected status %d: %s", response.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package ha
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/command"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/gossip"
+	"github.com/sol-strategies/solana-validator-ha/internal/journal"
+	"github.com/sol-strategies/solana-validator-ha/internal/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fenceTestRunner records the commands fence agents run and fails them with err
+type fenceTestRunner struct {
+	mu   sync.Mutex
+	runs []command.RunOptions
+	err  error
+}
+
+func (r *fenceTestRunner) Run(opts command.RunOptions) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs = append(r.runs, opts)
+	return r.err
+}
+
+// createFencingTestManager returns an initialized manager that has seen peer1 active in gossip, whose
+// validator RPC at peer1RPCURL (if set) reports the identity returned by peer1Identity
+func createFencingTestManager(t *testing.T, runner *fenceTestRunner, peer1Identity func(activePubkey string) string) *Manager {
+	t.Helper()
+
+	cfg := createTestConfig()
+	cfg.Failover.DryRun = false
+	cfg.Failover.StateDir = t.TempDir()
+	cfg.Failover.PollIntervalDuration = 10 * time.Millisecond
+	cfg.Failover.Fencing.TimeoutDuration = 100 * time.Millisecond
+	cfg.Failover.Fencing.OnTimeout = config.FencingOnTimeoutRefuse
+	activePubkey := cfg.Validator.Identities.ActiveKeyPair.PublicKey().String()
+
+	clusterServer := mockRPCServer(t, map[string]func() any{
+		"getClusterNodes": func() any {
+			return []map[string]any{{"pubkey": activePubkey, "gossip": "192.168.1.101:8001"}}
+		},
+		"getSlot": func() any { return 100 },
+		"getVoteAccounts": func() any {
+			return map[string]any{
+				"current": []map[string]any{{
+					"votePubkey":       createTestPrivateKey("vote").PublicKey().String(),
+					"nodePubkey":       activePubkey,
+					"activatedStake":   1,
+					"epochVoteAccount": true,
+					"commission":       0,
+					"lastVote":         100,
+					"epochCredits":     [][]uint64{},
+					"rootSlot":         0,
+				}},
+				"delinquent": []map[string]any{},
+			}
+		},
+	})
+
+	if peer1Identity != nil {
+		peer1Server := mockRPCServer(t, map[string]func() any{
+			"getIdentity": func() any { return map[string]any{"identity": peer1Identity(activePubkey)} },
+		})
+		peer1 := cfg.Failover.Peers["peer1"]
+		peer1.RPCURL = peer1Server.URL
+		cfg.Failover.Peers["peer1"] = peer1
+	}
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+		ClusterRPC:      rpc.NewClient("test", clusterServer.URL),
+		CommandRunner:   runner,
+		GossipDialFunc: func(network, address string) (net.Conn, error) {
+			conn, _ := net.Pipe()
+			return conn, nil
+		},
+	})
+	require.NoError(t, manager.initialize())
+
+	// see peer1 active
+	manager.gossipState.Refresh()
+	lastActivePeer, ok := manager.gossipState.Snapshot().GetLastActivePeer()
+	require.True(t, ok)
+	require.Equal(t, "peer1", lastActivePeer.Name)
+
+	manager.beginEvent(journal.EventTypeFailover)
+	return manager
+}
+
+func TestManager_FencePreviousActive_Disabled(t *testing.T) {
+	runner := &fenceTestRunner{}
+	manager := createFencingTestManager(t, runner, nil)
+
+	assert.NoError(t, manager.fencePreviousActive())
+	assert.Empty(t, runner.runs)
+}
+
+func TestManager_FencePreviousActive_RPCReportsPassive(t *testing.T) {
+	runner := &fenceTestRunner{err: errors.New("should not run")}
+	manager := createFencingTestManager(t, runner, func(string) string {
+		return createTestPrivateKey("passive").PublicKey().String()
+	})
+	manager.cfg.Failover.Fencing.Agents = []config.FenceAgent{{Name: "power-off", Type: config.FenceAgentTypeCommand, Command: "ipmitool"}}
+
+	assert.NoError(t, manager.fencePreviousActive())
+	assert.Empty(t, runner.runs)
+	require.Len(t, manager.event.Commands, 1)
+	assert.Equal(t, "fence-rpc getIdentity", manager.event.Commands[0].Name)
+}
+
+func TestManager_FencePreviousActive_CommandAgent(t *testing.T) {
+	runner := &fenceTestRunner{}
+	manager := createFencingTestManager(t, runner, func(activePubkey string) string { return activePubkey })
+	manager.cfg.Failover.Fencing.Agents = []config.FenceAgent{{
+		Name:    "power-off",
+		Type:    config.FenceAgentTypeCommand,
+		Command: "ipmitool",
+		Args:    []string{"-H", "bmc-{{ .PeerName }}", "power", "off"},
+	}}
+
+	assert.NoError(t, manager.fencePreviousActive())
+	require.Len(t, runner.runs, 1)
+	assert.Equal(t, "ipmitool", runner.runs[0].Command)
+	assert.Equal(t, []string{"-H", "bmc-peer1", "power", "off"}, runner.runs[0].Args)
+
+	// the rpc check still showing the active identity and the agent are both journaled
+	require.Len(t, manager.event.Commands, 2)
+	assert.NotEmpty(t, manager.event.Commands[0].Error)
+	assert.Equal(t, "fence-agent power-off", manager.event.Commands[1].Name)
+}
+
+func TestManager_FencePreviousActive_SSHAgent(t *testing.T) {
+	runner := &fenceTestRunner{}
+	manager := createFencingTestManager(t, runner, nil)
+	manager.cfg.Failover.Fencing.Agents = []config.FenceAgent{{
+		Name:    "stop-validator",
+		Type:    config.FenceAgentTypeSSH,
+		User:    "solana",
+		Command: "systemctl",
+		Args:    []string{"stop", "solana-validator"},
+	}}
+
+	assert.NoError(t, manager.fencePreviousActive())
+	require.Len(t, runner.runs, 1)
+	assert.Equal(t, "ssh", runner.runs[0].Command)
+	assert.Equal(t, []string{"-o", "BatchMode=yes", "solana@192.168.1.101", "systemctl", "stop", "solana-validator"}, runner.runs[0].Args)
+}
+
+func TestManager_FencePreviousActive_HTTPAgent(t *testing.T) {
+	var gotPath, gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.Path, r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	runner := &fenceTestRunner{}
+	manager := createFencingTestManager(t, runner, nil)
+	manager.cfg.Failover.Fencing.Agents = []config.FenceAgent{{
+		Name:    "pdu",
+		Type:    config.FenceAgentTypeHTTP,
+		URL:     server.URL + "/outlets/{{ .PeerName }}/off",
+		Method:  http.MethodPost,
+		Headers: map[string]string{"Authorization": "Bearer secret"},
+	}}
+
+	assert.NoError(t, manager.fencePreviousActive())
+	assert.Equal(t, "/outlets/peer1/off", gotPath)
+	assert.Equal(t, "Bearer secret", gotAuth)
+}
+
+func TestManager_FencePreviousActive_Timeout(t *testing.T) {
+	runner := &fenceTestRunner{err: errors.New("bmc unreachable")}
+	manager := createFencingTestManager(t, runner, nil)
+	manager.cfg.Failover.Fencing.Agents = []config.FenceAgent{{Name: "power-off", Type: config.FenceAgentTypeCommand, Command: "ipmitool"}}
+
+	// agents are retried every poll interval until the timeout
+	err := manager.fencePreviousActive()
+	assert.ErrorContains(t, err, "failed to fence previous active peer peer1")
+	outcome, _ := outcomeFromError(err)
+	assert.Equal(t, journal.OutcomeRefused, outcome)
+	assert.Greater(t, len(runner.runs), 1)
+
+	// unless the timeout policy allows taking over regardless
+	manager.cfg.Failover.Fencing.OnTimeout = config.FencingOnTimeoutProceed
+	assert.NoError(t, manager.fencePreviousActive())
+}
+
+func TestManager_FencePreviousActive_NoActiveSeen(t *testing.T) {
+	runner := &fenceTestRunner{}
+	manager := createFencingTestManager(t, runner, nil)
+	manager.cfg.Failover.Fencing.Agents = []config.FenceAgent{{
+		Name:    "power-off",
+		Type:    config.FenceAgentTypeCommand,
+		Command: "ipmitool",
+		Args:    []string{"-H", "bmc-{{ .PeerName }}", "power", "off"},
+	}}
+	// a state that has never seen an active peer
+	manager.gossipState = gossip.NewState(gossip.Options{
+		ClusterRPC:  manager.clusterRPC,
+		ConfigPeers: manager.cfg.Failover.Peers.Validators(),
+	})
+
+	// any other validator peer may still be signing, so all of them are fenced
+	assert.NoError(t, manager.fencePreviousActive())
+	require.Len(t, runner.runs, 2)
+	assert.Equal(t, []string{"-H", "bmc-peer1", "power", "off"}, runner.runs[0].Args)
+	assert.Equal(t, []string{"-H", "bmc-peer2", "power", "off"}, runner.runs[1].Args)
+
+	// failing to fence any of them is a fence failure
+	runner.err = errors.New("bmc unreachable")
+	err := manager.fencePreviousActive()
+	assert.ErrorContains(t, err, "failed to fence possibly active peer peer1")
+	outcome, _ := outcomeFromError(err)
+	assert.Equal(t, journal.OutcomeRefused, outcome)
+
+	// unless the timeout policy allows taking over regardless
+	manager.cfg.Failover.Fencing.OnTimeout = config.FencingOnTimeoutProceed
+	assert.NoError(t, manager.fencePreviousActive())
+}
+
+func TestManager_FencePreviousActive_WeWereActive(t *testing.T) {
+	runner := &fenceTestRunner{}
+	manager := createFencingTestManager(t, runner, nil)
+	manager.cfg.Failover.Fencing.Agents = []config.FenceAgent{{Name: "power-off", Type: config.FenceAgentTypeCommand, Command: "ipmitool"}}
+	manager.peerSelf.IP = "192.168.1.101"
+
+	assert.NoError(t, manager.fencePreviousActive())
+	assert.Empty(t, runner.runs)
+}
@@ -0,0 +1,156 @@
+package ha
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/command"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/journal"
+)
+
+// refusalError is returned when a precondition stops a transition before anything was changed
+type refusalError struct {
+	error
+}
+
+// Unwrap returns the refusal reason so that errors.Is sees through the refusal
+func (e refusalError) Unwrap() error {
+	return e.error
+}
+
+// refuse returns a refusalError
+func refuse(format string, args ...any) error {
+	return refusalError{fmt.Errorf(format, args...)}
+}
+
+// outcomeFromError returns the journal outcome and reason for the error a transition returned
+func outcomeFromError(err error) (outcome, reason string) {
+	if err == nil {
+		return journal.OutcomeSuccess, ""
+	}
+
+	var refusal refusalError
+	if errors.As(err, &refusal) {
+		return journal.OutcomeRefused, err.Error()
+	}
+
+	return journal.OutcomeFailure, err.Error()
+}
+
+// beginEvent starts journaling a failover decision of eventType with a snapshot of gossip - callers must
+// hold transitionMu and finish it with endEvent or endRecurringEvent
+func (m *Manager) beginEvent(eventType string) {
+	event := &journal.Event{
+		Time:      m.clock.Now().UTC(),
+		Type:      eventType,
+		Validator: m.cfg.Validator.Name,
+	}
+
+	if m.gossipState != nil {
+		gossipSnapshot := m.gossipState.Snapshot()
+		event.LeaderlessSamplesCount = gossipSnapshot.LeaderlessSamplesCount
+		for _, peerState := range gossipSnapshot.PeerStates {
+			event.Gossip = append(event.Gossip, journal.PeerSnapshot{
+				Name:       peerState.Name,
+				IP:         peerState.IP,
+				Pubkey:     peerState.Pubkey,
+				Active:     peerState.LastSeenActive,
+				LastSeenAt: peerState.LastSeenAtUTC,
+			})
+		}
+		sort.Slice(event.Gossip, func(i, j int) bool {
+			return event.Gossip[i].Name < event.Gossip[j].Name
+		})
+	}
+
+	m.event = event
+}
+
+// setEventDetail adds context to the event being journaled
+func (m *Manager) setEventDetail(format string, args ...any) {
+	if m.event == nil {
+		return
+	}
+	m.event.Detail = fmt.Sprintf(format, args...)
+}
+
+// endEvent finishes the event started by beginEvent with outcome and appends it to the journal
+func (m *Manager) endEvent(outcome, reason string) {
+	event := m.event
+	if event == nil {
+		return
+	}
+	m.event = nil
+
+	event.Outcome = outcome
+	event.Reason = reason
+	event.DurationMs = m.clock.Since(event.Time).Milliseconds()
+
+	if m.journal == nil {
+		return
+	}
+
+	if err := m.journal.Append(*event); err != nil {
+		m.logger.Error("failed to journal event", "type", event.Type, "outcome", outcome, "error", err)
+	}
+}
+
+// endRecurringEvent is endEvent for decisions re-evaluated every sample - a refusal is journaled once rather
+// than every sample until the refusal reason changes, the decision succeeds or fails, or clearRecurringEvent
+// is called once the condition that prompted the decision has passed
+func (m *Manager) endRecurringEvent(outcome, reason string) {
+	if m.event == nil {
+		return
+	}
+
+	eventType := m.event.Type
+	if outcome != journal.OutcomeRefused {
+		m.clearRecurringEvent(eventType)
+		m.endEvent(outcome, reason)
+		return
+	}
+
+	if journaledReason, ok := m.journaledRefusals[eventType]; ok && journaledReason == reason {
+		m.event = nil
+		return
+	}
+
+	if m.journaledRefusals == nil {
+		m.journaledRefusals = map[string]string{}
+	}
+	m.journaledRefusals[eventType] = reason
+	m.endEvent(outcome, reason)
+}
+
+// clearRecurringEvent forgets the refusal journaled for eventType so that the next one is journaled
+func (m *Manager) clearRecurringEvent(eventType string) {
+	delete(m.journaledRefusals, eventType)
+}
+
+// recordCommand adds the result of a role command or hook to the event being journaled
+func (m *Manager) recordCommand(name, cmd string, args []string, duration time.Duration, err error) {
+	if m.event == nil {
+		return
+	}
+
+	result := journal.CommandResult{
+		Name:       name,
+		Command:    cmd,
+		Args:       args,
+		ExitCode:   command.ExitCode(err),
+		DurationMs: duration.Milliseconds(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	m.event.Commands = append(m.event.Commands, result)
+}
+
+// recordHookRun adds the result of a hook to the event being journaled
+func (m *Manager) recordHookRun(hookType string, hook config.Hook, duration time.Duration, err error) {
+	m.recordCommand(fmt.Sprintf("%s-hook %s", hookType, hook.Name), hook.Command, hook.Args, duration, err)
+}
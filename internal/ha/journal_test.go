@@ -0,0 +1,144 @@
+package ha
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/journal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createJournalTestManager(t *testing.T) *Manager {
+	t.Helper()
+
+	cfg := createTestConfig()
+	cfg.Failover.StateDir = t.TempDir()
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+	return manager
+}
+
+func readJournal(t *testing.T, manager *Manager) []journal.Event {
+	t.Helper()
+
+	events, err := journal.Read(manager.journal.File())
+	require.NoError(t, err)
+	return events
+}
+
+func TestOutcomeFromError(t *testing.T) {
+	outcome, reason := outcomeFromError(nil)
+	assert.Equal(t, journal.OutcomeSuccess, outcome)
+	assert.Empty(t, reason)
+
+	outcome, reason = outcomeFromError(refuse("we are not active"))
+	assert.Equal(t, journal.OutcomeRefused, outcome)
+	assert.Equal(t, "we are not active", reason)
+
+	outcome, reason = outcomeFromError(errors.New("switchover failed"))
+	assert.Equal(t, journal.OutcomeFailure, outcome)
+	assert.Equal(t, "switchover failed", reason)
+}
+
+func TestManager_JournalRecordsCommands(t *testing.T) {
+	manager := createJournalTestManager(t)
+	manager.cfg.Failover.Passive.Hooks.Pre = []config.Hook{{Name: "notify", Command: "true"}}
+	manager.cfg.Failover.Passive.Hooks.Post = []config.Hook{{Name: "page", Command: "true", Args: []string{"--urgent"}}}
+
+	manager.beginEvent(journal.EventTypeDemote)
+	manager.ensurePassive()
+	manager.endEvent(journal.OutcomeSuccess, "")
+
+	events := readJournal(t, manager)
+	require.Len(t, events, 1)
+	event := events[0]
+	assert.Equal(t, journal.EventTypeDemote, event.Type)
+	assert.Equal(t, "test-validator", event.Validator)
+	assert.Equal(t, journal.OutcomeSuccess, event.Outcome)
+	require.Len(t, event.Commands, 3)
+	assert.Equal(t, "pre-hook notify", event.Commands[0].Name)
+	assert.Equal(t, constants.RoleNamePassive, event.Commands[1].Name)
+	assert.Equal(t, manager.cfg.Failover.Passive.Command, event.Commands[1].Command)
+	assert.Equal(t, 0, event.Commands[1].ExitCode)
+	assert.Equal(t, "post-hook page", event.Commands[2].Name)
+	assert.Equal(t, []string{"--urgent"}, event.Commands[2].Args)
+	assert.Nil(t, manager.event)
+}
+
+func TestManager_JournalNotStartedIsNoop(t *testing.T) {
+	manager := createJournalTestManager(t)
+
+	// commands run outside of a journaled decision are not recorded anywhere
+	manager.ensurePassive()
+	manager.endEvent(journal.OutcomeSuccess, "")
+	assert.Empty(t, readJournal(t, manager))
+}
+
+func TestManager_JournalDisabledWithoutStateDir(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Failover.StateDir = ""
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+
+	manager.beginEvent(journal.EventTypeFailover)
+	manager.endEvent(journal.OutcomeRefused, "we are not healthy")
+	assert.Empty(t, manager.journal.File())
+}
+
+func TestManager_EndRecurringEvent(t *testing.T) {
+	manager := createJournalTestManager(t)
+
+	refuse := func(reason string) {
+		manager.beginEvent(journal.EventTypeFailover)
+		manager.endRecurringEvent(journal.OutcomeRefused, reason)
+	}
+
+	// the same refusal every sample is journaled once
+	refuse("we are not healthy")
+	refuse("we are not healthy")
+	assert.Len(t, readJournal(t, manager), 1)
+
+	// a different reason is journaled
+	refuse("we are in maintenance mode")
+	assert.Len(t, readJournal(t, manager), 2)
+
+	// once the condition has passed the same refusal is journaled again
+	manager.clearRecurringEvent(journal.EventTypeFailover)
+	refuse("we are in maintenance mode")
+	assert.Len(t, readJournal(t, manager), 3)
+
+	// other outcomes are always journaled and reset the refusal
+	manager.beginEvent(journal.EventTypeFailover)
+	manager.endRecurringEvent(journal.OutcomeFailure, "not active")
+	refuse("we are in maintenance mode")
+
+	events := readJournal(t, manager)
+	require.Len(t, events, 5)
+	assert.Equal(t, journal.OutcomeFailure, events[3].Outcome)
+	assert.Equal(t, journal.OutcomeRefused, events[4].Outcome)
+}
+
+func TestManager_SwitchoverJournaled(t *testing.T) {
+	manager := createJournalTestManager(t)
+
+	err := manager.Switchover("unknown")
+	assert.Error(t, err)
+
+	events := readJournal(t, manager)
+	require.Len(t, events, 1)
+	assert.Equal(t, journal.EventTypeSwitchover, events[0].Type)
+	assert.Equal(t, journal.OutcomeRefused, events[0].Outcome)
+	assert.Equal(t, "to unknown", events[0].Detail)
+	assert.Contains(t, events[0].Reason, "not found in failover.peers")
+}
@@ -0,0 +1,133 @@
+package ha
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// leaderScheduleWaitPollInterval is how often the leader schedule is checked while waiting for a window
+// without leader slots - slots are ~400ms so this must be much shorter than failover.poll_interval_duration
+const leaderScheduleWaitPollInterval = time.Second
+
+// getUpcomingLeaderSlots returns the current slot and the active identity's leader slots within the next
+// failover.leader_schedule_lookahead_slots, including those in the next epoch if the lookahead crosses into it
+func (m *Manager) getUpcomingLeaderSlots(ctx context.Context) (currentSlot uint64, leaderSlots []uint64, err error) {
+	epochInfo, err := m.clusterRPC.GetEpochInfo(ctx)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to get epoch info: %w", err)
+	}
+
+	currentSlot = epochInfo.AbsoluteSlot
+	lookaheadEndSlot := currentSlot + uint64(m.cfg.Failover.LeaderScheduleLookaheadSlots)
+	activePubkey := m.cfg.Validator.Identities.ActivePublicKey()
+
+	for epochStartSlot := currentSlot - epochInfo.SlotIndex; epochStartSlot < lookaheadEndSlot; epochStartSlot += epochInfo.SlotsInEpoch {
+		leaderSchedule, err := m.clusterRPC.GetLeaderSchedule(ctx, activePubkey, epochStartSlot)
+		if err != nil {
+			return currentSlot, nil, fmt.Errorf("failed to get leader schedule for epoch starting at slot %d: %w", epochStartSlot, err)
+		}
+
+		// slot indices are relative to the start of the epoch
+		for _, slotIndex := range leaderSchedule[activePubkey] {
+			slot := epochStartSlot + slotIndex
+			if slot >= currentSlot && slot < lookaheadEndSlot {
+				leaderSlots = append(leaderSlots, slot)
+			}
+		}
+
+		if epochInfo.SlotsInEpoch == 0 {
+			break
+		}
+	}
+
+	slices.Sort(leaderSlots)
+	return currentSlot, leaderSlots, nil
+}
+
+// waitForLeaderSlotFreeWindow blocks a planned transition until the active identity has no leader slots within
+// the next failover.leader_schedule_lookahead_slots so that changing identity does not skip blocks. It returns
+// an error if no such window appears within failover.leader_schedule_wait_timeout_duration. Leader schedule RPC
+// errors are forgiven - the transition goes ahead rather than being blocked by an RPC glitch
+func (m *Manager) waitForLeaderSlotFreeWindow() error {
+	if m.cfg.Failover.LeaderScheduleLookaheadSlots <= 0 {
+		return nil
+	}
+
+	ctx, cancel := m.clock.WithTimeout(m.ctx, m.cfg.Failover.LeaderScheduleWaitTimeoutDuration)
+	defer cancel()
+	defer func() { m.setLeaderScheduleState(m.leaderSlotsAtRisk, false) }()
+
+	for {
+		currentSlot, leaderSlots, err := m.getUpcomingLeaderSlots(ctx)
+		if err != nil {
+			m.logger.Warn("unable to check leader schedule - proceeding", "error", err)
+			return nil
+		}
+
+		if len(leaderSlots) == 0 {
+			m.setLeaderScheduleState(0, false)
+			m.logger.Info("leader schedule clear - proceeding",
+				"current_slot", currentSlot,
+				"leader_schedule_lookahead_slots", m.cfg.Failover.LeaderScheduleLookaheadSlots,
+			)
+			return nil
+		}
+
+		m.setLeaderScheduleState(len(leaderSlots), true)
+		m.logger.Warn("upcoming leader slots - waiting for a clear window",
+			"current_slot", currentSlot,
+			"next_leader_slot", leaderSlots[0],
+			"leader_slots", len(leaderSlots),
+			"leader_schedule_lookahead_slots", m.cfg.Failover.LeaderScheduleLookaheadSlots,
+		)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("no window without leader slots in the next %d slots within %s",
+				m.cfg.Failover.LeaderScheduleLookaheadSlots, m.cfg.Failover.LeaderScheduleWaitTimeoutDuration)
+		case <-m.clock.After(leaderScheduleWaitPollInterval):
+		}
+	}
+}
+
+// logLeaderSlotsAtRisk logs how many of the active identity's leader slots are coming up as we take over in a
+// failover - a failover never waits as the cluster is already leaderless
+func (m *Manager) logLeaderSlotsAtRisk() {
+	if m.cfg.Failover.LeaderScheduleLookaheadSlots <= 0 {
+		return
+	}
+
+	currentSlot, leaderSlots, err := m.getUpcomingLeaderSlots(m.ctx)
+	if err != nil {
+		m.logger.Warn("unable to check leader schedule", "error", err)
+		return
+	}
+
+	m.setLeaderScheduleState(len(leaderSlots), false)
+	if len(leaderSlots) == 0 {
+		m.logger.Info("no leader slots at risk", "current_slot", currentSlot)
+		return
+	}
+
+	m.logger.Warn("leader slots at risk - taking over regardless as the cluster is leaderless",
+		"current_slot", currentSlot,
+		"next_leader_slot", leaderSlots[0],
+		"leader_slots_at_risk", len(leaderSlots),
+		"leader_schedule_lookahead_slots", m.cfg.Failover.LeaderScheduleLookaheadSlots,
+	)
+}
+
+// setLeaderScheduleState records the leader schedule decision and exports it straight away - waiting holds
+// transitionMu so the regular metrics refresh would not see it until we are done
+func (m *Manager) setLeaderScheduleState(leaderSlotsAtRisk int, waiting bool) {
+	m.leaderSlotsAtRisk = leaderSlotsAtRisk
+	m.leaderScheduleWaiting = waiting
+
+	state := m.cache.GetState()
+	state.LeaderSlotsAtRisk = leaderSlotsAtRisk
+	state.LeaderScheduleWaiting = waiting
+	m.cache.UpdateState(state)
+	m.metrics.RefreshMetrics()
+}
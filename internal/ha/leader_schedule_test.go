@@ -0,0 +1,97 @@
+package ha
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createLeaderScheduleTestManager returns an initialized manager whose cluster RPC is at slot 1000, 100 slots
+// into an epoch of slotsInEpoch slots, with the active identity leader at the given slot indices of every epoch
+func createLeaderScheduleTestManager(t *testing.T, slotsInEpoch uint64, leaderSlotIndices []uint64) *Manager {
+	cfg := createTestConfig()
+	cfg.Failover.LeaderScheduleLookaheadSlots = 20
+	cfg.Failover.LeaderScheduleWaitTimeoutDuration = 100 * time.Millisecond
+	activePubkey := cfg.Validator.Identities.ActiveKeyPair.PublicKey().String()
+
+	server := mockRPCServer(t, map[string]func() any{
+		"getEpochInfo": func() any {
+			return map[string]any{
+				"absoluteSlot": 1000,
+				"blockHeight":  1000,
+				"epoch":        1,
+				"slotIndex":    100,
+				"slotsInEpoch": slotsInEpoch,
+			}
+		},
+		"getLeaderSchedule": func() any {
+			return map[string]any{activePubkey: leaderSlotIndices}
+		},
+	})
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+	manager.clusterRPC = rpc.NewClient("test", server.URL)
+
+	return manager
+}
+
+func TestManager_GetUpcomingLeaderSlots(t *testing.T) {
+	manager := createLeaderScheduleTestManager(t, 432000, []uint64{4, 104, 105, 120, 121})
+
+	currentSlot, leaderSlots, err := manager.getUpcomingLeaderSlots(manager.ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1000), currentSlot)
+	assert.Equal(t, []uint64{1004, 1005}, leaderSlots)
+}
+
+func TestManager_GetUpcomingLeaderSlots_NextEpoch(t *testing.T) {
+	// the epoch ends at slot 1010 so the lookahead covers the first 10 slots of the next epoch too
+	manager := createLeaderScheduleTestManager(t, 110, []uint64{4, 104})
+
+	_, leaderSlots, err := manager.getUpcomingLeaderSlots(manager.ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1004, 1014}, leaderSlots)
+}
+
+func TestManager_WaitForLeaderSlotFreeWindow(t *testing.T) {
+	// disabled
+	manager := createLeaderScheduleTestManager(t, 432000, []uint64{104})
+	manager.cfg.Failover.LeaderScheduleLookaheadSlots = 0
+	assert.NoError(t, manager.waitForLeaderSlotFreeWindow())
+
+	// clear window
+	manager = createLeaderScheduleTestManager(t, 432000, []uint64{4, 200})
+	assert.NoError(t, manager.waitForLeaderSlotFreeWindow())
+	assert.Equal(t, 0, manager.cache.GetState().LeaderSlotsAtRisk)
+
+	// leader slots never clear
+	manager = createLeaderScheduleTestManager(t, 432000, []uint64{104, 105, 106, 107})
+	err := manager.waitForLeaderSlotFreeWindow()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no window without leader slots in the next 20 slots")
+	assert.Equal(t, 4, manager.cache.GetState().LeaderSlotsAtRisk)
+	assert.False(t, manager.cache.GetState().LeaderScheduleWaiting)
+}
+
+func TestManager_WaitForLeaderSlotFreeWindow_RPCError(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Failover.LeaderScheduleLookaheadSlots = 20
+	cfg.Failover.LeaderScheduleWaitTimeoutDuration = time.Minute
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+	manager.clusterRPC = rpc.NewClient("test", mockRPCServer(t, map[string]func() any{}).URL)
+
+	// rpc errors are forgiven
+	assert.NoError(t, manager.waitForLeaderSlotFreeWindow())
+}
@@ -0,0 +1,71 @@
+package ha
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/api"
+)
+
+// SetMaintenance enables or disables maintenance mode. While in maintenance mode the manager keeps
+// monitoring and exporting metrics but never takes over as active
+func (m *Manager) SetMaintenance(enabled bool) {
+	previous := m.maintenance.Swap(enabled)
+	if previous == enabled {
+		return
+	}
+
+	if enabled {
+		m.logger.Warn("maintenance mode enabled - takeover is suppressed")
+		return
+	}
+
+	m.logger.Info("maintenance mode disabled")
+	if m.isMaintenanceFilePresent() {
+		m.logger.Warn("maintenance mode remains enabled by failover.maintenance_file", "maintenance_file", m.cfg.Failover.MaintenanceFile)
+	}
+}
+
+// isInMaintenance returns true if maintenance mode was enabled via SetMaintenance or the
+// failover.maintenance_file sentinel file exists
+func (m *Manager) isInMaintenance() bool {
+	return m.maintenance.Load() || m.isMaintenanceFilePresent()
+}
+
+// isMaintenanceFilePresent returns true if failover.maintenance_file is set and exists
+func (m *Manager) isMaintenanceFilePresent() bool {
+	if m.cfg.Failover.MaintenanceFile == "" {
+		return false
+	}
+
+	_, err := os.Stat(m.cfg.Failover.MaintenanceFile)
+	return err == nil
+}
+
+// handleMaintenance handles a request to enable or disable maintenance mode
+func (m *Manager) handleMaintenance(w http.ResponseWriter, r *http.Request, body []byte) {
+	var request api.MaintenanceRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		api.WriteResponse(w, http.StatusBadRequest, api.Response{Message: "request body must be a JSON object with an \"enabled\" boolean"})
+		return
+	}
+
+	m.SetMaintenance(request.Enabled)
+
+	message := fmt.Sprintf("maintenance mode %s", enabledString(m.isInMaintenance()))
+	if !request.Enabled && m.isMaintenanceFilePresent() {
+		message += fmt.Sprintf(" - remove %s to disable", m.cfg.Failover.MaintenanceFile)
+	}
+
+	api.WriteResponse(w, http.StatusOK, api.Response{OK: true, Message: message})
+}
+
+// enabledString returns "enabled" or "disabled"
+func enabledString(enabled bool) string {
+	if enabled {
+		return "enabled"
+	}
+	return "disabled"
+}
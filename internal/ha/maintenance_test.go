@@ -0,0 +1,99 @@
+package ha
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_SetMaintenance(t *testing.T) {
+	cfg := createTestConfig()
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+
+	assert.False(t, manager.isInMaintenance())
+
+	manager.SetMaintenance(true)
+	assert.True(t, manager.isInMaintenance())
+
+	manager.SetMaintenance(false)
+	assert.False(t, manager.isInMaintenance())
+}
+
+func TestManager_MaintenanceFile(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Failover.MaintenanceFile = filepath.Join(t.TempDir(), "maintenance")
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+
+	// no sentinel file
+	assert.False(t, manager.isInMaintenance())
+
+	// sentinel file present
+	require.NoError(t, os.WriteFile(cfg.Failover.MaintenanceFile, nil, 0644))
+	assert.True(t, manager.isInMaintenance())
+
+	// disabling via API does not override the sentinel file
+	manager.SetMaintenance(false)
+	assert.True(t, manager.isInMaintenance())
+
+	// sentinel file removed
+	require.NoError(t, os.Remove(cfg.Failover.MaintenanceFile))
+	assert.False(t, manager.isInMaintenance())
+}
+
+func TestManager_RefreshMetrics_MaintenanceMode(t *testing.T) {
+	cfg := createTestConfig()
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+
+	manager.SetMaintenance(true)
+	manager.refreshMetrics()
+	assert.True(t, manager.cache.GetState().MaintenanceMode)
+
+	manager.SetMaintenance(false)
+	manager.refreshMetrics()
+	assert.False(t, manager.cache.GetState().MaintenanceMode)
+}
+
+func TestManager_HandleMaintenance(t *testing.T) {
+	cfg := createTestConfig()
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+
+	mux := http.NewServeMux()
+	manager.registerAPIHandlers(mux)
+
+	body := `{"enabled":true}`
+	request := httptest.NewRequest(http.MethodPost, api.PathMaintenance, strings.NewReader(body))
+	request.RemoteAddr = "127.0.0.1:1234"
+	require.NoError(t, api.Sign(request, []byte(body), *cfg.Validator.Identities.ActiveKeyPair, cfg.Validator.Name, time.Now()))
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "maintenance mode enabled")
+	assert.True(t, manager.isInMaintenance())
+}
@@ -2,32 +2,65 @@ package ha
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
+	"net"
 	"net/http"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/charmbracelet/log"
+	solanago "github.com/gagliardetto/solana-go"
 	solanagorpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/sol-strategies/solana-validator-ha/internal/api"
 	"github.com/sol-strategies/solana-validator-ha/internal/cache"
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+	"github.com/sol-strategies/solana-validator-ha/internal/command"
 	"github.com/sol-strategies/solana-validator-ha/internal/config"
 	"github.com/sol-strategies/solana-validator-ha/internal/constants"
 	"github.com/sol-strategies/solana-validator-ha/internal/gossip"
+	"github.com/sol-strategies/solana-validator-ha/internal/journal"
 	"github.com/sol-strategies/solana-validator-ha/internal/prometheus"
 	"github.com/sol-strategies/solana-validator-ha/internal/rpc"
 )
 
-// RPCClient interface for RPC operations
-type RPCClient interface {
-	GetClusterNodes(ctx context.Context) ([]*solanagorpc.GetClusterNodesResult, error)
+// LocalRPC is the RPC of the validator we manage, used to check its health and identity
+type LocalRPC interface {
+	GetHealth(ctx context.Context) (string, error)
 	GetIdentity(ctx context.Context) (*solanagorpc.GetIdentityResult, error)
 }
 
+// ClusterRPC is the cluster RPC used for gossip, vote accounts and the leader schedule
+type ClusterRPC interface {
+	gossip.ClusterRPC
+	GetEpochInfo(ctx context.Context) (*solanagorpc.GetEpochInfoResult, error)
+	GetLeaderSchedule(ctx context.Context, identity solanago.PublicKey, slot uint64) (solanagorpc.GetLeaderScheduleResult, error)
+}
+
 // NewManagerOptions is a struct that contains the configuration for the manager
 type NewManagerOptions struct {
 	Cfg             *config.Config
 	GetPublicIPFunc func() (string, error)
+
+	// the following default to the real thing and are overridden to run managers in simulations
+
+	// Clock defaults to the system clock
+	Clock clock.Clock
+	// LocalRPC defaults to an RPC client for validator.rpc_url - witnesses have none
+	LocalRPC LocalRPC
+	// ClusterRPC defaults to an RPC client for cluster.rpc_urls
+	ClusterRPC ClusterRPC
+	// CommandRunner runs role commands and hooks - defaults to running them as processes
+	CommandRunner command.Runner
+	// PeerTransport carries requests to peers' HA APIs - defaults to http.DefaultTransport
+	PeerTransport http.RoundTripper
+	// GossipDialFunc probes peers' gossip addresses for liveness - defaults to net.Dial
+	GossipDialFunc func(network, address string) (net.Conn, error)
+	// Rand is the source of takeover jitter - defaults to a randomly seeded source
+	Rand *rand.Rand
 }
 
 // Manager handles high availability logic
@@ -39,17 +72,72 @@ type Manager struct {
 	ctx             context.Context
 	peerSelf        *config.Peer
 	cancel          context.CancelFunc
+	commandCtx      context.Context
+	commandCancel   context.CancelFunc
+	loopMu          sync.Mutex
+	loopDone        chan struct{}
+	healthServerMu  sync.Mutex
+	healthServer    *http.Server
+	transitionMu    sync.Mutex // serializes HA state evaluation and API-driven role transitions
+	stopping        atomic.Bool
+	maintenance     atomic.Bool
+	apiClient       *api.Client
 	gossipState     *gossip.State
 	getPublicIPFunc func() (string, error)
-	localRPC        *rpc.Client
+	localRPC        LocalRPC
+	clusterRPC      ClusterRPC
+	clock           clock.Clock
+	commandRunner   command.Runner
+	peerTransport   http.RoundTripper
+	gossipDialFunc  func(network, address string) (net.Conn, error)
+	rand            *rand.Rand
 	peerCount       int
+	initMu          sync.Mutex // held while initializing so that Stop waits for startup to finish
 	initialized     bool
+	started         bool // failover.on_startup has been applied to the first cluster sample
 	logPrefix       string
+
+	// activeUnhealthySamplesCount is the number of consecutive samples we have been active and unhealthy
+	activeUnhealthySamplesCount int
+	// splitBrainSamplesCount is the number of consecutive samples with more than one active identity claimant
+	splitBrainSamplesCount int
+	splitBrainDetected     bool
+	// failbackPeerName is the preferred peer we have been seeing in gossip since failbackPeerSeenSince
+	failbackPeerName      string
+	failbackPeerSeenSince time.Time
+	// voteMu guards our takeover vote - it is separate from transitionMu so that candidates holding
+	// their own transitionMu can vote for each other
+	voteMu   sync.Mutex
+	votedFor string
+	votedAt  time.Time
+	// transitions are our recent role changes used for flap damping, persisted to failover.state_dir
+	transitions             roleTransitions
+	transitionBudgetAlerted bool
+	// takeoverBackoffUntil is when we may take over again after rolling back a failed activation
+	takeoverBackoffUntil time.Time
+	// leaderSlotsAtRisk is the active identity's leader slots within the lookahead at the last check
+	leaderSlotsAtRisk     int
+	leaderScheduleWaiting bool
+	// activeIdentityVoting is true when our last takeover was aborted by the double vote guard
+	activeIdentityVoting bool
+	// journal is the append-only record of failover decisions in failover.state_dir, event is the one
+	// being made and journaledRefusals the last refusal journaled per recurring event type
+	journal           *journal.Journal
+	event             *journal.Event
+	journaledRefusals map[string]string
+	// balances are the last sampled balances of the accounts validator.balance_monitor watches, by account -
+	// guarded by balancesMu as they are sampled apart from the HA monitor loop
+	balancesMu sync.Mutex
+	balances   map[string]cache.AccountBalance
+	// rentExemptMinimums are the last fetched rent-exempt minimums by data size - only touched by the balance
+	// monitor loop
+	rentExemptMinimums map[uint64]uint64
 }
 
 // NewManager creates a new HA manager from options
 func NewManager(opts NewManagerOptions) *Manager {
 	ctx, cancel := context.WithCancel(context.Background())
+	commandCtx, commandCancel := context.WithCancel(context.Background())
 
 	// Create cache
 	cache := cache.New()
@@ -62,20 +150,45 @@ func NewManager(opts NewManagerOptions) *Manager {
 	})
 
 	manager := &Manager{
-		cfg:       opts.Cfg,
-		metrics:   metrics,
-		cache:     cache,
-		logger:    log.WithPrefix(fmt.Sprintf("[%s ha_manager]", opts.Cfg.Validator.Name)),
-		localRPC:  rpc.NewClient(opts.Cfg.Validator.Name, opts.Cfg.Validator.RPCURL),
-		ctx:       ctx,
-		cancel:    cancel,
-		peerCount: len(opts.Cfg.Failover.Peers),
+		cfg:            opts.Cfg,
+		metrics:        metrics,
+		cache:          cache,
+		logger:         log.WithPrefix(fmt.Sprintf("[%s ha_manager]", opts.Cfg.Validator.Name)),
+		localRPC:       opts.LocalRPC,
+		clusterRPC:     opts.ClusterRPC,
+		clock:          opts.Clock,
+		commandRunner:  opts.CommandRunner,
+		peerTransport:  opts.PeerTransport,
+		gossipDialFunc: opts.GossipDialFunc,
+		rand:           opts.Rand,
+		ctx:            ctx,
+		cancel:         cancel,
+		commandCtx:     commandCtx,
+		commandCancel:  commandCancel,
+		peerCount:      len(opts.Cfg.Failover.Peers),
+		journal:        journal.New(journal.FilePath(opts.Cfg.Failover.StateDir)),
 	}
 
 	if opts.GetPublicIPFunc != nil {
 		manager.getPublicIPFunc = opts.GetPublicIPFunc
 	}
 
+	if manager.localRPC == nil && !opts.Cfg.Validator.IsWitness() {
+		manager.localRPC = rpc.NewClient(opts.Cfg.Validator.Name, opts.Cfg.Validator.RPCURL)
+	}
+
+	if manager.clock == nil {
+		manager.clock = clock.New()
+	}
+
+	if manager.commandRunner == nil {
+		manager.commandRunner = command.ExecRunner{}
+	}
+
+	if manager.rand == nil {
+		manager.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
 	return manager
 }
 
@@ -90,12 +203,118 @@ func (m *Manager) Run() error {
 	// start metrics server
 	go m.startMetricsServer()
 
+	return m.monitor()
+}
+
+// monitor starts the balance monitor loop and runs the HA monitor loop until the manager is stopped. It is
+// shared by Run and Daemon.Run, which start the servers themselves
+func (m *Manager) monitor() error {
+	// start balance monitor loop - stopped with the HA monitor loop
+	go m.balanceMonitorLoop(m.ctx)
+
 	// start monitoring loop
 	return m.haMonitorLoop()
 }
 
+// Step initializes the manager if needed and evaluates the HA state once, as the monitor loop does every
+// failover.poll_interval_duration. Until the startup policy has been applied to a cluster sample, steps
+// only try to do so. Simulations use it to drive managers one sample at a time
+func (m *Manager) Step() error {
+	if err := m.initialize(); err != nil {
+		return err
+	}
+
+	if !m.started {
+		m.startup()
+		return nil
+	}
+
+	m.ensureHAState()
+	return nil
+}
+
+// APIHandler returns a handler for the HA API peers call on the health check port
+func (m *Manager) APIHandler() http.Handler {
+	mux := http.NewServeMux()
+	m.registerAPIHandlers(mux)
+	return mux
+}
+
+// Stop gracefully shuts down the HA manager. It stops the monitor loop and waits for any in-flight
+// failover commands until ctx is done, after which they are cancelled. The failover.on_shutdown
+// policy is then applied within what is left of ctx before draining the metrics and health check servers.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.logger.Info("stopping", "on_shutdown", m.cfg.Failover.OnShutdown)
+
+	// stop the monitor loop from starting new HA state evaluations
+	m.stopping.Store(true)
+	m.cancel()
+
+	// wait for an in-flight evaluation (and the commands it may be running) to complete
+	m.loopMu.Lock()
+	loopDone := m.loopDone
+	m.loopMu.Unlock()
+	if loopDone != nil {
+		select {
+		case <-loopDone:
+			m.logger.Debug("HA monitor loop drained")
+		case <-ctx.Done():
+			m.logger.Warn("timed out waiting for in-flight commands to complete - cancelling them", "error", ctx.Err())
+			m.commandCancel()
+			<-loopDone
+		}
+	}
+
+	// step down if configured to and we are active - once any startup in progress has finished
+	m.initMu.Lock()
+	initialized := m.initialized
+	m.initMu.Unlock()
+	if m.cfg.Failover.OnShutdown == config.FailoverOnShutdownPassive && initialized && !m.isWitness() {
+		m.stepDownOnShutdown(ctx)
+	}
+
+	// drain metrics and health check servers
+	var errs []error
+	if err := m.metrics.ShutdownServer(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to shutdown metrics server: %w", err))
+	}
+
+	m.healthServerMu.Lock()
+	healthServer := m.healthServer
+	m.healthServerMu.Unlock()
+	if healthServer != nil {
+		if err := healthServer.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to shutdown health check server: %w", err))
+		}
+	}
+
+	m.commandCancel()
+	m.logger.Info("stopped")
+	return errors.Join(errs...)
+}
+
+// stepDownOnShutdown runs the passive command if we are active. The manager contexts are cancelled by
+// the time this is called, so the step-down runs within ctx - the one Stop was given
+func (m *Manager) stepDownOnShutdown(ctx context.Context) {
+	m.transitionMu.Lock()
+	defer m.transitionMu.Unlock()
+
+	if !m.hasActiveIdentity(ctx) {
+		m.logger.Info("we are not active - no need to step down on shutdown")
+		return
+	}
+
+	m.logger.Warn("we are active on shutdown - stepping down to passive", "on_shutdown", m.cfg.Failover.OnShutdown)
+	m.beginEvent(journal.EventTypeShutdown)
+	m.becomePassive(ctx, ctx)
+	m.endEvent(m.passiveOutcome(ctx))
+}
+
 // initialize initializes the manager
 func (m *Manager) initialize() error {
+	m.initMu.Lock()
+	defer m.initMu.Unlock()
+
 	m.logger.Debug("initializing manager")
 
 	// Check if already initialized
@@ -112,7 +331,6 @@ func (m *Manager) initialize() error {
 
 	// set global log prefix to pass everywhere
 	m.logPrefix = m.cfg.Validator.Name
-	m.logger = log.WithPrefix(fmt.Sprintf("[%s ha_manager]", m.logPrefix))
 
 	// peers config file must not declare ourselves
 	if m.cfg.Failover.Peers.HasIP(publicIP) {
@@ -122,30 +340,69 @@ func (m *Manager) initialize() error {
 	// now we can set ourselves as a peer and continue
 	m.logger.Debug("adding us to config peers", "name", m.cfg.Validator.Name, "ip", publicIP)
 	m.peerSelf = &config.Peer{
-		Name: m.cfg.Validator.Name,
-		IP:   publicIP,
+		Name:     m.cfg.Validator.Name,
+		IP:       publicIP,
+		Priority: m.cfg.Failover.Priority,
+		Witness:  m.isWitness(),
+	}
+	// we know our own passive identity, so nothing else at our address can be taken for us
+	if !m.isWitness() {
+		m.peerSelf.Pubkey = m.cfg.Validator.Identities.PassiveKeyPair.PublicKey().String()
 	}
 	m.cfg.Failover.Peers.Add(*m.peerSelf)
 
 	// initialize
-	m.logger.Info("initializing",
+	initializingArgs := []any{
+		"mode", m.cfg.Validator.Mode,
 		"public_ip", publicIP,
 		"cluster_rpc_urls", m.cfg.Cluster.RPCURLs,
-		"validator_rpc_url", m.cfg.Validator.RPCURL,
-		"active_pubkey", m.cfg.Validator.Identities.ActiveKeyPair.PublicKey().String(),
-		"passive_pubkey", m.cfg.Validator.Identities.PassiveKeyPair.PublicKey().String(),
-		"peers", m.cfg.Failover.Peers.String(),
-	)
+		"active_pubkey", m.cfg.Validator.Identities.ActivePublicKey().String(),
+	}
+	if !m.isWitness() {
+		initializingArgs = append(initializingArgs,
+			"validator_rpc_url", m.cfg.Validator.RPCURL,
+			"passive_pubkey", m.cfg.Validator.Identities.PassiveKeyPair.PublicKey().String(),
+		)
+	}
+	m.logger.Info("initializing", append(initializingArgs, "peers", m.cfg.Failover.Peers.String())...)
 
 	// create gossip state
 	m.logger.Debug("creating gossip state")
+	if m.clusterRPC == nil {
+		m.clusterRPC = rpc.NewClient(m.logPrefix, m.cfg.Cluster.RPCURLs...)
+	}
 	m.gossipState = gossip.NewState(gossip.Options{
-		ClusterRPC:   rpc.NewClient(m.logPrefix, m.cfg.Cluster.RPCURLs...),
-		ActivePubkey: m.cfg.Validator.Identities.ActiveKeyPair.PublicKey().String(),
-		ConfigPeers:  m.cfg.Failover.Peers,
+		ClusterRPC:   m.clusterRPC,
+		ActivePubkey: m.cfg.Validator.Identities.ActivePublicKey().String(),
+		ConfigPeers:  m.cfg.Failover.Peers.Validators(),
 		LogPrefix:    m.logPrefix,
+		DialFunc:     m.gossipDialFunc,
+		Clock:        m.clock,
+		// pings are signed with the passive identity - witnesses have none and sign with a generated key pair
+		ProbeStrategy:            m.cfg.Failover.GossipProbe.Strategy,
+		ProbeTimeout:             m.cfg.Failover.GossipProbe.TimeoutDuration,
+		PingKeyPair:              m.cfg.Validator.Identities.PassiveKeyPair,
+		RPCQuorum:                m.cfg.Cluster.LeaderlessRPCQuorum(),
+		MaxVoteLagSlots:          uint64(m.cfg.Failover.MaxVoteLagSlots),
+		VoteLagSamplesThreshold:  m.cfg.Failover.VoteLagSamplesThreshold,
+		HistorySize:              m.cfg.Failover.PeerHistory.Size,
+		FlapTransitionsThreshold: m.cfg.Failover.PeerHistory.FlapTransitionsThreshold,
 	})
 
+	// requests to peers are signed with the shared active identity - witnesses hold no key pairs and
+	// only answer requests
+	if !m.isWitness() {
+		m.apiClient = api.NewClient(*m.cfg.Validator.Identities.ActiveKeyPair)
+		if m.peerTransport != nil {
+			m.apiClient.WithTransport(m.peerTransport)
+		}
+	}
+
+	// role transitions survive restarts so that flapping cannot be reset by restarting
+	if err := m.loadTransitions(); err != nil {
+		m.logger.Warn("failed to load role transitions - flap damping starts from scratch", "error", err)
+	}
+
 	m.logger.Debug("initialized")
 	m.initialized = true
 	return nil
@@ -171,19 +428,23 @@ func (m *Manager) startMetricsServer() {
 		}
 	}()
 
-	// Start health check server on a different port
+	// Start health check and HA API server on a different port
 	go func() {
 		mux := http.NewServeMux()
 		mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
 			w.Write([]byte("healthy"))
 		})
+		m.registerAPIHandlers(mux)
 
-		port := strconv.Itoa(m.cfg.Prometheus.Port + 1) // Use next port for health check
+		port := strconv.Itoa(m.cfg.Prometheus.HealthCheckPort()) // Use next port for health check
 		healthServer := &http.Server{
 			Addr:    ":" + port,
 			Handler: mux,
 		}
+		m.healthServerMu.Lock()
+		m.healthServer = healthServer
+		m.healthServerMu.Unlock()
 
 		m.logger.Debug("starting health check server", "port", port)
 
@@ -197,17 +458,30 @@ func (m *Manager) startMetricsServer() {
 func (m *Manager) haMonitorLoop() error {
 	m.logger.Info("monitoring HA state", "poll_interval", m.cfg.Failover.PollIntervalDuration)
 
-	// initial gossip state population
-	m.gossipState.Refresh()
+	// signal to Stop when the loop and any in-flight evaluation has returned
+	loopDone := make(chan struct{})
+	m.loopMu.Lock()
+	m.loopDone = loopDone
+	m.loopMu.Unlock()
+	defer close(loopDone)
 
-	// check for active peer in state and log if found
-	m.checkForActivePeer()
+	interval := m.cfg.Failover.PollIntervalDuration
+
+	// initial gossip state population - retried every interval until the cluster rpc answers, then the
+	// failover.on_startup policy is applied to it
+	for !m.startup() {
+		select {
+		case <-m.ctx.Done():
+			m.logger.Info("HA monitor loop done")
+			return nil
+		case <-m.clock.After(interval):
+		}
+	}
 
 	// start the monitor loop with ticker aligned to interval boundaries
-	ticker := time.NewTicker(m.cfg.Failover.PollIntervalDuration)
+	ticker := m.clock.NewTicker(interval)
 	defer ticker.Stop()
 
-	interval := m.cfg.Failover.PollIntervalDuration
 	intervalNanos := int64(interval)
 
 	for {
@@ -215,11 +489,11 @@ func (m *Manager) haMonitorLoop() error {
 		case <-m.ctx.Done():
 			m.logger.Info("HA monitor loop done")
 			return nil
-		case <-ticker.C:
+		case <-ticker.C():
 			// Wait until the next aligned interval before running
 			// This ensures all nodes run at the same synchronized times
 			// For example, with 5s interval: all nodes run at 12:01:05, 12:01:10, etc.
-			now := time.Now()
+			now := m.clock.Now()
 			nanosSinceEpoch := now.UnixNano()
 			remainder := nanosSinceEpoch % intervalNanos
 
@@ -231,7 +505,7 @@ func (m *Manager) haMonitorLoop() error {
 				case <-m.ctx.Done():
 					m.logger.Info("HA monitor loop done")
 					return nil
-				case <-time.After(waitDuration):
+				case <-m.clock.After(waitDuration):
 					// Now we're at the aligned time
 				}
 			}
@@ -243,13 +517,14 @@ func (m *Manager) haMonitorLoop() error {
 
 // checkForActivePeer checks for an active peer in the gossip state
 func (m *Manager) checkForActivePeer() {
-	if m.gossipState.LeaderlessSamplesExceedsThreshold(m.cfg.Failover.LeaderlessSamplesThreshold) {
+	gossipSnapshot := m.gossipState.Snapshot()
+	if gossipSnapshot.LeaderlessSamplesExceedsThreshold(m.cfg.Failover.LeaderlessSamplesThreshold) {
 		m.logger.Warn(fmt.Sprintf("leaderless samples exceeds threshold %d > %d",
-			m.gossipState.LeaderlessSamplesCount, m.cfg.Failover.LeaderlessSamplesThreshold))
+			gossipSnapshot.LeaderlessSamplesCount, m.cfg.Failover.LeaderlessSamplesThreshold))
 		return
 	}
 
-	activePeerState, err := m.gossipState.GetActivePeer()
+	activePeerState, err := gossipSnapshot.GetActivePeer()
 	if err != nil {
 		m.logger.Warn("failed to get active peer from state", "error", err)
 		return
@@ -265,27 +540,63 @@ func (m *Manager) checkForActivePeer() {
 
 // ensureHAState implements basic HA logic
 func (m *Manager) ensureHAState() {
+	// witnesses have no validator to fail over
+	if m.isWitness() {
+		m.observe()
+		return
+	}
+
+	m.transitionMu.Lock()
+	defer m.transitionMu.Unlock()
+
 	m.logger.Debug("ensuring HA")
 
 	// refresh gossip state
 	m.gossipState.Refresh()
 
+	// detect split-brain before refreshing metrics so that it is exported with this sample
+	splitBrainClaimants := m.detectSplitBrain()
+
 	// refresh metrics
 	m.refreshMetrics()
 
+	// more than one node claiming the active identity trumps everything else
+	if m.resolveSplitBrain(splitBrainClaimants) {
+		return
+	}
+
+	// step down if we are an unhealthy active and a passive peer can take over
+	if m.demoteIfUnhealthyActive() {
+		return
+	}
+
+	// hand the active role back to the preferred peer if failback is enabled
+	if m.failbackToPreferredPeer() {
+		return
+	}
+
 	// if there is an active peer found in the last failover.leaderless_samples_threshold - we are good
 	// having a lookback grace period is important to allow for RPC glitches and other issues
-	if !m.gossipState.LeaderlessSamplesExceedsThreshold(m.cfg.Failover.LeaderlessSamplesThreshold) {
+	gossipSnapshot := m.gossipState.Snapshot()
+	if !gossipSnapshot.LeaderlessSamplesExceedsThreshold(m.cfg.Failover.LeaderlessSamplesThreshold) {
 		m.logger.Debug("active peer found - no failover required")
+		m.clearRecurringEvent(journal.EventTypeFailover)
+		m.activeIdentityVoting = false
 		return
 	}
 
 	// we see no active peer in the last failover.leaderless_samples_threshold, so we need to failover
-	m.logger.Error(fmt.Sprintf("no active peer found in the last %d samples - failover required", m.gossipState.LeaderlessSamplesCount))
+	m.logger.Error(fmt.Sprintf("no active peer found in the last %d samples - failover required", gossipSnapshot.LeaderlessSamplesCount))
+
+	// journal the failover decision however it turns out
+	m.beginEvent(journal.EventTypeFailover)
+	outcome, reason := journal.OutcomeRefused, ""
+	defer func() { m.endRecurringEvent(outcome, reason) }()
 
 	// if we don't see ourselves in gossip - bow out of the failover process and make sure we are passive - disconnection or starting up
 	if m.isSelfNotInGossip() {
 		m.logger.Error("we do not appear in gossip - unable to become active in failover, ensuring we are passive")
+		reason = "we do not appear in gossip"
 		m.ensurePassive()
 		// m.gossipState.Refresh() // refresh gossip state for clean next run
 		return
@@ -295,12 +606,36 @@ func (m *Manager) ensureHAState() {
 	// to participate in failover we must be healthy
 	if m.isSelfUnhealthy() {
 		m.logger.Error("we are not healthy - unable to become active in failover")
+		reason = "we are not healthy"
 		return
 	}
 
 	// one last check to ensure we are NOT already active
 	if m.isSelfActive() {
 		m.logger.Warn("we are already active - nothing to do")
+		reason = "we are already active"
+		return
+	}
+
+	// in maintenance mode we keep monitoring but never take over
+	if m.isInMaintenance() {
+		m.logger.Warn("we are in maintenance mode - skipping takeover", "maintenance_file", m.cfg.Failover.MaintenanceFile)
+		reason = "we are in maintenance mode"
+		return
+	}
+
+	// give the next-ranked peer a chance after our last activation was rolled back
+	if m.clock.Now().Before(m.takeoverBackoffUntil) {
+		m.logger.Warn("our last activation was rolled back - leaving takeover to the next-ranked peer",
+			"takeover_backoff_until", m.takeoverBackoffUntil.Format(time.RFC3339))
+		reason = "our last activation was rolled back"
+		return
+	}
+
+	// flap damping - refuse to take over if we have changed role too often or too recently
+	if err := m.checkTransitionAllowed(); err != nil {
+		m.logger.Error("refusing to take over", "reason", err)
+		reason = err.Error()
 		return
 	}
 
@@ -308,16 +643,22 @@ func (m *Manager) ensureHAState() {
 	// so we begin checks to make sure none of our peers have already taken over as active
 
 	// introduce a delay based on IP to safeguard against multiple nodes trying to become active at the same time
-	m.delayTakeover()
+	if err := m.delayTakeover(m.ctx); err != nil {
+		m.logger.Warn("stopped during takeover delay - not becoming active")
+		reason = "stopped during takeover delay"
+		return
+	}
 
 	// refresh the peers state to ensure no one else has taken over already if we know
 	// there are at least 2 possible peers other than ourselves - this will reset the leaderless samples count
 	// if a new leader is found
 	m.gossipState.Refresh()
+	gossipSnapshot = m.gossipState.Snapshot()
 
 	// if someone has already taken over as active - say so and return
-	if m.gossipState.LeaderlessSamplesBelowThreshold(m.cfg.Failover.LeaderlessSamplesThreshold) {
-		activePeerState, err := m.gossipState.GetActivePeer()
+	if gossipSnapshot.LeaderlessSamplesBelowThreshold(m.cfg.Failover.LeaderlessSamplesThreshold) {
+		reason = "a peer took over first"
+		activePeerState, err := gossipSnapshot.GetActivePeer()
 		if err != nil {
 			m.logger.Warn("failed to get active peer from state, but we know someone else already assumed active role", "error", err)
 			return
@@ -326,21 +667,59 @@ func (m *Manager) ensureHAState() {
 			"ip", activePeerState.IP,
 			"pubkey", activePeerState.Pubkey,
 		)
+		reason = fmt.Sprintf("peer %s took over first", activePeerState.Name)
 		return
 	}
 
+	// with failover.takeover_quorum a majority of peers must agree to us taking over
+	if !m.hasTakeoverQuorum() {
+		m.logger.Error("no takeover quorum - not becoming active")
+		reason = "no takeover quorum"
+		return
+	}
+
+	// make sure the previous active has stopped signing before we start
+	if err := m.fencePreviousActive(); err != nil {
+		m.logger.Error("previous active peer not fenced - not becoming active", "error", err)
+		outcome, reason = outcomeFromError(err)
+		return
+	}
+
+	// make sure nobody is still voting with the active identity - if we start voting too it is a double vote
+	if err := m.checkActiveIdentityNotVoting(); err != nil {
+		m.logger.Error("double vote guard - not becoming active", "error", err)
+		outcome, reason = outcomeFromError(err)
+		return
+	}
+
+	// say how many leader slots we are about to take over late for
+	m.logLeaderSlotsAtRisk()
+
 	// now we know we are healthy, passive, and none of our peers have assumed active role
 	// we can take over as active - this should be idempotent in setting the active role
 	m.ensureActive()
+	if !m.cfg.Failover.DryRun && !m.isSelfActive() {
+		outcome, reason = journal.OutcomeFailure, "not active as reported by local rpc after running active command"
+		return
+	}
+
+	// make sure we actually started voting
+	outcome, reason = outcomeFromError(m.verifyActivation())
 }
 
 // ensurePassive calls a user-specified command that should be idempotent in setting the passive role
 // safest thing would be to to ensure validator service always starts with passive identity
 // and the failover.passive.command simply retsarts the validator service or waits for it to start up
 func (m *Manager) ensurePassive() {
+	m.becomePassive(m.ctx, m.commandCtx)
+}
+
+// becomePassive is ensurePassive with local rpc calls made within ctx and commands run within commandCtx
+func (m *Manager) becomePassive(ctx, commandCtx context.Context) {
 	var err error
 	passivePubkey := m.cfg.Validator.Identities.PassiveKeyPair.PublicKey().String()
 	m.logger.Info("becoming passive", "pubkey", passivePubkey)
+	wasActive := m.hasActiveIdentity(ctx)
 
 	// Update failover status in cache
 	state := m.cache.GetState()
@@ -351,11 +730,14 @@ func (m *Manager) ensurePassive() {
 	if len(m.cfg.Failover.Passive.Hooks.Pre) > 0 {
 		m.logger.Debug("running pre-passive hooks")
 		err = m.cfg.Failover.Passive.Hooks.RunPre(config.HooksRunOptions{
+			Ctx:          commandCtx,
 			DryRun:       m.cfg.Failover.DryRun,
 			LoggerPrefix: m.logPrefix,
 			LoggerArgs: []any{
 				"failover_stage", "pre-passive",
 			},
+			OnHookRun: m.recordHookRun,
+			Runner:    m.commandRunner,
 		})
 	}
 	if err != nil {
@@ -365,14 +747,18 @@ func (m *Manager) ensurePassive() {
 
 	// run passive command
 	m.logger.Debug("running passive command")
+	started := m.clock.Now()
 	err = m.cfg.Failover.Passive.RunCommand(config.RoleCommandRunOptions{
+		Ctx:          commandCtx,
 		DryRun:       m.cfg.Failover.DryRun,
 		LoggerPrefix: m.logPrefix,
 		LoggerArgs: []any{
 			"failover_stage", constants.RoleNamePassive,
 			"passive_pubkey", passivePubkey,
 		},
+		Runner: m.commandRunner,
 	})
+	m.recordCommand(constants.RoleNamePassive, m.cfg.Failover.Passive.Command, m.cfg.Failover.Passive.Args, m.clock.Since(started), err)
 	if err != nil {
 		m.logger.Warn("failed to run passive command", "error", err)
 		return
@@ -382,16 +768,19 @@ func (m *Manager) ensurePassive() {
 	if len(m.cfg.Failover.Passive.Hooks.Post) > 0 {
 		m.logger.Debug("running post-passive hooks")
 		m.cfg.Failover.Passive.Hooks.RunPost(config.HooksRunOptions{
+			Ctx:          commandCtx,
 			DryRun:       m.cfg.Failover.DryRun,
 			LoggerPrefix: m.logPrefix,
 			LoggerArgs: []any{
 				"failover_stage", "post-passive",
 			},
+			OnHookRun: m.recordHookRun,
+			Runner:    m.commandRunner,
 		})
 	}
 
 	// check to ensure the call to the failover.passive.command was successful
-	if m.isNotSelfPassive() {
+	if !m.hasPassiveIdentity(ctx) {
 		m.logger.Error("we are not passive as reported by local rpc - unable to become active in failover",
 			"passive_pubkey", passivePubkey,
 		)
@@ -399,6 +788,9 @@ func (m *Manager) ensurePassive() {
 	}
 
 	m.logger.Debug("we are confirmed to be passive as reported by local rpc", "passive_pubkey", passivePubkey)
+	if wasActive {
+		m.recordTransition(constants.RoleNamePassive)
+	}
 
 	// refresh gossip state to warn if we are in gossip but not passive
 	m.gossipState.Refresh()
@@ -410,7 +802,7 @@ func (m *Manager) ensurePassive() {
 	}
 
 	// if we are in gossip but not passive, show error - failover.passive.command has likely fucked up
-	if m.isNotSelfPassive() {
+	if !m.hasPassiveIdentity(ctx) {
 		m.logger.Error("we are in gossip but not passive - this should not happen check failover.passive.command logic", "passive_pubkey", passivePubkey)
 		return
 	}
@@ -424,8 +816,9 @@ func (m *Manager) ensurePassive() {
 // and the failover.passive.command simply retsarts the validator service
 func (m *Manager) ensureActive() {
 	var err error
-	activePubkey := m.cfg.Validator.Identities.ActiveKeyPair.PublicKey().String()
+	activePubkey := m.cfg.Validator.Identities.ActivePublicKey().String()
 	m.logger.Info("becoming active", "pubkey", activePubkey)
+	wasActive := m.isSelfActive()
 
 	// Update failover status in cache
 	state := m.cache.GetState()
@@ -436,11 +829,14 @@ func (m *Manager) ensureActive() {
 	if len(m.cfg.Failover.Active.Hooks.Pre) > 0 {
 		m.logger.Debug("running pre-active hooks")
 		err = m.cfg.Failover.Active.Hooks.RunPre(config.HooksRunOptions{
+			Ctx:          m.commandCtx,
 			DryRun:       m.cfg.Failover.DryRun,
 			LoggerPrefix: m.logPrefix,
 			LoggerArgs: []any{
 				"failover_stage", "pre-active",
 			},
+			OnHookRun: m.recordHookRun,
+			Runner:    m.commandRunner,
 		})
 	}
 	if err != nil {
@@ -450,14 +846,18 @@ func (m *Manager) ensureActive() {
 
 	// run active command
 	m.logger.Debug("running active command")
+	started := m.clock.Now()
 	err = m.cfg.Failover.Active.RunCommand(config.RoleCommandRunOptions{
+		Ctx:          m.commandCtx,
 		DryRun:       m.cfg.Failover.DryRun,
 		LoggerPrefix: m.logPrefix,
 		LoggerArgs: []any{
 			"failover_stage", constants.RoleNameActive,
 			"active_pubkey", activePubkey,
 		},
+		Runner: m.commandRunner,
 	})
+	m.recordCommand(constants.RoleNameActive, m.cfg.Failover.Active.Command, m.cfg.Failover.Active.Args, m.clock.Since(started), err)
 	if err != nil {
 		m.logger.Warn("failed to run active command", "error", err)
 		return
@@ -467,11 +867,14 @@ func (m *Manager) ensureActive() {
 	if len(m.cfg.Failover.Active.Hooks.Post) > 0 {
 		m.logger.Debug("running post-active hooks")
 		m.cfg.Failover.Active.Hooks.RunPost(config.HooksRunOptions{
+			Ctx:          m.commandCtx,
 			DryRun:       m.cfg.Failover.DryRun,
 			LoggerPrefix: m.logPrefix,
 			LoggerArgs: []any{
 				"failover_stage", "post-active",
 			},
+			OnHookRun: m.recordHookRun,
+			Runner:    m.commandRunner,
 		})
 	}
 
@@ -484,6 +887,17 @@ func (m *Manager) ensureActive() {
 	}
 
 	m.logger.Info("we are confirmed to be active", "active_pubkey", activePubkey)
+	if !wasActive {
+		m.recordTransition(constants.RoleNameActive)
+	}
+}
+
+// passiveOutcome returns the journal outcome of running the passive command, checking local rpc within ctx
+func (m *Manager) passiveOutcome(ctx context.Context) (outcome, reason string) {
+	if !m.cfg.Failover.DryRun && !m.hasPassiveIdentity(ctx) {
+		return journal.OutcomeFailure, "not passive as reported by local rpc after running passive command"
+	}
+	return journal.OutcomeSuccess, ""
 }
 
 // isSelfHealthy checks if the validator is healthy by calling the local RPC client
@@ -511,24 +925,39 @@ func (m *Manager) isSelfUnhealthy() (isUnhealthy bool) {
 
 // isSelfActive checks if the validator is active by checking the local RPC client getIdentity response to confirm it is the active identity
 func (m *Manager) isSelfActive() (isActive bool) {
-	identity, err := m.localRPC.GetIdentity(m.ctx)
+	return m.hasActiveIdentity(m.ctx)
+}
+
+// hasActiveIdentity is isSelfActive with the local RPC call made within ctx
+func (m *Manager) hasActiveIdentity(ctx context.Context) bool {
+	// witnesses have no validator to be active
+	if m.isWitness() {
+		return false
+	}
+
+	identity, err := m.localRPC.GetIdentity(ctx)
 	if err != nil {
 		m.logger.Error(err.Error())
 		return false
 	}
 
-	return identity.Identity.String() == m.cfg.Validator.Identities.ActiveKeyPair.PublicKey().String()
+	return identity.Identity.String() == m.cfg.Validator.Identities.ActivePublicKey().String()
 }
 
 // isSelfPassive checks if the validator is passive by checking the local RPC client getIdentity response to confirm it is not the active identity
 func (m *Manager) isSelfPassive() bool {
-	identity, err := m.localRPC.GetIdentity(m.ctx)
+	return m.hasPassiveIdentity(m.ctx)
+}
+
+// hasPassiveIdentity is isSelfPassive with the local RPC call made within ctx
+func (m *Manager) hasPassiveIdentity(ctx context.Context) bool {
+	identity, err := m.localRPC.GetIdentity(ctx)
 	if err != nil {
 		m.logger.Error(err.Error())
 		return false
 	}
 
-	return identity.Identity.String() != m.cfg.Validator.Identities.ActiveKeyPair.PublicKey().String()
+	return identity.Identity.String() != m.cfg.Validator.Identities.ActivePublicKey().String()
 }
 
 // isNotSelfPassive checks if the validator is not passive by checking the local RPC client getIdentity response to confirm it is not the active identity
@@ -538,7 +967,7 @@ func (m *Manager) isNotSelfPassive() (isNotPassive bool) {
 
 // isSelfInGossip checks if the validator is in the gossip state
 func (m *Manager) isSelfInGossip() (isInGossip bool) {
-	return m.gossipState.HasIP(m.peerSelf.IP)
+	return m.gossipState.Snapshot().HasIP(m.peerSelf.IP)
 }
 
 // isSelfNotInGossip checks if the validator is not in the gossip state
@@ -548,7 +977,7 @@ func (m *Manager) isSelfNotInGossip() (isNotInGossip bool) {
 
 // selfGossipPubkey returns the pubkey of the validator in gossip
 func (m *Manager) selfGossipPubkey() (pubkey string) {
-	for _, peer := range m.gossipState.GetPeerStates() {
+	for _, peer := range m.gossipState.Snapshot().PeerStates {
 		if peer.IP == m.peerSelf.IP {
 			return peer.Pubkey
 		}
@@ -562,7 +991,9 @@ func (m *Manager) refreshMetrics() {
 
 	// Determine role and status
 	var role, status string
-	if m.isSelfActive() {
+	if m.isWitness() {
+		role = constants.RoleNameWitness
+	} else if m.isSelfActive() {
 		role = constants.RoleNameActive
 	} else if m.isSelfPassive() {
 		role = constants.RoleNamePassive
@@ -570,25 +1001,78 @@ func (m *Manager) refreshMetrics() {
 		role = constants.RoleNameUnknown
 	}
 
-	if m.isSelfHealthy() {
+	// witnesses have no validator whose health to check
+	if m.isWitness() || m.isSelfHealthy() {
 		status = constants.StatusHealthy
 	} else {
 		status = constants.StatusUnhealthy
 	}
 
 	// Get peer count and self in gossip status
-	peerCount := len(m.gossipState.GetPeerStates())
-	selfInGossip := m.gossipState.HasIP(m.peerSelf.IP)
+	m.pruneTransitions()
+	gossipSnapshot := m.gossipState.Snapshot()
+	peerCount := len(gossipSnapshot.PeerStates)
+	selfInGossip := gossipSnapshot.HasIP(m.peerSelf.IP)
+	maintenanceMode := m.isInMaintenance()
+
+	var clusterRPCLeaderless map[string]bool
+	for _, endpointView := range gossipSnapshot.EndpointViews {
+		if endpointView.Err != nil {
+			continue
+		}
+		if clusterRPCLeaderless == nil {
+			clusterRPCLeaderless = make(map[string]bool)
+		}
+		clusterRPCLeaderless[endpointView.Endpoint] = endpointView.Leaderless
+	}
+
+	peerStats := make(map[string]cache.PeerStats, len(gossipSnapshot.PeerStats))
+	for peerName, stats := range gossipSnapshot.PeerStats {
+		peerStats[peerName] = cache.PeerStats{
+			AvailabilityPercent: stats.AvailabilityPercent,
+			TransitionsPerHour:  stats.TransitionsPerHour,
+			Flapping:            stats.Flapping,
+		}
+	}
+
+	// every other config peer is exported, so that a peer nothing is spoofing reads zero
+	peerUnexpectedIdentities := make(map[string]int, len(m.cfg.Failover.Peers))
+	for peerName := range m.cfg.Failover.Peers {
+		if peerName != m.peerSelf.Name {
+			peerUnexpectedIdentities[peerName] = len(gossipSnapshot.UnexpectedIdentities[peerName])
+		}
+	}
 
 	// Update cache with current state
 	state := cache.State{
-		ValidatorName:  m.cfg.Validator.Name,
-		PublicIP:       m.peerSelf.IP,
-		Role:           role,
-		Status:         status,
-		PeerCount:      peerCount,
-		SelfInGossip:   selfInGossip,
-		FailoverStatus: constants.StatusIdle,
+		ValidatorName:      m.cfg.Validator.Name,
+		PublicIP:           m.peerSelf.IP,
+		Role:               role,
+		Status:             status,
+		PeerCount:          peerCount,
+		SelfInGossip:       selfInGossip,
+		FailoverStatus:     constants.StatusIdle,
+		MaintenanceMode:    maintenanceMode,
+		SplitBrainDetected: m.splitBrainDetected,
+
+		RoleTransitions:           len(m.transitions.Transitions),
+		RoleTransitionsTotal:      m.transitions.Total,
+		TransitionBudgetExhausted: m.isTransitionBudgetExhausted(),
+
+		LeaderSlotsAtRisk:     m.leaderSlotsAtRisk,
+		LeaderScheduleWaiting: m.leaderScheduleWaiting,
+
+		DoubleVoteGuardTripped: m.activeIdentityVoting,
+
+		ClusterRPCLeaderless:   clusterRPCLeaderless,
+		ClusterRPCDisagreement: gossipSnapshot.EndpointsDisagree(),
+
+		PeerVoteLagSlots: gossipSnapshot.VoteLagSlots,
+		PeerStats:        peerStats,
+
+		PeerUnexpectedIdentities: peerUnexpectedIdentities,
+
+		AccountBalances: m.accountBalances(),
 	}
 
 	m.cache.UpdateState(state)
@@ -601,17 +1085,19 @@ func (m *Manager) refreshMetrics() {
 		"status", status,
 		"peer_count", peerCount,
 		"self_in_gossip", selfInGossip,
+		"maintenance_mode", maintenanceMode,
 	)
 }
 
 // delayTakeover introduces a delay when there are multiple peers
-// to safeguard against multiple nodes trying to become active at the same time
-func (m *Manager) delayTakeover() {
+// to safeguard against multiple nodes trying to become active at the same time. It returns ctx's error
+// if ctx is done before the delay is over
+func (m *Manager) delayTakeover(ctx context.Context) error {
 	if m.peerCount <= 1 {
-		return
+		return nil
 	}
 
-	// get the peer rank - artificial ordering of peers by IP so that it is common across all nodes
+	// get the peer rank - ordering of peers by priority then IP so that it is common across all nodes
 	// running this function
 	selfPeerRank := len(m.cfg.Failover.Peers) + 1
 
@@ -628,11 +1114,16 @@ func (m *Manager) delayTakeover() {
 	jitterNanos := m.cfg.Failover.TakeoverJitterDuration.Nanoseconds()
 	if jitterNanos > 0 {
 		// rand.Int63n(n) returns [0, n), so we use n+1 to make it inclusive [0, n]
-		randomJitterNanos := rand.Int63n(jitterNanos + 1)
+		randomJitterNanos := m.rand.Int63n(jitterNanos + 1)
 		delay += time.Duration(randomJitterNanos)
 	}
 
 	m.logger.Debug("delaying takeover to avoid race conditions", "delay", delay, "self_peer_rank", selfPeerRank)
-	time.Sleep(delay)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-m.clock.After(delay):
+	}
 	m.logger.Debug("takeover delay complete", "self_peer_rank", selfPeerRank)
+	return nil
 }
@@ -7,6 +7,8 @@ import (
 
 	solanago "github.com/gagliardetto/solana-go"
 	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/journal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -959,3 +961,162 @@ func TestManager_EnsurePassive_WithDryRun(t *testing.T) {
 	state := manager.cache.GetState()
 	assert.Equal(t, "becoming_passive", state.FailoverStatus)
 }
+
+func TestManager_Stop(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Prometheus.Port = 9094
+
+	opts := NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	}
+
+	manager := NewManager(opts)
+
+	// Run the manager in a goroutine
+	done := make(chan error, 1)
+	go func() {
+		done <- manager.Run()
+	}()
+
+	// Let it run for a short time to ensure it starts properly
+	time.Sleep(100 * time.Millisecond)
+
+	// Stop the manager gracefully
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := manager.Stop(ctx)
+	assert.NoError(t, err)
+
+	// Run should return once stopped
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("manager did not stop within timeout")
+	}
+
+	// commands must be cancelled once stopped
+	assert.Error(t, manager.commandCtx.Err())
+}
+
+func TestManager_Stop_NotRunning(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Failover.OnShutdown = config.FailoverOnShutdownPassive
+
+	opts := NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	}
+
+	manager := NewManager(opts)
+
+	// Stop without Run should not block or attempt to step down
+	err := manager.Stop(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, manager.cache.GetState().FailoverStatus)
+}
+
+func TestManager_Stop_StepsDownWithinStopContext(t *testing.T) {
+	// we are active with nothing else claiming the active identity
+	manager := createStartupTestManager(t, config.FailoverOnStartupObserve, true)
+	manager.cfg.Failover.OnShutdown = config.FailoverOnShutdownPassive
+
+	// the manager contexts are cancelled first thing, the step-down runs within ours
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, manager.Stop(ctx))
+	assert.Equal(t, constants.StatusBecomingPassive, manager.cache.GetState().FailoverStatus)
+
+	events := readJournal(t, manager)
+	require.Len(t, events, 1)
+	assert.Equal(t, journal.EventTypeShutdown, events[0].Type)
+	assert.Equal(t, journal.OutcomeSuccess, events[0].Outcome)
+}
+
+func TestManager_Stop_StepDownRespectsStopContext(t *testing.T) {
+	manager := createStartupTestManager(t, config.FailoverOnStartupObserve, true)
+	manager.cfg.Failover.OnShutdown = config.FailoverOnShutdownPassive
+
+	// with no time left to shut down in, the step-down is not attempted
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	manager.Stop(ctx)
+	assert.Empty(t, manager.cache.GetState().FailoverStatus)
+	assert.Empty(t, readJournal(t, manager))
+}
+
+func TestManager_Stop_DuringStartup(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Failover.OnShutdown = config.FailoverOnShutdownPassive
+	cfg.Prometheus.Port = 9095
+
+	// hold startup in getting our public IP until we have asked to stop
+	release := make(chan struct{})
+	manager := NewManager(NewManagerOptions{
+		Cfg: cfg,
+		GetPublicIPFunc: func() (string, error) {
+			<-release
+			return mockPublicIPFunc()
+		},
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- manager.Run()
+	}()
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- manager.Stop(context.Background())
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	require.NoError(t, <-stopped)
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("manager did not stop within timeout")
+	}
+}
+
+func TestManager_Stop_WithOnShutdownPassive(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Failover.OnShutdown = config.FailoverOnShutdownPassive
+
+	opts := NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	}
+
+	manager := NewManager(opts)
+
+	// Initialize the manager
+	err := manager.initialize()
+	require.NoError(t, err)
+
+	// Stop - local rpc is unreachable so we are not active and must not step down
+	err = manager.Stop(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, manager.cache.GetState().FailoverStatus)
+}
+
+func TestManager_DelayTakeover_StopsWithContext(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Failover.TakeoverJitterDuration = time.Hour
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+
+	// stopping during the delay returns straight away instead of waiting it out
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	started := time.Now()
+	assert.ErrorIs(t, manager.delayTakeover(ctx), context.Canceled)
+	assert.Less(t, time.Since(started), 5*time.Second)
+}
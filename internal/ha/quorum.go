@@ -0,0 +1,126 @@
+package ha
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/api"
+)
+
+// hasTakeoverQuorum returns true if failover.takeover_quorum is disabled or a majority of all peers (us
+// included) vote for us taking over. We vote for ourselves first so that we never vote for another
+// candidate within the same lease window, which guarantees at most one candidate can win a majority
+func (m *Manager) hasTakeoverQuorum() bool {
+	if !m.cfg.Failover.TakeoverQuorum {
+		return true
+	}
+
+	required := len(m.cfg.Failover.Peers)/2 + 1
+	if err := m.grantVote(m.ctx, m.peerSelf.Name); err != nil {
+		m.logger.Warn("unable to vote for ourselves", "error", err)
+		return false
+	}
+
+	type voteResult struct {
+		name string
+		err  error
+	}
+
+	ctx, cancel := m.clock.WithTimeout(m.ctx, m.cfg.Failover.TakeoverVoteTimeoutDuration)
+	defer cancel()
+
+	results := make(chan voteResult, len(m.cfg.Failover.Peers))
+	requested := 0
+	for _, peer := range m.cfg.Failover.Peers {
+		if peer.Name == m.peerSelf.Name {
+			continue
+		}
+		requested++
+		go func() {
+			_, err := m.apiClient.Post(ctx, m.peerAPIURL(peer, api.PathVote), peer.Name, api.VoteRequest{Candidate: m.peerSelf.Name})
+			results <- voteResult{name: peer.Name, err: err}
+		}()
+	}
+
+	votes := 1
+	for range requested {
+		result := <-results
+		if result.err != nil {
+			m.logger.Warn("peer did not vote for us", "name", result.name, "error", result.err)
+			continue
+		}
+		m.logger.Debug("peer voted for us", "name", result.name)
+		votes++
+	}
+
+	m.logger.Info("takeover vote complete", "votes", votes, "required", required, "peers", len(m.cfg.Failover.Peers))
+	if votes < required {
+		// we are not taking over - free our vote for whichever candidate can
+		m.releaseVote(m.peerSelf.Name)
+		return false
+	}
+	return true
+}
+
+// grantVote records our vote for candidate to take over as active. It is refused while we are active or
+// if we voted for a different candidate within failover.takeover_vote_lease_duration. The lease runs from
+// the first vote for candidate and is not extended when it asks again, so a candidate cannot hold our vote
+// indefinitely. Whether we are active is checked within ctx, so that votes requested while we are shutting
+// down are still refused until we have stepped down
+func (m *Manager) grantVote(ctx context.Context, candidate string) error {
+	m.voteMu.Lock()
+	defer m.voteMu.Unlock()
+
+	if m.votedFor != "" && m.votedFor != candidate && m.clock.Since(m.votedAt) < m.cfg.Failover.TakeoverVoteLeaseDuration {
+		return fmt.Errorf("already voted for %s %s ago", m.votedFor, m.clock.Since(m.votedAt).Round(time.Second))
+	}
+
+	if candidate != m.peerSelf.Name && m.hasActiveIdentity(ctx) {
+		return fmt.Errorf("we are active")
+	}
+
+	if m.votedFor == candidate && m.clock.Since(m.votedAt) < m.cfg.Failover.TakeoverVoteLeaseDuration {
+		return nil
+	}
+
+	m.votedFor = candidate
+	m.votedAt = m.clock.Now()
+	return nil
+}
+
+// releaseVote withdraws our vote if it is held by candidate
+func (m *Manager) releaseVote(candidate string) {
+	m.voteMu.Lock()
+	defer m.voteMu.Unlock()
+
+	if m.votedFor == candidate {
+		m.votedFor = ""
+		m.votedAt = time.Time{}
+	}
+}
+
+// handleVote handles a request from a passive peer for our vote to take over as active
+func (m *Manager) handleVote(w http.ResponseWriter, r *http.Request, body []byte) {
+	var request api.VoteRequest
+	if err := json.Unmarshal(body, &request); err != nil || request.Candidate == "" {
+		api.WriteResponse(w, http.StatusBadRequest, api.Response{Message: "request body must be a JSON object with a non-empty \"candidate\" peer name"})
+		return
+	}
+
+	if _, ok := m.cfg.Failover.Peers[request.Candidate]; !ok || request.Candidate == m.peerSelf.Name {
+		api.WriteResponse(w, http.StatusBadRequest, api.Response{Message: fmt.Sprintf("candidate %s is not a peer", request.Candidate)})
+		return
+	}
+
+	if err := m.grantVote(r.Context(), request.Candidate); err != nil {
+		m.logger.Warn("refused takeover vote", "candidate", request.Candidate, "reason", err)
+		api.WriteResponse(w, http.StatusConflict, api.Response{Message: fmt.Sprintf("vote refused: %s", err)})
+		return
+	}
+
+	m.logger.Info("granted takeover vote", "candidate", request.Candidate)
+	api.WriteResponse(w, http.StatusOK, api.Response{OK: true, Message: fmt.Sprintf("voted for %s", request.Candidate)})
+}
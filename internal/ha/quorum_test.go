@@ -0,0 +1,184 @@
+package ha
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startQuorumTestNodes starts in-process managers named node1..nodeN on loopback addresses 127.0.0.1..N,
+// each serving the HA API on the same port as it would in production
+func startQuorumTestNodes(t *testing.T, count int) []*Manager {
+	t.Helper()
+
+	activeKeyPair := createTestPrivateKey("active")
+	peers := config.Peers{}
+	for i := 1; i <= count; i++ {
+		peers.Add(config.Peer{Name: fmt.Sprintf("node%d", i), IP: fmt.Sprintf("127.0.0.%d", i)})
+	}
+
+	managers := []*Manager{}
+	for i := 1; i <= count; i++ {
+		cfg := createTestConfig()
+		cfg.Validator.Name = fmt.Sprintf("node%d", i)
+		cfg.Validator.Identities.ActiveKeyPair = activeKeyPair
+		cfg.Prometheus.Port = 9290
+		cfg.Failover.TakeoverQuorum = true
+		cfg.Failover.TakeoverVoteLeaseDuration = time.Minute
+		cfg.Failover.TakeoverVoteTimeoutDuration = 2 * time.Second
+
+		// every node's peers exclude itself
+		cfg.Failover.Peers = config.Peers{}
+		for name, peer := range peers {
+			if name != cfg.Validator.Name {
+				cfg.Failover.Peers.Add(peer)
+			}
+		}
+
+		selfIP := peers[cfg.Validator.Name].IP
+		manager := NewManager(NewManagerOptions{
+			Cfg:             cfg,
+			GetPublicIPFunc: func() (string, error) { return selfIP, nil },
+		})
+		require.NoError(t, manager.initialize())
+
+		mux := http.NewServeMux()
+		manager.registerAPIHandlers(mux)
+		listener, err := net.Listen("tcp", net.JoinHostPort(selfIP, "9291"))
+		if err != nil {
+			t.Skipf("unable to listen on %s: %v", selfIP, err)
+		}
+		server := &http.Server{Handler: mux}
+		go server.Serve(listener)
+		t.Cleanup(func() {
+			server.Shutdown(context.Background())
+			// pooled keep-alive connections to the old server would fail the next test's votes
+			http.DefaultTransport.(*http.Transport).CloseIdleConnections()
+		})
+
+		managers = append(managers, manager)
+	}
+
+	return managers
+}
+
+func TestManager_HasTakeoverQuorum_Disabled(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Failover.TakeoverQuorum = false
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+
+	assert.True(t, manager.hasTakeoverQuorum())
+	assert.Empty(t, manager.votedFor)
+}
+
+func TestManager_HasTakeoverQuorum_SingleWinner(t *testing.T) {
+	nodes := startQuorumTestNodes(t, 3)
+
+	// the first candidate wins every vote
+	assert.True(t, nodes[0].hasTakeoverQuorum())
+	for _, node := range nodes {
+		assert.Equal(t, "node1", node.votedFor)
+	}
+
+	// a competing candidate within the lease only has its own vote - node1 and node3 refuse
+	assert.False(t, nodes[1].hasTakeoverQuorum())
+
+	// the winner may ask again within its lease
+	assert.True(t, nodes[0].hasTakeoverQuorum())
+}
+
+func TestManager_HasTakeoverQuorum_Majority(t *testing.T) {
+	nodes := startQuorumTestNodes(t, 3)
+
+	// node3 already voted for node2 - node1 still wins with node2's vote as node2 is not a candidate yet
+	require.NoError(t, nodes[2].grantVote(context.Background(), "node2"))
+	assert.True(t, nodes[0].hasTakeoverQuorum())
+	assert.Equal(t, "node1", nodes[1].votedFor)
+	assert.Equal(t, "node2", nodes[2].votedFor)
+}
+
+func TestManager_HasTakeoverQuorum_OverlappingCandidates(t *testing.T) {
+	nodes := startQuorumTestNodes(t, 3)
+
+	// node2 and node3 stand at the same time - node1's vote decides between them
+	results := make([]bool, len(nodes))
+	var wg sync.WaitGroup
+	for _, i := range []int{1, 2} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = nodes[i].hasTakeoverQuorum()
+		}()
+	}
+	wg.Wait()
+
+	require.NotEqual(t, results[1], results[2], "exactly one candidate wins")
+	winner, loser := nodes[1], nodes[2]
+	if results[2] {
+		winner, loser = nodes[2], nodes[1]
+	}
+	assert.Equal(t, winner.cfg.Validator.Name, nodes[0].votedFor)
+	assert.Equal(t, winner.cfg.Validator.Name, winner.votedFor)
+
+	// the loser does not hold on to its own vote so the winner can have it
+	assert.NotEqual(t, loser.cfg.Validator.Name, loser.votedFor)
+	assert.True(t, winner.hasTakeoverQuorum())
+	assert.Equal(t, winner.cfg.Validator.Name, loser.votedFor)
+}
+
+func TestManager_HasTakeoverQuorum_PeersUnreachable(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Failover.TakeoverQuorum = true
+	cfg.Failover.TakeoverVoteTimeoutDuration = 500 * time.Millisecond
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+
+	// only our own vote out of 3, which is then released
+	assert.False(t, manager.hasTakeoverQuorum())
+	assert.Empty(t, manager.votedFor)
+}
+
+func TestManager_GrantVote_Lease(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Failover.TakeoverVoteLeaseDuration = time.Minute
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+
+	require.NoError(t, manager.grantVote(context.Background(), "peer1"))
+
+	// asking again within the lease does not extend it
+	votedAt := time.Now().Add(-30 * time.Second)
+	manager.votedAt = votedAt
+	require.NoError(t, manager.grantVote(context.Background(), "peer1"))
+	assert.Equal(t, votedAt, manager.votedAt)
+
+	err := manager.grantVote(context.Background(), "peer2")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "already voted for peer1")
+
+	// once the lease expires another candidate can be voted for
+	manager.votedAt = time.Now().Add(-2 * time.Minute)
+	assert.NoError(t, manager.grantVote(context.Background(), "peer2"))
+	assert.Equal(t, "peer2", manager.votedFor)
+}
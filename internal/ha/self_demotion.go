@@ -0,0 +1,96 @@
+package ha
+
+import (
+	"sort"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/journal"
+)
+
+// demoteIfUnhealthyActive steps us down when we are active and have been locally unhealthy for
+// failover.active_unhealthy_samples_threshold consecutive samples, provided gossip shows at least one
+// passive peer that can take over. Passive peers are then asked to take over in rank order so that the
+// handover does not have to wait for us to become delinquent and the cluster leaderless
+func (m *Manager) demoteIfUnhealthyActive() (demoted bool) {
+	threshold := m.cfg.Failover.ActiveUnhealthySamplesThreshold
+	if threshold <= 0 {
+		return false
+	}
+
+	if !m.isSelfActive() || m.isSelfHealthy() {
+		m.activeUnhealthySamplesCount = 0
+		m.clearRecurringEvent(journal.EventTypeSelfDemotion)
+		return false
+	}
+
+	m.activeUnhealthySamplesCount++
+	m.logger.Warn("we are active but unhealthy",
+		"active_unhealthy_samples_count", m.activeUnhealthySamplesCount,
+		"active_unhealthy_samples_threshold", threshold,
+	)
+
+	if m.activeUnhealthySamplesCount < threshold {
+		return false
+	}
+
+	m.beginEvent(journal.EventTypeSelfDemotion)
+
+	// only step down if someone can take over - an unhealthy leader beats no leader
+	passivePeers := m.gossipState.Snapshot().GetPassivePeers(m.peerSelf.IP)
+	if len(passivePeers) == 0 {
+		m.logger.Error("we are active and unhealthy but no passive peers are in gossip to take over - remaining active")
+		m.endRecurringEvent(journal.OutcomeRefused, "no passive peers in gossip to take over")
+		return false
+	}
+
+	// flap damping - an unhealthy active is better than flapping
+	if err := m.checkTransitionAllowed(); err != nil {
+		m.logger.Error("we are active and unhealthy but refusing to step down", "reason", err)
+		m.endRecurringEvent(journal.OutcomeRefused, err.Error())
+		return false
+	}
+
+	m.logger.Error("we are active and have been unhealthy for too long - stepping down",
+		"active_unhealthy_samples_count", m.activeUnhealthySamplesCount,
+		"passive_peers_in_gossip", len(passivePeers),
+	)
+	m.activeUnhealthySamplesCount = 0
+	m.ensurePassive()
+
+	if m.isNotSelfPassive() {
+		m.logger.Error("failed to step down - still not passive as reported by local rpc")
+		m.endRecurringEvent(journal.OutcomeFailure, "not passive as reported by local rpc after running passive command")
+		return false
+	}
+
+	// hand over to the first passive peer that accepts, in rank order - if none do the regular
+	// leaderless failover will have a passive peer take over
+	rankedIPs := m.cfg.Failover.Peers.GetRankedIPs()
+	sort.Slice(passivePeers, func(i, j int) bool {
+		return rankedIPs[passivePeers[i].IP] < rankedIPs[passivePeers[j].IP]
+	})
+
+	for _, passivePeer := range passivePeers {
+		peer, ok := m.cfg.Failover.Peers[passivePeer.Name]
+		if !ok {
+			continue
+		}
+
+		ctx, cancel := m.clock.WithTimeout(m.ctx, m.cfg.Failover.SwitchoverTimeoutDuration)
+		err := m.promotePeer(ctx, peer)
+		cancel()
+		if err != nil {
+			m.logger.Warn("passive peer declined to take over", "name", peer.Name, "ip", peer.IP, "error", err)
+			continue
+		}
+
+		m.logger.Info("handed over to passive peer", "name", peer.Name, "ip", peer.IP)
+		m.setEventDetail("handed over to %s", peer.Name)
+		m.endRecurringEvent(journal.OutcomeSuccess, "")
+		return true
+	}
+
+	// we have still stepped down, but the handover failed
+	m.logger.Warn("no passive peer accepted the handover - leaving it to leaderless failover")
+	m.endRecurringEvent(journal.OutcomeFailure, "no passive peer accepted the handover")
+	return true
+}
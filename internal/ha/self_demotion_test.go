@@ -0,0 +1,178 @@
+package ha
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/api"
+	"github.com/sol-strategies/solana-validator-ha/internal/journal"
+	"github.com/sol-strategies/solana-validator-ha/internal/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_DemoteIfUnhealthyActive_Disabled(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Failover.ActiveUnhealthySamplesThreshold = 0
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+
+	assert.False(t, manager.demoteIfUnhealthyActive())
+	assert.Equal(t, 0, manager.activeUnhealthySamplesCount)
+	assert.Empty(t, manager.cache.GetState().FailoverStatus)
+}
+
+func TestManager_DemoteIfUnhealthyActive_NotActive(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Failover.ActiveUnhealthySamplesThreshold = 1
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+
+	// local rpc is unreachable so we are not active - the count resets and nothing is demoted
+	manager.activeUnhealthySamplesCount = 5
+	assert.False(t, manager.demoteIfUnhealthyActive())
+	assert.Equal(t, 0, manager.activeUnhealthySamplesCount)
+	assert.Empty(t, manager.cache.GetState().FailoverStatus)
+}
+
+// createSelfDemotionTestManager returns an initialized manager - not in dry run - that is active and
+// unhealthy until runner runs the passive command, with peer1 and peer2 passive in gossip until one of
+// them accepts a promotion through peers. peer2 ranks first
+func createSelfDemotionTestManager(t *testing.T, runner *fenceTestRunner, peers func(activePubkey string, promoted *atomic.Value) peerAPITestTransport) *Manager {
+	t.Helper()
+
+	cfg := createTestConfig()
+	cfg.Failover.DryRun = false
+	cfg.Failover.StateDir = t.TempDir()
+	cfg.Failover.ActiveUnhealthySamplesThreshold = 1
+	cfg.Failover.PollIntervalDuration = 10 * time.Millisecond
+	cfg.Failover.SwitchoverTimeoutDuration = time.Second
+	peer2 := cfg.Failover.Peers["peer2"]
+	peer2.Priority = 1
+	cfg.Failover.Peers["peer2"] = peer2
+	activePubkey := cfg.Validator.Identities.ActivePublicKey().String()
+	passivePubkey := cfg.Validator.Identities.PassiveKeyPair.PublicKey().String()
+
+	// the name of the peer that accepted a promotion
+	promoted := &atomic.Value{}
+	promoted.Store("")
+
+	localServer := mockRPCServer(t, map[string]func() any{
+		"getIdentity": func() any {
+			if runner.ranCommand(cfg.Failover.Passive.Command) {
+				return map[string]any{"identity": passivePubkey}
+			}
+			return map[string]any{"identity": activePubkey}
+		},
+		"getHealth": func() any { return "behind" },
+	})
+	clusterServer := mockRPCServer(t, map[string]func() any{
+		"getClusterNodes": func() any {
+			nodes := []map[string]any{}
+			for _, name := range []string{"peer1", "peer2"} {
+				pubkey := createTestPrivateKey(name + "-passive").PublicKey().String()
+				if promoted.Load() == name {
+					pubkey = activePubkey
+				}
+				nodes = append(nodes, map[string]any{"pubkey": pubkey, "gossip": cfg.Failover.Peers[name].IP + ":8001"})
+			}
+			return nodes
+		},
+		"getSlot":         func() any { return 100 },
+		"getVoteAccounts": func() any { return map[string]any{"current": []any{}, "delinquent": []any{}} },
+	})
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+		LocalRPC:        rpc.NewClient("local", localServer.URL),
+		ClusterRPC:      rpc.NewClient("cluster", clusterServer.URL),
+		CommandRunner:   runner,
+		PeerTransport:   peers(activePubkey, promoted),
+		GossipDialFunc: func(network, address string) (net.Conn, error) {
+			conn, _ := net.Pipe()
+			return conn, nil
+		},
+	})
+	require.NoError(t, manager.initialize())
+	manager.gossipState.Refresh()
+
+	return manager
+}
+
+// promotionTestHandler answers promotion requests for the named peer, accepting them by reporting
+// activePubkey if accept is set
+func promotionTestHandler(t *testing.T, name string, accept bool, activePubkey string, promoted *atomic.Value, requests *atomic.Int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		assert.True(t, strings.HasSuffix(r.URL.Path, api.PathPromote))
+		if !accept {
+			api.WriteResponse(w, http.StatusConflict, api.Response{Message: "we are not healthy"})
+			return
+		}
+		promoted.Store(name)
+		api.WriteResponse(w, http.StatusOK, api.Response{
+			OK:       true,
+			Identity: activePubkey,
+		})
+	}
+}
+
+func TestManager_DemoteIfUnhealthyActive_HandsOverToRankedPeer(t *testing.T) {
+	runner := &fenceTestRunner{}
+	var peer1Requests, peer2Requests atomic.Int32
+	manager := createSelfDemotionTestManager(t, runner, func(activePubkey string, promoted *atomic.Value) peerAPITestTransport {
+		return peerAPITestTransport{
+			"192.168.1.101": promotionTestHandler(t, "peer1", true, activePubkey, promoted, &peer1Requests),
+			"192.168.1.102": promotionTestHandler(t, "peer2", true, activePubkey, promoted, &peer2Requests),
+		}
+	})
+
+	assert.True(t, manager.demoteIfUnhealthyActive())
+	assert.True(t, runner.ranCommand(manager.cfg.Failover.Passive.Command))
+
+	// peer2 ranks first so takes over without peer1 being asked
+	assert.Equal(t, int32(0), peer1Requests.Load())
+	assert.Equal(t, int32(1), peer2Requests.Load())
+
+	events := readJournal(t, manager)
+	require.Len(t, events, 1)
+	assert.Equal(t, journal.EventTypeSelfDemotion, events[0].Type)
+	assert.Equal(t, journal.OutcomeSuccess, events[0].Outcome)
+	assert.Equal(t, "handed over to peer2", events[0].Detail)
+}
+
+func TestManager_DemoteIfUnhealthyActive_AllPeersDecline(t *testing.T) {
+	runner := &fenceTestRunner{}
+	var peer1Requests, peer2Requests atomic.Int32
+	manager := createSelfDemotionTestManager(t, runner, func(activePubkey string, promoted *atomic.Value) peerAPITestTransport {
+		return peerAPITestTransport{
+			"192.168.1.101": promotionTestHandler(t, "peer1", false, activePubkey, promoted, &peer1Requests),
+			"192.168.1.102": promotionTestHandler(t, "peer2", false, activePubkey, promoted, &peer2Requests),
+		}
+	})
+
+	// we still step down and leave it to leaderless failover
+	assert.True(t, manager.demoteIfUnhealthyActive())
+	assert.True(t, runner.ranCommand(manager.cfg.Failover.Passive.Command))
+	assert.Equal(t, int32(1), peer1Requests.Load())
+	assert.Equal(t, int32(1), peer2Requests.Load())
+
+	events := readJournal(t, manager)
+	require.Len(t, events, 1)
+	assert.Equal(t, journal.EventTypeSelfDemotion, events[0].Type)
+	assert.Equal(t, journal.OutcomeFailure, events[0].Outcome)
+	assert.Equal(t, "no passive peer accepted the handover", events[0].Reason)
+}
@@ -0,0 +1,96 @@
+package ha
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/api"
+	"github.com/sol-strategies/solana-validator-ha/internal/journal"
+)
+
+// detectSplitBrain returns the names of the peers (us included) claiming the active identity if more than
+// one has done so for failover.split_brain_samples_threshold consecutive samples. Claims are taken from
+// every gossip entry presenting the active identity plus our local getIdentity, since gossip may only
+// show one contact info per identity
+func (m *Manager) detectSplitBrain() (claimants []string) {
+	claimants = m.gossipState.Snapshot().ActivePeerNames
+	if m.isSelfActive() && !slices.Contains(claimants, m.peerSelf.Name) {
+		claimants = append(claimants, m.peerSelf.Name)
+	}
+
+	if len(claimants) < 2 {
+		if m.splitBrainSamplesCount > 0 {
+			m.logger.Info("split-brain cleared - a single peer claims the active identity")
+		}
+		m.splitBrainSamplesCount = 0
+		m.splitBrainDetected = false
+		return nil
+	}
+
+	m.splitBrainSamplesCount++
+	if m.splitBrainSamplesCount < m.cfg.Failover.SplitBrainSamplesThreshold {
+		m.logger.Warn("multiple peers claim the active identity - waiting for gossip to settle",
+			"claimants", claimants,
+			"split_brain_samples_count", m.splitBrainSamplesCount,
+			"split_brain_samples_threshold", m.cfg.Failover.SplitBrainSamplesThreshold,
+		)
+		return nil
+	}
+
+	m.splitBrainDetected = true
+	m.logger.Error("‼️ split-brain detected - multiple peers claim the active identity",
+		"claimants", claimants,
+		"split_brain_samples_count", m.splitBrainSamplesCount,
+	)
+	return claimants
+}
+
+// resolveSplitBrain deterministically resolves a split-brain: the claimant with the best peer rank stays
+// active and every other claimant is demoted - us with the passive command, peers via their HA API
+func (m *Manager) resolveSplitBrain(claimants []string) (resolved bool) {
+	if len(claimants) < 2 {
+		return false
+	}
+
+	rankedIPs := m.cfg.Failover.Peers.GetRankedIPs()
+	sort.Slice(claimants, func(i, j int) bool {
+		return rankedIPs[m.cfg.Failover.Peers[claimants[i]].IP] < rankedIPs[m.cfg.Failover.Peers[claimants[j]].IP]
+	})
+	winner := claimants[0]
+
+	// we lose - step down immediately, the winner carries on
+	if winner != m.peerSelf.Name {
+		if slices.Contains(claimants, m.peerSelf.Name) {
+			m.logger.Error("‼️ split-brain - we lost to a better ranked peer, becoming passive", "winner", winner, "claimants", claimants)
+			m.beginEvent(journal.EventTypeSplitBrain)
+			m.setEventDetail("lost to %s, claimants %s", winner, strings.Join(claimants, ", "))
+			m.ensurePassive()
+			m.endEvent(m.passiveOutcome(m.ctx))
+		}
+		return true
+	}
+
+	// we win - losers may not see the conflict themselves (gossip can show a single contact info per
+	// identity), so ask them to step down
+	m.beginEvent(journal.EventTypeSplitBrain)
+	m.setEventDetail("won, claimants %s", strings.Join(claimants, ", "))
+	var demoteErrs []error
+	for _, loser := range claimants[1:] {
+		peer := m.cfg.Failover.Peers[loser]
+		m.logger.Error("‼️ split-brain - we won, requesting peer to become passive", "loser", loser, "ip", peer.IP)
+
+		ctx, cancel := m.clock.WithTimeout(m.ctx, m.cfg.Failover.SwitchoverTimeoutDuration)
+		_, err := m.apiClient.Post(ctx, m.peerAPIURL(peer, api.PathDemote), peer.Name, nil)
+		cancel()
+		if err != nil {
+			m.logger.Error("failed to request peer to become passive", "loser", loser, "ip", peer.IP, "error", err)
+			demoteErrs = append(demoteErrs, fmt.Errorf("failed to request %s to become passive: %w", loser, err))
+		}
+	}
+	m.endEvent(outcomeFromError(errors.Join(demoteErrs...)))
+
+	return true
+}
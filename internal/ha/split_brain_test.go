@@ -0,0 +1,168 @@
+package ha
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/api"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/journal"
+	"github.com/sol-strategies/solana-validator-ha/internal/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_DetectSplitBrain_NoClaimants(t *testing.T) {
+	cfg := createTestConfig()
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+
+	// gossip is empty and local rpc is unreachable so nobody claims the active identity
+	manager.splitBrainSamplesCount = 2
+	manager.splitBrainDetected = true
+	assert.Nil(t, manager.detectSplitBrain())
+	assert.Equal(t, 0, manager.splitBrainSamplesCount)
+	assert.False(t, manager.splitBrainDetected)
+}
+
+func TestManager_ResolveSplitBrain_SingleClaimant(t *testing.T) {
+	cfg := createTestConfig()
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+
+	assert.False(t, manager.resolveSplitBrain(nil))
+	assert.False(t, manager.resolveSplitBrain([]string{"peer1"}))
+	assert.Empty(t, manager.cache.GetState().FailoverStatus)
+}
+
+func TestManager_ResolveSplitBrain_PeersOnly(t *testing.T) {
+	cfg := createTestConfig()
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+
+	// we are not a claimant and not the winner - nothing for us to do but the sample is handled
+	assert.True(t, manager.resolveSplitBrain([]string{"peer2", "peer1"}))
+	assert.Empty(t, manager.cache.GetState().FailoverStatus)
+}
+
+// createSplitBrainTestManager returns an initialized manager - not in dry run - that is active until runner
+// runs the passive command, with peers' HA APIs served by peers
+func createSplitBrainTestManager(t *testing.T, runner *fenceTestRunner, peers peerAPITestTransport, configure func(cfg *config.Config)) *Manager {
+	t.Helper()
+
+	cfg := createTestConfig()
+	cfg.Failover.DryRun = false
+	cfg.Failover.StateDir = t.TempDir()
+	cfg.Failover.SwitchoverTimeoutDuration = time.Second
+	if configure != nil {
+		configure(cfg)
+	}
+	activePubkey := cfg.Validator.Identities.ActivePublicKey().String()
+	passivePubkey := cfg.Validator.Identities.PassiveKeyPair.PublicKey().String()
+
+	localServer := mockRPCServer(t, map[string]func() any{
+		"getIdentity": func() any {
+			if runner.ranCommand(cfg.Failover.Passive.Command) {
+				return map[string]any{"identity": passivePubkey}
+			}
+			return map[string]any{"identity": activePubkey}
+		},
+	})
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+		LocalRPC:        rpc.NewClient("local", localServer.URL),
+		CommandRunner:   runner,
+		PeerTransport:   peers,
+	})
+	require.NoError(t, manager.initialize())
+
+	return manager
+}
+
+// demotionTestHandler answers demotion requests, counting them in requests
+func demotionTestHandler(t *testing.T, requests *atomic.Int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		assert.True(t, strings.HasSuffix(r.URL.Path, api.PathDemote))
+		api.WriteResponse(w, http.StatusOK, api.Response{OK: true, Message: "passive"})
+	}
+}
+
+func TestManager_ResolveSplitBrain_LoseStepsDown(t *testing.T) {
+	runner := &fenceTestRunner{}
+	var peer1Requests atomic.Int32
+	manager := createSplitBrainTestManager(t, runner, peerAPITestTransport{
+		"192.168.1.101": demotionTestHandler(t, &peer1Requests),
+	}, func(cfg *config.Config) {
+		// peer1 outranks us
+		peer1 := cfg.Failover.Peers["peer1"]
+		peer1.Priority = 1
+		cfg.Failover.Peers["peer1"] = peer1
+	})
+
+	assert.True(t, manager.resolveSplitBrain([]string{"test-validator", "peer1"}))
+	assert.True(t, runner.ranCommand(manager.cfg.Failover.Passive.Command))
+	assert.Equal(t, int32(0), peer1Requests.Load(), "the winner is not asked to step down")
+
+	events := readJournal(t, manager)
+	require.Len(t, events, 1)
+	assert.Equal(t, journal.EventTypeSplitBrain, events[0].Type)
+	assert.Equal(t, journal.OutcomeSuccess, events[0].Outcome)
+	assert.Equal(t, "lost to peer1, claimants peer1, test-validator", events[0].Detail)
+}
+
+func TestManager_ResolveSplitBrain_WinDemotesClaimants(t *testing.T) {
+	runner := &fenceTestRunner{}
+	var peer1Requests, peer2Requests atomic.Int32
+	manager := createSplitBrainTestManager(t, runner, peerAPITestTransport{
+		"192.168.1.101": demotionTestHandler(t, &peer1Requests),
+		"192.168.1.102": demotionTestHandler(t, &peer2Requests),
+	}, nil)
+
+	// we rank first by IP address
+	assert.True(t, manager.resolveSplitBrain([]string{"peer2", "test-validator", "peer1"}))
+	assert.False(t, runner.ranCommand(manager.cfg.Failover.Passive.Command))
+	assert.Equal(t, int32(1), peer1Requests.Load())
+	assert.Equal(t, int32(1), peer2Requests.Load())
+
+	events := readJournal(t, manager)
+	require.Len(t, events, 1)
+	assert.Equal(t, journal.EventTypeSplitBrain, events[0].Type)
+	assert.Equal(t, journal.OutcomeSuccess, events[0].Outcome)
+	assert.Equal(t, "won, claimants test-validator, peer1, peer2", events[0].Detail)
+}
+
+func TestManager_ResolveSplitBrain_WinDemotionFails(t *testing.T) {
+	runner := &fenceTestRunner{}
+	var peer1Requests atomic.Int32
+	manager := createSplitBrainTestManager(t, runner, peerAPITestTransport{
+		"192.168.1.101": demotionTestHandler(t, &peer1Requests),
+	}, nil)
+
+	// peer2 is unreachable
+	assert.True(t, manager.resolveSplitBrain([]string{"peer2", "test-validator", "peer1"}))
+	assert.False(t, runner.ranCommand(manager.cfg.Failover.Passive.Command))
+	assert.Equal(t, int32(1), peer1Requests.Load())
+
+	events := readJournal(t, manager)
+	require.Len(t, events, 1)
+	assert.Equal(t, journal.OutcomeFailure, events[0].Outcome)
+	assert.Contains(t, events[0].Reason, "failed to request peer2 to become passive")
+}
@@ -0,0 +1,87 @@
+package ha
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/journal"
+)
+
+// startup samples the cluster and reconciles our local identity with it as failover.on_startup says. It
+// returns false until the cluster RPC has returned gossip at least once - an empty gossip state says
+// nothing about who is active, so the HA state must not be evaluated from it
+func (m *Manager) startup() (started bool) {
+	m.transitionMu.Lock()
+	defer m.transitionMu.Unlock()
+
+	m.gossipState.Refresh()
+	if !m.gossipState.Snapshot().HasClusterSample() {
+		m.logger.Warn("no cluster sample yet - waiting for the cluster rpc before monitoring HA state",
+			"cluster_rpc_urls", m.cfg.Cluster.RPCURLs,
+		)
+		return false
+	}
+
+	// check for active peer in state and log if found
+	m.checkForActivePeer()
+
+	// witnesses have no local identity to reconcile
+	if !m.isWitness() {
+		m.reconcileOnStartup()
+	}
+	m.started = true
+	return true
+}
+
+// reconcileOnStartup compares our local identity with the active identity claimants in gossip and steps
+// down as failover.on_startup says - a validator restarted with the active identity while a peer took
+// over is a split-brain waiting to happen
+func (m *Manager) reconcileOnStartup() {
+	if !m.isSelfActive() {
+		m.logger.Debug("we are not active on startup - nothing to reconcile", "on_startup", m.cfg.Failover.OnStartup)
+		return
+	}
+
+	var peerClaimants []string
+	for _, name := range m.gossipState.Snapshot().ActivePeerNames {
+		if name != m.peerSelf.Name {
+			peerClaimants = append(peerClaimants, name)
+		}
+	}
+	slices.Sort(peerClaimants)
+
+	peerClaimantIPs := make([]string, 0, len(peerClaimants))
+	for _, name := range peerClaimants {
+		peerClaimantIPs = append(peerClaimantIPs, m.cfg.Failover.Peers[name].IP)
+	}
+
+	switch {
+	case m.cfg.Failover.OnStartup == config.FailoverOnStartupForcePassive:
+		m.logger.Warn("we are active on startup - stepping down to passive", "on_startup", m.cfg.Failover.OnStartup)
+		m.demoteOnStartup("we are active on startup")
+	case len(peerClaimants) == 0:
+		m.logger.Info("we are active on startup and no peer claims the active identity in gossip")
+	case m.cfg.Failover.OnStartup == config.FailoverOnStartupReconcile:
+		m.logger.Error("‼️ we are active on startup but gossip shows the active identity on peers - stepping down to passive",
+			"peers", peerClaimants,
+			"peer_ips", peerClaimantIPs,
+			"on_startup", m.cfg.Failover.OnStartup,
+		)
+		m.demoteOnStartup("gossip shows the active identity on %s (%s)", strings.Join(peerClaimants, ", "), strings.Join(peerClaimantIPs, ", "))
+	default:
+		m.logger.Error("‼️ we are active on startup but gossip shows the active identity on peers - observing only",
+			"peers", peerClaimants,
+			"peer_ips", peerClaimantIPs,
+			"on_startup", m.cfg.Failover.OnStartup,
+		)
+	}
+}
+
+// demoteOnStartup runs the passive command and journals why - callers must hold transitionMu
+func (m *Manager) demoteOnStartup(detailFormat string, args ...any) {
+	m.beginEvent(journal.EventTypeStartup)
+	m.setEventDetail(detailFormat, args...)
+	m.ensurePassive()
+	m.endEvent(m.passiveOutcome(m.ctx))
+}
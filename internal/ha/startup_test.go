@@ -0,0 +1,125 @@
+package ha
+
+import (
+	"net"
+	"testing"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/journal"
+	"github.com/sol-strategies/solana-validator-ha/internal/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createStartupTestManager returns an initialized manager whose local rpc reports the given identity and whose
+// cluster rpc shows the active identity in gossip on each of activeIPs
+func createStartupTestManager(t *testing.T, onStartup string, selfActive bool, activeIPs ...string) *Manager {
+	t.Helper()
+
+	cfg := createTestConfig()
+	cfg.Failover.OnStartup = onStartup
+	cfg.Failover.StateDir = t.TempDir()
+	activePubkey := cfg.Validator.Identities.ActiveKeyPair.PublicKey().String()
+
+	identity := cfg.Validator.Identities.PassiveKeyPair.PublicKey().String()
+	if selfActive {
+		identity = activePubkey
+	}
+
+	clusterNodes := []map[string]any{}
+	for _, ip := range activeIPs {
+		clusterNodes = append(clusterNodes, map[string]any{"pubkey": activePubkey, "gossip": ip + ":8001"})
+	}
+
+	server := mockRPCServer(t, map[string]func() any{
+		"getIdentity":     func() any { return map[string]any{"identity": identity} },
+		"getClusterNodes": func() any { return clusterNodes },
+		"getSlot":         func() any { return 100 },
+		"getVoteAccounts": func() any { return map[string]any{"current": []any{}, "delinquent": []any{}} },
+	})
+
+	rpcClient := rpc.NewClient("test", server.URL)
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+		LocalRPC:        rpcClient,
+		ClusterRPC:      rpcClient,
+		GossipDialFunc: func(network, address string) (net.Conn, error) {
+			conn, _ := net.Pipe()
+			return conn, nil
+		},
+	})
+	require.NoError(t, manager.initialize())
+
+	return manager
+}
+
+func TestManager_Startup_WaitsForClusterSample(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Cluster.RPCURLs = []string{"http://127.0.0.1:1"}
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+
+	assert.False(t, manager.startup())
+	assert.False(t, manager.started)
+
+	// steps do not evaluate the HA state until the startup policy has been applied
+	require.NoError(t, manager.Step())
+	assert.False(t, manager.started)
+	assert.Empty(t, manager.cache.GetState().FailoverStatus)
+}
+
+func TestManager_Startup_Reconcile(t *testing.T) {
+	// we are active but gossip shows the active identity on peer1
+	manager := createStartupTestManager(t, config.FailoverOnStartupReconcile, true, "192.168.1.101")
+
+	assert.True(t, manager.startup())
+	assert.True(t, manager.started)
+	assert.Equal(t, constants.StatusBecomingPassive, manager.cache.GetState().FailoverStatus)
+
+	events := readJournal(t, manager)
+	require.Len(t, events, 1)
+	assert.Equal(t, journal.EventTypeStartup, events[0].Type)
+	assert.Equal(t, "gossip shows the active identity on peer1 (192.168.1.101)", events[0].Detail)
+}
+
+func TestManager_Startup_ReconcileAgreesWithGossip(t *testing.T) {
+	// we are active and gossip shows the active identity on us only
+	manager := createStartupTestManager(t, config.FailoverOnStartupReconcile, true, "192.168.1.100")
+
+	assert.True(t, manager.startup())
+	assert.Empty(t, manager.cache.GetState().FailoverStatus)
+	assert.Empty(t, readJournal(t, manager))
+}
+
+func TestManager_Startup_Observe(t *testing.T) {
+	manager := createStartupTestManager(t, config.FailoverOnStartupObserve, true, "192.168.1.101")
+
+	assert.True(t, manager.startup())
+	assert.Empty(t, manager.cache.GetState().FailoverStatus)
+	assert.Empty(t, readJournal(t, manager))
+}
+
+func TestManager_Startup_ForcePassive(t *testing.T) {
+	// no peer claims the active identity but we step down regardless
+	manager := createStartupTestManager(t, config.FailoverOnStartupForcePassive, true)
+
+	assert.True(t, manager.startup())
+	assert.Equal(t, constants.StatusBecomingPassive, manager.cache.GetState().FailoverStatus)
+
+	events := readJournal(t, manager)
+	require.Len(t, events, 1)
+	assert.Equal(t, "we are active on startup", events[0].Detail)
+}
+
+func TestManager_Startup_Passive(t *testing.T) {
+	manager := createStartupTestManager(t, config.FailoverOnStartupForcePassive, false, "192.168.1.101")
+
+	assert.True(t, manager.startup())
+	assert.Empty(t, manager.cache.GetState().FailoverStatus)
+}
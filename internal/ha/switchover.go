@@ -0,0 +1,306 @@
+package ha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/api"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/journal"
+)
+
+// registerAPIHandlers registers the HA API endpoints - all of them require requests to be signed
+// with the shared active identity for us, and each request is accepted once only. The switchover and
+// maintenance endpoints are only ever called by the CLI on this host so are only served locally
+func (m *Manager) registerAPIHandlers(mux *http.ServeMux) {
+	verifier := api.NewVerifier(m.cfg.Validator.Identities.ActivePublicKey(), m.cfg.Validator.Name)
+	mux.HandleFunc(m.cfg.APIPath(api.PathVote), verifier.Authenticated(m.handleVote))
+
+	// witnesses have no validator to switch over, promote or demote
+	if m.isWitness() {
+		return
+	}
+
+	mux.HandleFunc(m.cfg.APIPath(api.PathSwitchover), api.LocalOnly(verifier.Authenticated(m.handleSwitchover)))
+	mux.HandleFunc(m.cfg.APIPath(api.PathPromote), verifier.Authenticated(m.handlePromote))
+	mux.HandleFunc(m.cfg.APIPath(api.PathDemote), verifier.Authenticated(m.handleDemote))
+	mux.HandleFunc(m.cfg.APIPath(api.PathMaintenance), api.LocalOnly(verifier.Authenticated(m.handleMaintenance)))
+}
+
+// handleSwitchover handles a request to hand the active role over to a named peer
+func (m *Manager) handleSwitchover(w http.ResponseWriter, r *http.Request, body []byte) {
+	var request api.SwitchoverRequest
+	if err := json.Unmarshal(body, &request); err != nil || request.To == "" {
+		api.WriteResponse(w, http.StatusBadRequest, api.Response{Message: "request body must be a JSON object with a non-empty \"to\" peer name"})
+		return
+	}
+
+	if err := m.Switchover(request.To); err != nil {
+		api.WriteResponse(w, http.StatusConflict, api.Response{Message: err.Error()})
+		return
+	}
+
+	api.WriteResponse(w, http.StatusOK, api.Response{
+		OK:      true,
+		Message: fmt.Sprintf("switchover to %s complete", request.To),
+	})
+}
+
+// handlePromote handles a request from the active peer for us to become active during a switchover
+func (m *Manager) handlePromote(w http.ResponseWriter, r *http.Request, body []byte) {
+	m.transitionMu.Lock()
+	defer m.transitionMu.Unlock()
+
+	if m.stopping.Load() {
+		api.WriteResponse(w, http.StatusServiceUnavailable, api.Response{Message: "shutting down"})
+		return
+	}
+
+	m.beginEvent(journal.EventTypePromote)
+	status, response := m.promote()
+	if response.OK {
+		m.endEvent(journal.OutcomeSuccess, "")
+	} else if status == http.StatusConflict {
+		m.endEvent(journal.OutcomeRefused, response.Message)
+	} else {
+		m.endEvent(journal.OutcomeFailure, response.Message)
+	}
+
+	api.WriteResponse(w, status, response)
+}
+
+// promote makes us active at the request of the active peer - callers must hold transitionMu
+func (m *Manager) promote() (status int, response api.Response) {
+	activePubkey := m.cfg.Validator.Identities.ActivePublicKey().String()
+
+	// idempotent - nothing to do if we are already active
+	if m.isSelfActive() {
+		m.setEventDetail("already active")
+		return http.StatusOK, api.Response{OK: true, Message: "already active", Identity: activePubkey}
+	}
+
+	// same preconditions as taking over in a failover
+	if m.isInMaintenance() {
+		return http.StatusConflict, api.Response{Message: "we are in maintenance mode - refusing to become active"}
+	}
+
+	if m.isSelfUnhealthy() {
+		return http.StatusConflict, api.Response{Message: "we are not healthy - refusing to become active"}
+	}
+
+	m.gossipState.Refresh()
+	if m.isSelfNotInGossip() {
+		return http.StatusConflict, api.Response{Message: "we do not appear in gossip - refusing to become active"}
+	}
+
+	m.logger.Warn("promotion requested by active peer for planned switchover")
+	m.ensureActive()
+
+	if !m.isSelfActive() {
+		return http.StatusInternalServerError, api.Response{Message: "not active as reported by local rpc after running active command"}
+	}
+
+	return http.StatusOK, api.Response{OK: true, Message: "active", Identity: activePubkey}
+}
+
+// handleDemote handles a request for us to become passive, used to roll back a failed switchover or
+// resolve a split-brain
+func (m *Manager) handleDemote(w http.ResponseWriter, r *http.Request, body []byte) {
+	m.transitionMu.Lock()
+	defer m.transitionMu.Unlock()
+
+	m.logger.Warn("demotion requested by peer")
+	m.beginEvent(journal.EventTypeDemote)
+	m.ensurePassive()
+
+	if m.isNotSelfPassive() {
+		m.endEvent(journal.OutcomeFailure, "not passive as reported by local rpc after running passive command")
+		api.WriteResponse(w, http.StatusInternalServerError, api.Response{Message: "not passive as reported by local rpc after running passive command"})
+		return
+	}
+
+	m.endEvent(journal.OutcomeSuccess, "")
+	api.WriteResponse(w, http.StatusOK, api.Response{
+		OK:       true,
+		Message:  "passive",
+		Identity: m.cfg.Validator.Identities.PassiveKeyPair.PublicKey().String(),
+	})
+}
+
+// Switchover performs a planned handover of the active role from us to the named peer. We are demoted
+// with the passive command, the target is asked to run its active command, and the handover is verified
+// in gossip. If the target never confirms within failover.switchover_timeout_duration we roll back
+// by demoting the target and becoming active again - see rollbackSwitchover.
+func (m *Manager) Switchover(to string) error {
+	m.transitionMu.Lock()
+	defer m.transitionMu.Unlock()
+
+	m.beginEvent(journal.EventTypeSwitchover)
+	m.setEventDetail("to %s", to)
+	err := m.switchover(to)
+	m.endEvent(outcomeFromError(err))
+	return err
+}
+
+// switchover performs a planned handover to the named peer - callers must hold transitionMu
+func (m *Manager) switchover(to string) error {
+	targetPeer, ok := m.cfg.Failover.Peers[to]
+	if !ok {
+		return refuse("peer %s not found in failover.peers", to)
+	}
+
+	if targetPeer.IP == m.peerSelf.IP {
+		return refuse("cannot switchover to ourselves (%s)", to)
+	}
+
+	if targetPeer.Witness {
+		return refuse("cannot switchover to witness %s - it has no validator", to)
+	}
+
+	// only the active peer may hand over the active role
+	if !m.isSelfActive() {
+		return refuse("we are not active - switchover must be run against the active peer")
+	}
+
+	// the target must be visible in gossip before we give anything up
+	m.gossipState.Refresh()
+	if !m.gossipState.Snapshot().HasIP(targetPeer.IP) {
+		return refuse("peer %s (%s) not found in gossip - refusing to switchover", to, targetPeer.IP)
+	}
+
+	// changing identity around our leader slots would skip blocks
+	if err := m.waitForLeaderSlotFreeWindow(); err != nil {
+		return refuse("refusing to switchover to %s: %w", to, err)
+	}
+
+	m.logger.Warn("starting switchover", "to", to, "to_ip", targetPeer.IP)
+
+	ctx, cancel := m.clock.WithTimeout(m.ctx, m.cfg.Failover.SwitchoverTimeoutDuration)
+	defer cancel()
+
+	// demote ourselves first so there is never more than one active identity voting
+	m.ensurePassive()
+	if m.isNotSelfPassive() {
+		return fmt.Errorf("failed to become passive - aborting switchover to %s", to)
+	}
+
+	// ask the target to take over and wait for it to appear as active in gossip
+	err := m.promotePeer(ctx, targetPeer)
+	if err == nil {
+		err = m.waitForActivePeer(ctx, targetPeer)
+	}
+
+	if err != nil {
+		m.logger.Error("switchover failed - rolling back", "to", to, "error", err)
+		rolledBack, rollbackErr := m.rollbackSwitchover(targetPeer)
+		if rollbackErr != nil {
+			m.logger.Error("‼️ unable to roll back switchover safely - staying passive, failover takes over from here",
+				"to", to, "error", rollbackErr,
+			)
+			return fmt.Errorf("switchover to %s failed and was not rolled back: %w", to, errors.Join(err, rollbackErr))
+		}
+		if rolledBack {
+			return fmt.Errorf("switchover to %s failed and was rolled back: %w", to, err)
+		}
+		m.logger.Warn("switchover completed despite error", "to", to, "error", err)
+		return nil
+	}
+
+	m.logger.Info("switchover complete", "to", to, "to_ip", targetPeer.IP)
+	return nil
+}
+
+// promotePeer asks peer to become active via its HA API
+func (m *Manager) promotePeer(ctx context.Context, peer config.Peer) error {
+	m.logger.Info("requesting peer to become active", "name", peer.Name, "ip", peer.IP)
+	response, err := m.apiClient.Post(ctx, m.peerAPIURL(peer, api.PathPromote), peer.Name, nil)
+	if err != nil {
+		return err
+	}
+
+	// the peer confirms with its local getIdentity
+	activePubkey := m.cfg.Validator.Identities.ActivePublicKey().String()
+	if response.Identity != activePubkey {
+		return fmt.Errorf("peer %s reported identity %q after promotion, expected %s", peer.Name, response.Identity, activePubkey)
+	}
+
+	return nil
+}
+
+// waitForActivePeer refreshes gossip every poll interval until peer is seen as the active peer or ctx is done
+func (m *Manager) waitForActivePeer(ctx context.Context, peer config.Peer) error {
+	for {
+		m.gossipState.Refresh()
+		if activePeer, err := m.gossipState.Snapshot().GetActivePeer(); err == nil && activePeer.Name == peer.Name {
+			return nil
+		}
+
+		m.logger.Debug("waiting for peer to appear as active in gossip", "name", peer.Name, "ip", peer.IP)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("peer %s not seen as active in gossip: %w", peer.Name, ctx.Err())
+		case <-m.clock.After(m.cfg.Failover.PollIntervalDuration):
+		}
+	}
+}
+
+// rollbackSwitchover restores us as active after a failed switchover. It returns false without rolling
+// back if the target turns out to have become active after all. The target may be part-way through
+// becoming active, so we only become active again once it confirms it is passive - or failing that, once
+// it is fenced and the active identity is confirmed not voting. Otherwise an error is returned and we stay
+// passive
+func (m *Manager) rollbackSwitchover(targetPeer config.Peer) (rolledBack bool, err error) {
+	m.gossipState.Refresh()
+	if activePeer, err := m.gossipState.Snapshot().GetActivePeer(); err == nil && activePeer.Name == targetPeer.Name {
+		m.logger.Warn("target peer is active in gossip - not rolling back", "name", targetPeer.Name)
+		return false, nil
+	}
+
+	if err := m.demoteSwitchoverTarget(targetPeer); err != nil {
+		m.logger.Error("target peer did not confirm it is passive - fencing it before rolling back",
+			"name", targetPeer.Name, "error", err,
+		)
+		if !m.cfg.Failover.Fencing.Enabled(m.cfg.Failover.Peers) {
+			return false, fmt.Errorf("target peer %s did not confirm it is passive and failover.fencing is not configured: %w", targetPeer.Name, err)
+		}
+		if err := m.fence(targetPeer, "switchover target peer"); err != nil {
+			return false, err
+		}
+		if err := m.checkActiveIdentityNotVoting(); err != nil {
+			return false, err
+		}
+	}
+
+	m.ensureActive()
+	return true, nil
+}
+
+// demoteSwitchoverTarget asks the target of a failed switchover to become passive, returning an error
+// unless it confirms with its local getIdentity that it no longer holds the active identity
+func (m *Manager) demoteSwitchoverTarget(targetPeer config.Peer) error {
+	ctx, cancel := m.clock.WithTimeout(m.ctx, m.cfg.Failover.SwitchoverTimeoutDuration)
+	defer cancel()
+
+	response, err := m.apiClient.Post(ctx, m.peerAPIURL(targetPeer, api.PathDemote), targetPeer.Name, nil)
+	if err != nil {
+		return err
+	}
+
+	if response.Identity == "" || response.Identity == m.cfg.Validator.Identities.ActivePublicKey().String() {
+		return fmt.Errorf("peer %s reported identity %q after demotion", targetPeer.Name, response.Identity)
+	}
+
+	return nil
+}
+
+// peerAPIURL returns the URL for path on peer's HA API - peers are expected to serve it on the same port and
+// under the same group as us
+func (m *Manager) peerAPIURL(peer config.Peer, path string) string {
+	host := net.JoinHostPort(peer.IP, strconv.Itoa(m.cfg.Prometheus.HealthCheckPort()))
+	return "http://" + host + m.cfg.APIPath(path)
+}
@@ -0,0 +1,271 @@
+package ha
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/api"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/journal"
+	"github.com/sol-strategies/solana-validator-ha/internal/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_Switchover_UnknownPeer(t *testing.T) {
+	cfg := createTestConfig()
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+
+	err := manager.Switchover("nope")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "peer nope not found in failover.peers")
+}
+
+func TestManager_Switchover_ToSelf(t *testing.T) {
+	cfg := createTestConfig()
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+
+	err := manager.Switchover("test-validator")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot switchover to ourselves")
+}
+
+func TestManager_Switchover_NotActive(t *testing.T) {
+	cfg := createTestConfig()
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+
+	// local rpc is unreachable so we can't be active - nothing must be demoted
+	err := manager.Switchover("peer1")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "we are not active")
+	assert.Empty(t, manager.cache.GetState().FailoverStatus)
+}
+
+func TestManager_PeerAPIURL(t *testing.T) {
+	cfg := createTestConfig()
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+
+	url := manager.peerAPIURL(config.Peer{Name: "peer1", IP: "192.168.1.101"}, api.PathPromote)
+	assert.Equal(t, "http://192.168.1.101:9091/v1/promote", url)
+
+	url = manager.peerAPIURL(config.Peer{Name: "peer1", IP: "2001:db8::101"}, api.PathPromote)
+	assert.Equal(t, "http://[2001:db8::101]:9091/v1/promote", url)
+}
+
+func TestManager_APIHandlers_RequireSignature(t *testing.T) {
+	cfg := createTestConfig()
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+
+	mux := http.NewServeMux()
+	manager.registerAPIHandlers(mux)
+
+	for _, path := range []string{api.PathSwitchover, api.PathPromote, api.PathDemote} {
+		request := httptest.NewRequest(http.MethodPost, path, nil)
+		request.RemoteAddr = "127.0.0.1:1234"
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, request)
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code, path)
+	}
+
+	// the switchover and maintenance endpoints are only served to the CLI on this host
+	for _, path := range []string{api.PathSwitchover, api.PathMaintenance} {
+		request := httptest.NewRequest(http.MethodPost, path, nil)
+		require.NoError(t, api.Sign(request, nil, *cfg.Validator.Identities.ActiveKeyPair, cfg.Validator.Name, time.Now()))
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, request)
+		assert.Equal(t, http.StatusForbidden, recorder.Code, path)
+	}
+
+	// a signed switchover request without a target is rejected
+	request := httptest.NewRequest(http.MethodPost, api.PathSwitchover, nil)
+	request.RemoteAddr = "127.0.0.1:1234"
+	require.NoError(t, api.Sign(request, nil, *cfg.Validator.Identities.ActiveKeyPair, cfg.Validator.Name, time.Now()))
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+// peerAPITestTransport serves requests to peers' HA APIs with the handler for the peer's IP - peers
+// without one are unreachable
+type peerAPITestTransport map[string]http.HandlerFunc
+
+func (t peerAPITestTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	handler, ok := t[request.URL.Hostname()]
+	if !ok {
+		return nil, fmt.Errorf("peer %s unreachable", request.URL.Host)
+	}
+	recorder := httptest.NewRecorder()
+	handler(recorder, request)
+	return recorder.Result(), nil
+}
+
+// ranCommand reports whether runner has run commandLine
+func (r *fenceTestRunner) ranCommand(commandLine string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, run := range r.runs {
+		if run.Command == commandLine {
+			return true
+		}
+	}
+	return false
+}
+
+// createRollbackTestManager returns an initialized passive manager - not in dry run - that has just failed
+// to switch over to peer1, whose HA API is served by peers and whose validator RPC (if peer1Identity is
+// set) reports the identity peer1Identity returns. The local rpc reports the active identity once runner
+// has run the active command
+func createRollbackTestManager(t *testing.T, runner *fenceTestRunner, peers peerAPITestTransport, peer1Identity func() string) *Manager {
+	t.Helper()
+
+	cfg := createTestConfig()
+	cfg.Failover.DryRun = false
+	cfg.Failover.StateDir = t.TempDir()
+	cfg.Failover.PollIntervalDuration = 10 * time.Millisecond
+	cfg.Failover.SwitchoverTimeoutDuration = time.Second
+	cfg.Failover.Fencing.TimeoutDuration = 100 * time.Millisecond
+	cfg.Failover.Fencing.OnTimeout = config.FencingOnTimeoutRefuse
+	activePubkey := cfg.Validator.Identities.ActivePublicKey().String()
+	passivePubkey := cfg.Validator.Identities.PassiveKeyPair.PublicKey().String()
+
+	if peer1Identity != nil {
+		peer1Server := mockRPCServer(t, map[string]func() any{
+			"getIdentity": func() any { return map[string]any{"identity": peer1Identity()} },
+		})
+		peer1 := cfg.Failover.Peers["peer1"]
+		peer1.RPCURL = peer1Server.URL
+		cfg.Failover.Peers["peer1"] = peer1
+	}
+
+	localServer := mockRPCServer(t, map[string]func() any{
+		"getIdentity": func() any {
+			if runner.ranCommand(cfg.Failover.Active.Command) {
+				return map[string]any{"identity": activePubkey}
+			}
+			return map[string]any{"identity": passivePubkey}
+		},
+	})
+	clusterServer := mockRPCServer(t, map[string]func() any{
+		"getClusterNodes": func() any { return []map[string]any{} },
+		"getSlot":         func() any { return 100 },
+		"getVoteAccounts": func() any { return map[string]any{"current": []any{}, "delinquent": []any{}} },
+	})
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+		LocalRPC:        rpc.NewClient("local", localServer.URL),
+		ClusterRPC:      rpc.NewClient("cluster", clusterServer.URL),
+		CommandRunner:   runner,
+		PeerTransport:   peers,
+		GossipDialFunc: func(network, address string) (net.Conn, error) {
+			conn, _ := net.Pipe()
+			return conn, nil
+		},
+	})
+	require.NoError(t, manager.initialize())
+	manager.beginEvent(journal.EventTypeSwitchover)
+
+	return manager
+}
+
+func TestManager_RollbackSwitchover_TargetConfirmsPassive(t *testing.T) {
+	runner := &fenceTestRunner{}
+	var manager *Manager
+	manager = createRollbackTestManager(t, runner, peerAPITestTransport{
+		"192.168.1.101": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, manager.cfg.APIPath(api.PathDemote), r.URL.Path)
+			api.WriteResponse(w, http.StatusOK, api.Response{
+				OK:       true,
+				Identity: createTestPrivateKey("peer1-passive").PublicKey().String(),
+			})
+		},
+	}, nil)
+
+	rolledBack, err := manager.rollbackSwitchover(manager.cfg.Failover.Peers["peer1"])
+	require.NoError(t, err)
+	assert.True(t, rolledBack)
+	assert.True(t, runner.ranCommand(manager.cfg.Failover.Active.Command))
+}
+
+func TestManager_RollbackSwitchover_TargetStillActive(t *testing.T) {
+	runner := &fenceTestRunner{}
+	var manager *Manager
+	manager = createRollbackTestManager(t, runner, peerAPITestTransport{
+		"192.168.1.101": func(w http.ResponseWriter, r *http.Request) {
+			api.WriteResponse(w, http.StatusOK, api.Response{
+				OK:       true,
+				Identity: manager.cfg.Validator.Identities.ActivePublicKey().String(),
+			})
+		},
+	}, nil)
+
+	rolledBack, err := manager.rollbackSwitchover(manager.cfg.Failover.Peers["peer1"])
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.fencing is not configured")
+	assert.False(t, rolledBack)
+	assert.False(t, runner.ranCommand(manager.cfg.Failover.Active.Command))
+}
+
+func TestManager_RollbackSwitchover_TargetUnreachableNoFencing(t *testing.T) {
+	runner := &fenceTestRunner{}
+	manager := createRollbackTestManager(t, runner, peerAPITestTransport{}, nil)
+
+	rolledBack, err := manager.rollbackSwitchover(manager.cfg.Failover.Peers["peer1"])
+	require.Error(t, err)
+	assert.False(t, rolledBack)
+	assert.False(t, runner.ranCommand(manager.cfg.Failover.Active.Command))
+}
+
+func TestManager_RollbackSwitchover_TargetUnreachableFenced(t *testing.T) {
+	runner := &fenceTestRunner{}
+	manager := createRollbackTestManager(t, runner, peerAPITestTransport{}, func() string {
+		return createTestPrivateKey("peer1-passive").PublicKey().String()
+	})
+
+	rolledBack, err := manager.rollbackSwitchover(manager.cfg.Failover.Peers["peer1"])
+	require.NoError(t, err)
+	assert.True(t, rolledBack)
+	assert.True(t, runner.ranCommand(manager.cfg.Failover.Active.Command))
+}
+
+func TestManager_RollbackSwitchover_TargetUnreachableNotFenced(t *testing.T) {
+	runner := &fenceTestRunner{}
+	var manager *Manager
+	manager = createRollbackTestManager(t, runner, peerAPITestTransport{}, func() string {
+		return manager.cfg.Validator.Identities.ActivePublicKey().String()
+	})
+
+	rolledBack, err := manager.rollbackSwitchover(manager.cfg.Failover.Peers["peer1"])
+	require.Error(t, err)
+	assert.False(t, rolledBack)
+	assert.False(t, runner.ranCommand(manager.cfg.Failover.Active.Command))
+}
@@ -0,0 +1,158 @@
+package ha
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// transitionsFileName is the file in failover.state_dir our role transitions are persisted to
+const transitionsFileName = "transitions.json"
+
+// roleTransitions is the record of our role changes persisted across restarts
+type roleTransitions struct {
+	// Total is the number of role changes ever recorded
+	Total int `json:"total"`
+	// Transitions are the role changes within failover.transition_window_duration, oldest first
+	Transitions []roleTransition `json:"transitions"`
+}
+
+// roleTransition is a single role change
+type roleTransition struct {
+	Role string    `json:"role"`
+	At   time.Time `json:"at"`
+}
+
+// recordTransition records a role change to role and persists it
+func (m *Manager) recordTransition(role string) {
+	m.transitions.Total++
+	m.transitions.Transitions = append(m.transitions.Transitions, roleTransition{Role: role, At: m.clock.Now()})
+	m.pruneTransitions()
+
+	m.logger.Info("role transition recorded",
+		"role", role,
+		"transitions_in_window", len(m.transitions.Transitions),
+		"max_transitions", m.cfg.Failover.MaxTransitions,
+		"transition_window", m.cfg.Failover.TransitionWindowDuration,
+	)
+
+	if err := m.saveTransitions(); err != nil {
+		m.logger.Error("failed to persist role transitions", "error", err)
+	}
+}
+
+// checkTransitionAllowed returns an error if an automatic role change is refused by flap damping - either
+// we changed role less than failover.transition_cooldown_duration ago or failover.max_transitions have
+// happened within failover.transition_window_duration. Exhausting the budget is alerted on once
+func (m *Manager) checkTransitionAllowed() error {
+	m.pruneTransitions()
+
+	transitionCount := len(m.transitions.Transitions)
+	if transitionCount > 0 && m.cfg.Failover.TransitionCooldownDuration > 0 {
+		lastTransition := m.transitions.Transitions[transitionCount-1]
+		if cooldownRemaining := m.cfg.Failover.TransitionCooldownDuration - m.clock.Since(lastTransition.At); cooldownRemaining > 0 {
+			return fmt.Errorf("last role change to %s was %s ago - in cooldown for another %s",
+				lastTransition.Role,
+				m.clock.Since(lastTransition.At).Round(time.Second),
+				cooldownRemaining.Round(time.Second),
+			)
+		}
+	}
+
+	if !m.isTransitionBudgetExhausted() {
+		m.transitionBudgetAlerted = false
+		return nil
+	}
+
+	if !m.transitionBudgetAlerted {
+		m.transitionBudgetAlerted = true
+		m.logger.Error("‼️ role transition budget exhausted - automatic role changes are refused, this node is flapping",
+			"transitions_in_window", transitionCount,
+			"max_transitions", m.cfg.Failover.MaxTransitions,
+			"transition_window", m.cfg.Failover.TransitionWindowDuration,
+		)
+	}
+
+	return fmt.Errorf("%d role changes within %s - max_transitions of %d reached",
+		transitionCount, m.cfg.Failover.TransitionWindowDuration, m.cfg.Failover.MaxTransitions)
+}
+
+// isTransitionBudgetExhausted returns true if failover.max_transitions role changes have happened
+// within failover.transition_window_duration
+func (m *Manager) isTransitionBudgetExhausted() bool {
+	return m.cfg.Failover.MaxTransitions > 0 && len(m.transitions.Transitions) >= m.cfg.Failover.MaxTransitions
+}
+
+// pruneTransitions forgets role changes older than failover.transition_window_duration
+func (m *Manager) pruneTransitions() {
+	windowStart := m.clock.Now().Add(-m.cfg.Failover.TransitionWindowDuration)
+	keepFrom := 0
+	for keepFrom < len(m.transitions.Transitions) && m.transitions.Transitions[keepFrom].At.Before(windowStart) {
+		keepFrom++
+	}
+	m.transitions.Transitions = m.transitions.Transitions[keepFrom:]
+}
+
+// transitionsFile returns the path role transitions are persisted to or empty if failover.state_dir is unset
+func (m *Manager) transitionsFile() string {
+	if m.cfg.Failover.StateDir == "" {
+		return ""
+	}
+	return filepath.Join(m.cfg.Failover.StateDir, transitionsFileName)
+}
+
+// loadTransitions loads persisted role transitions - a missing file is not an error
+func (m *Manager) loadTransitions() error {
+	transitionsFile := m.transitionsFile()
+	if transitionsFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(transitionsFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", transitionsFile, err)
+	}
+
+	var transitions roleTransitions
+	if err := json.Unmarshal(data, &transitions); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", transitionsFile, err)
+	}
+
+	m.transitions = transitions
+	m.pruneTransitions()
+	return nil
+}
+
+// saveTransitions atomically persists role transitions to failover.state_dir
+func (m *Manager) saveTransitions() error {
+	transitionsFile := m.transitionsFile()
+	if transitionsFile == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(m.transitions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal role transitions: %w", err)
+	}
+
+	if err := os.MkdirAll(m.cfg.Failover.StateDir, 0o750); err != nil {
+		return fmt.Errorf("failed to create failover.state_dir %s: %w", m.cfg.Failover.StateDir, err)
+	}
+
+	tmpFile := transitionsFile + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0o640); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmpFile, err)
+	}
+
+	if err := os.Rename(tmpFile, transitionsFile); err != nil {
+		return fmt.Errorf("failed to rename %s to %s: %w", tmpFile, transitionsFile, err)
+	}
+
+	return nil
+}
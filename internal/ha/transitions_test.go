@@ -0,0 +1,118 @@
+package ha
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_CheckTransitionAllowed_Budget(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Failover.MaxTransitions = 2
+	cfg.Failover.TransitionWindowDuration = time.Hour
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+
+	assert.NoError(t, manager.checkTransitionAllowed())
+
+	manager.recordTransition(constants.RoleNameActive)
+	assert.NoError(t, manager.checkTransitionAllowed())
+
+	manager.recordTransition(constants.RoleNamePassive)
+	err := manager.checkTransitionAllowed()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "max_transitions of 2 reached")
+	assert.True(t, manager.isTransitionBudgetExhausted())
+	assert.True(t, manager.transitionBudgetAlerted)
+
+	// transitions outside the window no longer count
+	for i := range manager.transitions.Transitions {
+		manager.transitions.Transitions[i].At = time.Now().Add(-2 * time.Hour)
+	}
+	assert.NoError(t, manager.checkTransitionAllowed())
+	assert.Empty(t, manager.transitions.Transitions)
+	assert.Equal(t, 2, manager.transitions.Total)
+	assert.False(t, manager.transitionBudgetAlerted)
+}
+
+func TestManager_CheckTransitionAllowed_Cooldown(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Failover.TransitionWindowDuration = time.Hour
+	cfg.Failover.TransitionCooldownDuration = 5 * time.Minute
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+
+	manager.recordTransition(constants.RoleNamePassive)
+	err := manager.checkTransitionAllowed()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "in cooldown")
+
+	// cooldown over - max_transitions is unset so there is no budget
+	manager.transitions.Transitions[0].At = time.Now().Add(-10 * time.Minute)
+	assert.NoError(t, manager.checkTransitionAllowed())
+}
+
+func TestManager_Transitions_Persisted(t *testing.T) {
+	stateDir := filepath.Join(t.TempDir(), "state")
+
+	cfg := createTestConfig()
+	cfg.Failover.StateDir = stateDir
+	cfg.Failover.MaxTransitions = 1
+	cfg.Failover.TransitionWindowDuration = time.Hour
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+	manager.recordTransition(constants.RoleNameActive)
+	assert.FileExists(t, filepath.Join(stateDir, transitionsFileName))
+
+	// a restarted manager picks up where we left off
+	cfg = createTestConfig()
+	cfg.Failover.StateDir = stateDir
+	cfg.Failover.MaxTransitions = 1
+	cfg.Failover.TransitionWindowDuration = time.Hour
+
+	restarted := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, restarted.initialize())
+	assert.Equal(t, 1, restarted.transitions.Total)
+	assert.Len(t, restarted.transitions.Transitions, 1)
+	assert.Equal(t, constants.RoleNameActive, restarted.transitions.Transitions[0].Role)
+	assert.Error(t, restarted.checkTransitionAllowed())
+}
+
+func TestManager_LoadTransitions_Corrupt(t *testing.T) {
+	stateDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(stateDir, transitionsFileName), []byte("not json"), 0o640))
+
+	cfg := createTestConfig()
+	cfg.Failover.StateDir = stateDir
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+
+	err := manager.loadTransitions()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse")
+	assert.Equal(t, 0, manager.transitions.Total)
+}
@@ -0,0 +1,32 @@
+package ha
+
+import "fmt"
+
+// isWitness returns true if we run in witness mode, without a validator of our own
+func (m *Manager) isWitness() bool {
+	return m.cfg.Validator.IsWitness()
+}
+
+// observe evaluates the HA state as a witness. Witnesses never become active, so they only sample gossip
+// and export metrics - their part in failover is voting on peers' takeovers through the HA API
+func (m *Manager) observe() {
+	m.transitionMu.Lock()
+	defer m.transitionMu.Unlock()
+
+	m.logger.Debug("observing HA state")
+
+	m.gossipState.Refresh()
+
+	// split-brain is only reported - resolving it is left to the claimants
+	m.detectSplitBrain()
+
+	m.refreshMetrics()
+
+	gossipSnapshot := m.gossipState.Snapshot()
+	if !gossipSnapshot.LeaderlessSamplesExceedsThreshold(m.cfg.Failover.LeaderlessSamplesThreshold) {
+		m.logger.Debug("active peer found")
+		return
+	}
+
+	m.logger.Warn(fmt.Sprintf("no active peer found in the last %d samples - waiting for a peer to take over", gossipSnapshot.LeaderlessSamplesCount))
+}
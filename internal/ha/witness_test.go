@@ -0,0 +1,165 @@
+package ha
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	solanago "github.com/gagliardetto/solana-go"
+	"github.com/sol-strategies/solana-validator-ha/internal/api"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createWitnessTestManager returns an initialized witness manager and the active identity key pair peers sign
+// requests with. Its cluster rpc shows peer2 passive and, if activeIP is set, the active identity voting on
+// activeIP in gossip
+func createWitnessTestManager(t *testing.T, activeIP string) (*Manager, solanago.PrivateKey) {
+	t.Helper()
+
+	activeKeyPair := createTestPrivateKey("active")
+	cfg := createTestConfig()
+	cfg.Validator.Mode = config.ValidatorModeWitness
+	cfg.Validator.Identities = config.ValidatorIdentities{
+		ActivePubkey: activeKeyPair.PublicKey().String(),
+	}
+	cfg.Failover.Active = config.Role{}
+	cfg.Failover.Passive = config.Role{}
+	cfg.Failover.StateDir = t.TempDir()
+	cfg.Failover.TakeoverVoteLeaseDuration = time.Minute
+	activePubkey := cfg.Validator.Identities.ActivePubkey
+
+	server := mockRPCServer(t, map[string]func() any{
+		"getClusterNodes": func() any {
+			clusterNodes := []map[string]any{
+				{"pubkey": createTestPrivateKey("passive").PublicKey().String(), "gossip": "192.168.1.102:8001"},
+			}
+			if activeIP != "" {
+				clusterNodes = append(clusterNodes, map[string]any{"pubkey": activePubkey, "gossip": activeIP + ":8001"})
+			}
+			return clusterNodes
+		},
+		"getSlot": func() any { return 100 },
+		"getVoteAccounts": func() any {
+			return map[string]any{
+				"current": []map[string]any{{
+					"votePubkey":       createTestPrivateKey("vote").PublicKey().String(),
+					"nodePubkey":       activePubkey,
+					"activatedStake":   1,
+					"epochVoteAccount": true,
+					"commission":       0,
+					"lastVote":         100,
+					"epochCredits":     [][]uint64{},
+					"rootSlot":         0,
+				}},
+				"delinquent": []map[string]any{},
+			}
+		},
+	})
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+		ClusterRPC:      rpc.NewClient("test", server.URL),
+		GossipDialFunc: func(network, address string) (net.Conn, error) {
+			conn, _ := net.Pipe()
+			return conn, nil
+		},
+	})
+	require.NoError(t, manager.initialize())
+
+	return manager, *activeKeyPair
+}
+
+func TestManager_Witness_Step(t *testing.T) {
+	manager, _ := createWitnessTestManager(t, "192.168.1.101")
+
+	// witnesses have no validator to talk to or sign requests with
+	assert.Nil(t, manager.localRPC)
+	assert.Nil(t, manager.apiClient)
+	assert.True(t, manager.peerSelf.Witness)
+
+	require.NoError(t, manager.Step())
+	assert.True(t, manager.started)
+	require.NoError(t, manager.Step())
+
+	// gossip-derived metrics are exported as for any other peer
+	state := manager.cache.GetState()
+	assert.Equal(t, constants.RoleNameWitness, state.Role)
+	assert.Equal(t, constants.StatusHealthy, state.Status)
+	assert.Equal(t, 2, state.PeerCount)
+	assert.False(t, state.SelfInGossip)
+	assert.Equal(t, constants.StatusIdle, state.FailoverStatus)
+
+	activePeer, err := manager.gossipState.Snapshot().GetActivePeer()
+	require.NoError(t, err)
+	assert.Equal(t, "peer1", activePeer.Name)
+}
+
+func TestManager_Witness_NeverTakesOver(t *testing.T) {
+	manager, _ := createWitnessTestManager(t, "")
+
+	// even with the cluster leaderless, a witness only observes
+	for range manager.cfg.Failover.LeaderlessSamplesThreshold + 2 {
+		require.NoError(t, manager.Step())
+	}
+	require.True(t, manager.gossipState.Snapshot().LeaderlessSamplesExceedsThreshold(manager.cfg.Failover.LeaderlessSamplesThreshold))
+
+	assert.Empty(t, readJournal(t, manager))
+	assert.Equal(t, constants.StatusIdle, manager.cache.GetState().FailoverStatus)
+}
+
+func TestManager_Witness_APIHandlers(t *testing.T) {
+	manager, activeKeyPair := createWitnessTestManager(t, "192.168.1.101")
+
+	mux := http.NewServeMux()
+	manager.registerAPIHandlers(mux)
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		require.NoError(t, api.Sign(request, []byte(body), activeKeyPair, manager.cfg.Validator.Name, time.Now()))
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// witnesses vote on takeovers
+	recorder := post(api.PathVote, `{"candidate":"peer1"}`)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "voted for peer1")
+
+	recorder = post(api.PathVote, `{"candidate":"peer2"}`)
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "already voted for peer1")
+
+	// but have no validator to switch over, promote or demote
+	for _, path := range []string{api.PathSwitchover, api.PathPromote, api.PathDemote, api.PathMaintenance} {
+		assert.Equal(t, http.StatusNotFound, post(path, `{}`).Code, path)
+	}
+}
+
+func TestManager_Switchover_ToWitness(t *testing.T) {
+	cfg := createTestConfig()
+	peer2 := cfg.Failover.Peers["peer2"]
+	peer2.Witness = true
+	cfg.Failover.Peers["peer2"] = peer2
+
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+	})
+	require.NoError(t, manager.initialize())
+
+	err := manager.switchover("peer2")
+	assert.ErrorContains(t, err, "cannot switchover to witness peer2")
+
+	// witnesses are never ranked to become active
+	_, ranked := cfg.Failover.Peers.GetRankedIPs()["192.168.1.102"]
+	assert.False(t, ranked)
+}
@@ -0,0 +1,228 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// FileName is the file in failover.state_dir the event journal is appended to
+const FileName = "events.jsonl"
+
+// Event types - one per kind of failover decision
+const (
+	EventTypeFailover     = "failover"
+	EventTypeSelfDemotion = "self_demotion"
+	EventTypeSplitBrain   = "split_brain"
+	EventTypeFailback     = "failback"
+	EventTypeSwitchover   = "switchover"
+	EventTypePromote      = "promote"
+	EventTypeDemote       = "demote"
+	EventTypeStartup      = "startup"
+	EventTypeShutdown     = "shutdown"
+)
+
+// EventTypes are all event types
+var EventTypes = []string{
+	EventTypeFailover,
+	EventTypeSelfDemotion,
+	EventTypeSplitBrain,
+	EventTypeFailback,
+	EventTypeSwitchover,
+	EventTypePromote,
+	EventTypeDemote,
+	EventTypeStartup,
+	EventTypeShutdown,
+}
+
+// Event outcomes
+const (
+	// OutcomeSuccess means the decision was carried out
+	OutcomeSuccess = "success"
+	// OutcomeFailure means the decision was taken but carrying it out failed
+	OutcomeFailure = "failure"
+	// OutcomeRefused means a precondition stopped the decision from being carried out
+	OutcomeRefused = "refused"
+)
+
+// Outcomes are all event outcomes
+var Outcomes = []string{OutcomeSuccess, OutcomeFailure, OutcomeRefused}
+
+// Event is a single failover decision
+type Event struct {
+	// Time is when the decision was started
+	Time time.Time `json:"time"`
+	// Type is one of the EventType constants
+	Type string `json:"type"`
+	// Validator is the name of the validator that made the decision
+	Validator string `json:"validator"`
+	// Outcome is one of the Outcome constants
+	Outcome string `json:"outcome"`
+	// Reason explains a refusal or failure
+	Reason string `json:"reason,omitempty"`
+	// Detail is any other context for the decision, e.g. the switchover target
+	Detail string `json:"detail,omitempty"`
+	// LeaderlessSamplesCount is the number of consecutive samples without an active peer when the decision was started
+	LeaderlessSamplesCount int `json:"leaderless_samples_count"`
+	// Gossip is the state of our peers in gossip when the decision was started
+	Gossip []PeerSnapshot `json:"gossip"`
+	// Commands are the role commands and hooks run while carrying out the decision
+	Commands []CommandResult `json:"commands,omitempty"`
+	// DurationMs is how long the decision took to carry out
+	DurationMs int64 `json:"duration_ms"`
+}
+
+// PeerSnapshot is the state of a peer in gossip
+type PeerSnapshot struct {
+	Name       string    `json:"name"`
+	IP         string    `json:"ip"`
+	Pubkey     string    `json:"pubkey"`
+	Active     bool      `json:"active"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// CommandResult is the result of a role command or hook
+type CommandResult struct {
+	Name       string   `json:"name"`
+	Command    string   `json:"command"`
+	Args       []string `json:"args,omitempty"`
+	ExitCode   int      `json:"exit_code"`
+	DurationMs int64    `json:"duration_ms"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// Duration returns how long the decision took to carry out
+func (e *Event) Duration() time.Duration {
+	return time.Duration(e.DurationMs) * time.Millisecond
+}
+
+// FilePath returns the path of the event journal in stateDir or empty if stateDir is unset
+func FilePath(stateDir string) string {
+	if stateDir == "" {
+		return ""
+	}
+	return filepath.Join(stateDir, FileName)
+}
+
+// Journal is an append-only JSON lines file of events
+type Journal struct {
+	mu   sync.Mutex
+	file string
+}
+
+// New creates a journal appending to file - an empty file disables journaling
+func New(file string) *Journal {
+	return &Journal{file: file}
+}
+
+// File returns the path of the journal file
+func (j *Journal) File() string {
+	return j.file
+}
+
+// Append writes event as a single line to the end of the journal
+func (j *Journal) Append(event Event) error {
+	if j.file == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(j.file), 0o750); err != nil {
+		return fmt.Errorf("failed to create journal directory: %w", err)
+	}
+
+	f, err := os.OpenFile(j.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", j.file, err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write %s: %w", j.file, err)
+	}
+
+	return nil
+}
+
+// Read returns all events in the journal file, oldest first - a missing file has no events. Lines that
+// cannot be parsed (e.g. truncated by a crash mid-write) are skipped
+func Read(file string) ([]Event, error) {
+	f, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", file, err)
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		events = append(events, event)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	return events, nil
+}
+
+// Filter selects events from the journal
+type Filter struct {
+	// Types keeps only events of these types - all types when empty
+	Types []string
+	// Outcomes keeps only events with these outcomes - all outcomes when empty
+	Outcomes []string
+	// Since keeps only events at or after this time - all events when zero
+	Since time.Time
+	// Until keeps only events before this time - all events when zero
+	Until time.Time
+	// Limit keeps only the most recent events - all events when zero
+	Limit int
+}
+
+// Apply returns the events matching the filter, preserving their order
+func (f Filter) Apply(events []Event) []Event {
+	var matched []Event
+	for _, event := range events {
+		if len(f.Types) > 0 && !slices.Contains(f.Types, event.Type) {
+			continue
+		}
+		if len(f.Outcomes) > 0 && !slices.Contains(f.Outcomes, event.Outcome) {
+			continue
+		}
+		if !f.Since.IsZero() && event.Time.Before(f.Since) {
+			continue
+		}
+		if !f.Until.IsZero() && !event.Time.Before(f.Until) {
+			continue
+		}
+		matched = append(matched, event)
+	}
+
+	if f.Limit > 0 && len(matched) > f.Limit {
+		matched = matched[len(matched)-f.Limit:]
+	}
+
+	return matched
+}
@@ -0,0 +1,99 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilePath(t *testing.T) {
+	assert.Equal(t, "", FilePath(""))
+	assert.Equal(t, filepath.Join("/var/lib/solana-validator-ha", FileName), FilePath("/var/lib/solana-validator-ha"))
+}
+
+func TestJournal_AppendAndRead(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "state", FileName)
+	j := New(file)
+
+	first := Event{
+		Time:                   time.Now().UTC().Truncate(time.Millisecond),
+		Type:                   EventTypeFailover,
+		Validator:              "validator-1",
+		Outcome:                OutcomeSuccess,
+		LeaderlessSamplesCount: 3,
+		Gossip:                 []PeerSnapshot{{Name: "validator-2", IP: "192.168.1.101", Pubkey: "passive", Active: false}},
+		Commands:               []CommandResult{{Name: "active", Command: "/bin/true", ExitCode: 0, DurationMs: 12}},
+		DurationMs:             15,
+	}
+	second := Event{Time: first.Time.Add(time.Minute), Type: EventTypeSwitchover, Validator: "validator-1", Outcome: OutcomeRefused, Reason: "we are not active"}
+
+	require.NoError(t, j.Append(first))
+	require.NoError(t, j.Append(second))
+
+	events, err := Read(file)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, first, events[0])
+	assert.Equal(t, second, events[1])
+	assert.Equal(t, 15*time.Millisecond, events[0].Duration())
+}
+
+func TestJournal_AppendDisabled(t *testing.T) {
+	assert.NoError(t, New("").Append(Event{Type: EventTypeFailover}))
+}
+
+func TestRead_MissingFile(t *testing.T) {
+	events, err := Read(filepath.Join(t.TempDir(), FileName))
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestRead_SkipsUnparseableLines(t *testing.T) {
+	file := filepath.Join(t.TempDir(), FileName)
+	content := `{"type":"failover","outcome":"success"}
+{"type":"switch
+{"type":"demote","outcome":"failure"}
+`
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o640))
+
+	events, err := Read(file)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, EventTypeFailover, events[0].Type)
+	assert.Equal(t, EventTypeDemote, events[1].Type)
+}
+
+func TestFilter_Apply(t *testing.T) {
+	now := time.Now()
+	events := []Event{
+		{Time: now.Add(-3 * time.Hour), Type: EventTypeFailover, Outcome: OutcomeRefused},
+		{Time: now.Add(-2 * time.Hour), Type: EventTypeFailover, Outcome: OutcomeSuccess},
+		{Time: now.Add(-1 * time.Hour), Type: EventTypeSwitchover, Outcome: OutcomeSuccess},
+		{Time: now, Type: EventTypeSplitBrain, Outcome: OutcomeFailure},
+	}
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		expected []Event
+	}{
+		{name: "no filter", filter: Filter{}, expected: events},
+		{name: "by type", filter: Filter{Types: []string{EventTypeFailover}}, expected: events[:2]},
+		{name: "by outcome", filter: Filter{Outcomes: []string{OutcomeSuccess}}, expected: events[1:3]},
+		{name: "since", filter: Filter{Since: now.Add(-90 * time.Minute)}, expected: events[2:]},
+		{name: "until", filter: Filter{Until: now.Add(-2 * time.Hour)}, expected: events[:1]},
+		{name: "limit keeps most recent", filter: Filter{Limit: 2}, expected: events[2:]},
+		{name: "combined", filter: Filter{Types: []string{EventTypeFailover, EventTypeSwitchover}, Outcomes: []string{OutcomeSuccess}, Limit: 1}, expected: events[2:3]},
+		{name: "no matches", filter: Filter{Types: []string{EventTypeShutdown}}, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.filter.Apply(events))
+		})
+	}
+}
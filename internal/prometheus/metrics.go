@@ -1,8 +1,10 @@
 package prometheus
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"sync"
 
 	"github.com/charmbracelet/log"
 	"github.com/prometheus/client_golang/prometheus"
@@ -10,6 +12,7 @@ import (
 
 	"github.com/sol-strategies/solana-validator-ha/internal/cache"
 	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
 )
 
 const (
@@ -21,6 +24,11 @@ const (
 	failoverStatusLabelName  = "status"
 	peerCountLabelName       = "peer_count"
 	selfInGossipLabelName    = "self_in_gossip"
+	groupLabelName           = "group"
+	rpcEndpointLabelName     = "rpc_endpoint"
+	peerNameLabelName        = "peer_name"
+	accountLabelName         = "account"
+	pubkeyLabelName          = "pubkey"
 )
 
 var (
@@ -35,15 +43,41 @@ type Metrics struct {
 	config           *config.Config
 	logger           *log.Logger
 	cache            *cache.Cache
+	serverMu         sync.Mutex
 	server           *http.Server
 	registry         *prometheus.Registry
 	commonLabelNames []string
 
 	// Metrics
-	metadata       *prometheus.GaugeVec
-	peerCount      *prometheus.GaugeVec
-	selfInGossip   *prometheus.GaugeVec
-	failoverStatus *prometheus.GaugeVec
+	metadata        *prometheus.GaugeVec
+	peerCount       *prometheus.GaugeVec
+	selfInGossip    *prometheus.GaugeVec
+	failoverStatus  *prometheus.GaugeVec
+	maintenanceMode *prometheus.GaugeVec
+	splitBrain      *prometheus.GaugeVec
+
+	roleTransitions           *prometheus.GaugeVec
+	roleTransitionsTotal      *prometheus.GaugeVec
+	transitionBudgetExhausted *prometheus.GaugeVec
+
+	leaderSlotsAtRisk     *prometheus.GaugeVec
+	leaderScheduleWaiting *prometheus.GaugeVec
+
+	doubleVoteGuardTripped *prometheus.GaugeVec
+
+	clusterRPCLeaderless   *prometheus.GaugeVec
+	clusterRPCDisagreement *prometheus.GaugeVec
+
+	peerVoteLagSlots *prometheus.GaugeVec
+
+	peerAvailabilityPercent *prometheus.GaugeVec
+	peerTransitionsPerHour  *prometheus.GaugeVec
+	peerFlapping            *prometheus.GaugeVec
+
+	peerUnexpectedIdentities *prometheus.GaugeVec
+
+	balanceLamports *prometheus.GaugeVec
+	balanceLevel    *prometheus.GaugeVec
 }
 
 // Options for creating a new Metrics instance
@@ -66,6 +100,11 @@ func New(opts Options) *Metrics {
 		},
 	}
 
+	// managers of different groups export the same metrics, told apart by group
+	if m.config.Group != "" {
+		m.commonLabelNames = append(m.commonLabelNames, groupLabelName)
+	}
+
 	// Add static labels names from config
 	for labelName := range m.config.Prometheus.StaticLabels {
 		m.commonLabelNames = append(m.commonLabelNames, labelName)
@@ -122,11 +161,193 @@ func (m *Metrics) initMetrics() {
 		failoverLabelNames,
 	)
 
+	// Maintenance mode metric
+	m.maintenanceMode = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "maintenance_mode",
+			Help: "Whether this node is in maintenance mode and will not take over as active (1 = yes, 0 = no)",
+		},
+		m.commonLabelNames,
+	)
+
+	// Split-brain metric
+	m.splitBrain = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "split_brain_detected",
+			Help: "Whether more than one node is claiming the active identity (1 = yes, 0 = no)",
+		},
+		m.commonLabelNames,
+	)
+
+	// Flap damping metrics - gauges rather than counters as they are restored from failover.state_dir on restart
+	m.roleTransitions = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "role_transitions",
+			Help: "Number of role changes within failover.transition_window_duration",
+		},
+		m.commonLabelNames,
+	)
+	m.roleTransitionsTotal = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "role_transitions_total",
+			Help: "Number of role changes ever recorded, persisted across restarts",
+		},
+		m.commonLabelNames,
+	)
+	m.transitionBudgetExhausted = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "transition_budget_exhausted",
+			Help: "Whether failover.max_transitions is reached and automatic role changes are refused (1 = yes, 0 = no)",
+		},
+		m.commonLabelNames,
+	)
+
+	// Leader schedule metrics
+	m.leaderSlotsAtRisk = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "leader_slots_at_risk",
+			Help: "Active identity leader slots within failover.leader_schedule_lookahead_slots at the last role transition check",
+		},
+		m.commonLabelNames,
+	)
+	m.leaderScheduleWaiting = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "leader_schedule_waiting",
+			Help: "Whether a planned transition is waiting for a window without leader slots (1 = yes, 0 = no)",
+		},
+		m.commonLabelNames,
+	)
+
+	// Double vote guard metric
+	m.doubleVoteGuardTripped = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "double_vote_guard_tripped",
+			Help: "Whether the last takeover was aborted because the active identity was still voting (1 = yes, 0 = no)",
+		},
+		m.commonLabelNames,
+	)
+
+	// Cluster RPC consensus metrics
+	clusterRPCLeaderlessLabelNames := []string{
+		rpcEndpointLabelName,
+	}
+	clusterRPCLeaderlessLabelNames = append(clusterRPCLeaderlessLabelNames, m.commonLabelNames...)
+	m.clusterRPCLeaderless = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "cluster_rpc_leaderless",
+			Help: "Whether each cluster RPC endpoint that answered the last sample showed the cluster leaderless (1 = yes, 0 = no), with cluster.rpc_mode consensus",
+		},
+		clusterRPCLeaderlessLabelNames,
+	)
+	m.clusterRPCDisagreement = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "cluster_rpc_disagreement",
+			Help: "Whether cluster RPC endpoints disagreed on the active peer at the last sample (1 = yes, 0 = no)",
+		},
+		m.commonLabelNames,
+	)
+
+	// Vote lag metric
+	peerVoteLagSlotsLabelNames := []string{
+		peerNameLabelName,
+	}
+	peerVoteLagSlotsLabelNames = append(peerVoteLagSlotsLabelNames, m.commonLabelNames...)
+	m.peerVoteLagSlots = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "peer_vote_lag_slots",
+			Help: "How many slots behind the cluster each peer presenting the active identity last voted",
+		},
+		peerVoteLagSlotsLabelNames,
+	)
+
+	// Peer history metrics
+	peerHistoryLabelNames := []string{
+		peerNameLabelName,
+	}
+	peerHistoryLabelNames = append(peerHistoryLabelNames, m.commonLabelNames...)
+	m.peerAvailabilityPercent = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "peer_availability_percent",
+			Help: "Percentage of each peer's recent gossip samples it was available in",
+		},
+		peerHistoryLabelNames,
+	)
+	m.peerTransitionsPerHour = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "peer_transitions_per_hour",
+			Help: "How often each peer's availability changed over its recent gossip samples, per hour",
+		},
+		peerHistoryLabelNames,
+	)
+	m.peerFlapping = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "peer_flapping",
+			Help: "Whether each peer is flapping in and out of availability (1=yes, 0=no)",
+		},
+		peerHistoryLabelNames,
+	)
+
+	// Peer identity metrics
+	peerIdentityLabelNames := []string{
+		peerNameLabelName,
+	}
+	peerIdentityLabelNames = append(peerIdentityLabelNames, m.commonLabelNames...)
+	m.peerUnexpectedIdentities = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "peer_unexpected_identities",
+			Help: "Number of nodes at each peer's addresses in gossip presenting neither its pubkey nor the active identity",
+		},
+		peerIdentityLabelNames,
+	)
+
+	// Balance monitor metrics
+	balanceLamportsLabelNames := []string{
+		accountLabelName,
+		pubkeyLabelName,
+	}
+	balanceLamportsLabelNames = append(balanceLamportsLabelNames, m.commonLabelNames...)
+	m.balanceLamports = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "balance_lamports",
+			Help: "Balance of each account the balance monitor watches, in lamports",
+		},
+		balanceLamportsLabelNames,
+	)
+
+	balanceLevelLabelNames := []string{
+		accountLabelName,
+	}
+	balanceLevelLabelNames = append(balanceLevelLabelNames, m.commonLabelNames...)
+	m.balanceLevel = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "balance_level",
+			Help: "Balance level of each account the balance monitor watches (0=ok, 1=warn, 2=critical)",
+		},
+		balanceLevelLabelNames,
+	)
+
 	// Register all metrics
 	m.registry.MustRegister(m.metadata)
 	m.registry.MustRegister(m.peerCount)
 	m.registry.MustRegister(m.selfInGossip)
 	m.registry.MustRegister(m.failoverStatus)
+	m.registry.MustRegister(m.maintenanceMode)
+	m.registry.MustRegister(m.splitBrain)
+	m.registry.MustRegister(m.roleTransitions)
+	m.registry.MustRegister(m.roleTransitionsTotal)
+	m.registry.MustRegister(m.transitionBudgetExhausted)
+	m.registry.MustRegister(m.leaderSlotsAtRisk)
+	m.registry.MustRegister(m.leaderScheduleWaiting)
+	m.registry.MustRegister(m.doubleVoteGuardTripped)
+	m.registry.MustRegister(m.clusterRPCLeaderless)
+	m.registry.MustRegister(m.clusterRPCDisagreement)
+	m.registry.MustRegister(m.peerVoteLagSlots)
+	m.registry.MustRegister(m.peerAvailabilityPercent)
+	m.registry.MustRegister(m.peerTransitionsPerHour)
+	m.registry.MustRegister(m.peerFlapping)
+	m.registry.MustRegister(m.peerUnexpectedIdentities)
+	m.registry.MustRegister(m.balanceLamports)
+	m.registry.MustRegister(m.balanceLevel)
 
 	m.logger.Debug("initialized Prometheus metrics")
 }
@@ -136,28 +357,56 @@ func (m *Metrics) StartServer(port int) error {
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
 
-	m.server = &http.Server{
+	server := &http.Server{
 		Addr:    fmt.Sprintf(":%d", port),
 		Handler: mux,
 	}
+	m.serverMu.Lock()
+	m.server = server
+	m.serverMu.Unlock()
 
 	m.logger.Debug("starting Prometheus metrics server", "port", port)
 
-	err := m.server.ListenAndServe()
+	err := server.ListenAndServe()
 	if err != nil {
 		m.logger.Error("Prometheus metrics server failed", "error", err)
 	}
 	return err
 }
 
+// Handler returns a handler serving the metrics of all of metrics together, for the managers of all
+// groups to share one metrics server
+func Handler(metrics ...*Metrics) http.Handler {
+	gatherers := prometheus.Gatherers{}
+	for _, m := range metrics {
+		gatherers = append(gatherers, m.registry)
+	}
+	return promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{})
+}
+
 // StopServer stops the Prometheus metrics HTTP server
 func (m *Metrics) StopServer() error {
+	m.serverMu.Lock()
+	defer m.serverMu.Unlock()
+
 	if m.server != nil {
 		return m.server.Close()
 	}
 	return nil
 }
 
+// ShutdownServer gracefully stops the Prometheus metrics HTTP server, waiting for
+// in-flight scrapes to complete until ctx is done
+func (m *Metrics) ShutdownServer(ctx context.Context) error {
+	m.serverMu.Lock()
+	defer m.serverMu.Unlock()
+
+	if m.server != nil {
+		return m.server.Shutdown(ctx)
+	}
+	return nil
+}
+
 // GetRegistry returns the Prometheus registry for testing
 func (m *Metrics) GetRegistry() *prometheus.Registry {
 	return m.registry
@@ -172,6 +421,16 @@ func (m *Metrics) RefreshMetrics() {
 	m.exportMetricPeerCount(&state)
 	m.exportMetricSelfInGossip(&state)
 	m.exportMetricFailoverStatus(&state)
+	m.exportMetricMaintenanceMode(&state)
+	m.exportMetricSplitBrain(&state)
+	m.exportMetricRoleTransitions(&state)
+	m.exportMetricLeaderSchedule(&state)
+	m.exportMetricDoubleVoteGuard(&state)
+	m.exportMetricClusterRPC(&state)
+	m.exportMetricPeerVoteLag(&state)
+	m.exportMetricPeerStats(&state)
+	m.exportMetricPeerUnexpectedIdentities(&state)
+	m.exportMetricBalances(&state)
 
 	m.logger.Debug("metrics refreshed",
 		validatorRoleLabelName, state.Role,
@@ -179,6 +438,14 @@ func (m *Metrics) RefreshMetrics() {
 		peerCountLabelName, state.PeerCount,
 		selfInGossipLabelName, state.SelfInGossip,
 		failoverStatusLabelName, state.FailoverStatus,
+		"maintenance_mode", state.MaintenanceMode,
+		"split_brain_detected", state.SplitBrainDetected,
+		"role_transitions", state.RoleTransitions,
+		"transition_budget_exhausted", state.TransitionBudgetExhausted,
+		"leader_slots_at_risk", state.LeaderSlotsAtRisk,
+		"leader_schedule_waiting", state.LeaderScheduleWaiting,
+		"double_vote_guard_tripped", state.DoubleVoteGuardTripped,
+		"cluster_rpc_disagreement", state.ClusterRPCDisagreement,
 	)
 }
 
@@ -229,6 +496,149 @@ func (m *Metrics) exportMetricFailoverStatus(state *cache.State) {
 		Set(1)
 }
 
+func (m *Metrics) exportMetricMaintenanceMode(state *cache.State) {
+	var maintenanceModeValue float64
+	if state.MaintenanceMode {
+		maintenanceModeValue = 1
+	}
+	m.maintenanceMode.
+		With(m.getCommonLabels(state)).
+		Set(maintenanceModeValue)
+}
+
+func (m *Metrics) exportMetricSplitBrain(state *cache.State) {
+	var splitBrainValue float64
+	if state.SplitBrainDetected {
+		splitBrainValue = 1
+	}
+	m.splitBrain.
+		With(m.getCommonLabels(state)).
+		Set(splitBrainValue)
+}
+
+func (m *Metrics) exportMetricRoleTransitions(state *cache.State) {
+	commonLabels := m.getCommonLabels(state)
+	m.roleTransitions.With(commonLabels).Set(float64(state.RoleTransitions))
+	m.roleTransitionsTotal.With(commonLabels).Set(float64(state.RoleTransitionsTotal))
+
+	var transitionBudgetExhaustedValue float64
+	if state.TransitionBudgetExhausted {
+		transitionBudgetExhaustedValue = 1
+	}
+	m.transitionBudgetExhausted.With(commonLabels).Set(transitionBudgetExhaustedValue)
+}
+
+func (m *Metrics) exportMetricLeaderSchedule(state *cache.State) {
+	commonLabels := m.getCommonLabels(state)
+	m.leaderSlotsAtRisk.With(commonLabels).Set(float64(state.LeaderSlotsAtRisk))
+
+	var leaderScheduleWaitingValue float64
+	if state.LeaderScheduleWaiting {
+		leaderScheduleWaitingValue = 1
+	}
+	m.leaderScheduleWaiting.With(commonLabels).Set(leaderScheduleWaitingValue)
+}
+
+func (m *Metrics) exportMetricDoubleVoteGuard(state *cache.State) {
+	var doubleVoteGuardTrippedValue float64
+	if state.DoubleVoteGuardTripped {
+		doubleVoteGuardTrippedValue = 1
+	}
+	m.doubleVoteGuardTripped.
+		With(m.getCommonLabels(state)).
+		Set(doubleVoteGuardTrippedValue)
+}
+
+func (m *Metrics) exportMetricClusterRPC(state *cache.State) {
+	commonLabels := m.getCommonLabels(state)
+
+	// Reset to remove endpoints that did not answer
+	m.clusterRPCLeaderless.Reset()
+	for endpoint, leaderless := range state.ClusterRPCLeaderless {
+		var leaderlessValue float64
+		if leaderless {
+			leaderlessValue = 1
+		}
+		m.clusterRPCLeaderless.
+			With(m.mergeLabels(prometheus.Labels{rpcEndpointLabelName: endpoint}, commonLabels)).
+			Set(leaderlessValue)
+	}
+
+	var disagreementValue float64
+	if state.ClusterRPCDisagreement {
+		disagreementValue = 1
+	}
+	m.clusterRPCDisagreement.With(commonLabels).Set(disagreementValue)
+}
+
+func (m *Metrics) exportMetricPeerVoteLag(state *cache.State) {
+	commonLabels := m.getCommonLabels(state)
+
+	// Reset to remove peers no longer presenting the active identity
+	m.peerVoteLagSlots.Reset()
+	for peerName, voteLagSlots := range state.PeerVoteLagSlots {
+		m.peerVoteLagSlots.
+			With(m.mergeLabels(prometheus.Labels{peerNameLabelName: peerName}, commonLabels)).
+			Set(float64(voteLagSlots))
+	}
+}
+
+func (m *Metrics) exportMetricPeerStats(state *cache.State) {
+	commonLabels := m.getCommonLabels(state)
+
+	// Reset to remove peers no longer configured
+	m.peerAvailabilityPercent.Reset()
+	m.peerTransitionsPerHour.Reset()
+	m.peerFlapping.Reset()
+	for peerName, stats := range state.PeerStats {
+		labels := m.mergeLabels(prometheus.Labels{peerNameLabelName: peerName}, commonLabels)
+		m.peerAvailabilityPercent.With(labels).Set(stats.AvailabilityPercent)
+		m.peerTransitionsPerHour.With(labels).Set(stats.TransitionsPerHour)
+
+		var flappingValue float64
+		if stats.Flapping {
+			flappingValue = 1
+		}
+		m.peerFlapping.With(labels).Set(flappingValue)
+	}
+}
+
+func (m *Metrics) exportMetricPeerUnexpectedIdentities(state *cache.State) {
+	commonLabels := m.getCommonLabels(state)
+
+	// Reset to remove peers no longer configured
+	m.peerUnexpectedIdentities.Reset()
+	for peerName, count := range state.PeerUnexpectedIdentities {
+		m.peerUnexpectedIdentities.
+			With(m.mergeLabels(prometheus.Labels{peerNameLabelName: peerName}, commonLabels)).
+			Set(float64(count))
+	}
+}
+
+func (m *Metrics) exportMetricBalances(state *cache.State) {
+	commonLabels := m.getCommonLabels(state)
+
+	// Reset to remove accounts no longer watched, such as a vote account the active identity moved away from
+	m.balanceLamports.Reset()
+	m.balanceLevel.Reset()
+	for account, balance := range state.AccountBalances {
+		m.balanceLamports.
+			With(m.mergeLabels(prometheus.Labels{accountLabelName: account, pubkeyLabelName: balance.Pubkey}, commonLabels)).
+			Set(float64(balance.Lamports))
+
+		var levelValue float64
+		switch balance.Level {
+		case constants.BalanceLevelWarn:
+			levelValue = 1
+		case constants.BalanceLevelCritical:
+			levelValue = 2
+		}
+		m.balanceLevel.
+			With(m.mergeLabels(prometheus.Labels{accountLabelName: account}, commonLabels)).
+			Set(levelValue)
+	}
+}
+
 // mergeLabels merges fromLabels into toLabels
 func (m *Metrics) mergeLabels(toLabels prometheus.Labels, fromLabels prometheus.Labels) prometheus.Labels {
 	for labelName, labelValue := range fromLabels {
@@ -242,6 +652,9 @@ func (m *Metrics) getCommonLabels(state *cache.State) prometheus.Labels {
 		publicIPLabelName:      state.PublicIP,
 		validatorNameLabelName: state.ValidatorName,
 	}
+	if m.config.Group != "" {
+		commonLabels[groupLabelName] = m.config.Group
+	}
 	for k, v := range m.config.Prometheus.StaticLabels {
 		commonLabels[k] = v
 	}
@@ -1,19 +1,23 @@
 package prometheus
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/sol-strategies/solana-validator-ha/internal/cache"
 	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
 )
 
 func createTestConfig() *config.Config {
@@ -448,6 +452,305 @@ func TestExportMetricSelfInGossip_False(t *testing.T) {
 	assert.Equal(t, float64(0), *selfInGossipMetric.Metric[0].Gauge.Value)
 }
 
+func TestExportMetricMaintenanceMode(t *testing.T) {
+	cfg := createTestConfig()
+	cacheInstance := createTestCache()
+	logger := createTestLogger()
+
+	opts := Options{
+		Config: cfg,
+		Logger: logger,
+		Cache:  cacheInstance,
+	}
+
+	metrics := New(opts)
+
+	state := cache.State{
+		ValidatorName:   "test-validator",
+		PublicIP:        "192.168.1.100",
+		MaintenanceMode: true,
+	}
+
+	metrics.exportMetricMaintenanceMode(&state)
+
+	// Verify the metric was set by checking the registry
+	registry := metrics.GetRegistry()
+	metricsList, err := registry.Gather()
+	require.NoError(t, err)
+
+	var maintenanceModeMetric *dto.MetricFamily
+	for _, metricFamily := range metricsList {
+		if *metricFamily.Name == "solana_validator_ha_maintenance_mode" {
+			maintenanceModeMetric = metricFamily
+			break
+		}
+	}
+
+	require.NotNil(t, maintenanceModeMetric)
+	assert.Len(t, maintenanceModeMetric.Metric, 1)
+	assert.Equal(t, float64(1), *maintenanceModeMetric.Metric[0].Gauge.Value)
+}
+
+func TestExportMetricSplitBrain(t *testing.T) {
+	cfg := createTestConfig()
+	cacheInstance := createTestCache()
+	logger := createTestLogger()
+
+	opts := Options{
+		Config: cfg,
+		Logger: logger,
+		Cache:  cacheInstance,
+	}
+
+	metrics := New(opts)
+
+	state := cache.State{
+		ValidatorName:      "test-validator",
+		PublicIP:           "192.168.1.100",
+		SplitBrainDetected: true,
+	}
+
+	metrics.exportMetricSplitBrain(&state)
+
+	// Verify the metric was set by checking the registry
+	registry := metrics.GetRegistry()
+	metricsList, err := registry.Gather()
+	require.NoError(t, err)
+
+	var splitBrainMetric *dto.MetricFamily
+	for _, metricFamily := range metricsList {
+		if *metricFamily.Name == "solana_validator_ha_split_brain_detected" {
+			splitBrainMetric = metricFamily
+			break
+		}
+	}
+
+	require.NotNil(t, splitBrainMetric)
+	assert.Len(t, splitBrainMetric.Metric, 1)
+	assert.Equal(t, float64(1), *splitBrainMetric.Metric[0].Gauge.Value)
+}
+
+func TestExportMetricDoubleVoteGuard(t *testing.T) {
+	cfg := createTestConfig()
+	cacheInstance := createTestCache()
+	logger := createTestLogger()
+
+	opts := Options{
+		Config: cfg,
+		Logger: logger,
+		Cache:  cacheInstance,
+	}
+
+	metrics := New(opts)
+
+	state := cache.State{
+		ValidatorName:          "test-validator",
+		PublicIP:               "192.168.1.100",
+		DoubleVoteGuardTripped: true,
+	}
+
+	metrics.exportMetricDoubleVoteGuard(&state)
+
+	// Verify the metric was set by checking the registry
+	registry := metrics.GetRegistry()
+	metricsList, err := registry.Gather()
+	require.NoError(t, err)
+
+	var doubleVoteGuardMetric *dto.MetricFamily
+	for _, metricFamily := range metricsList {
+		if *metricFamily.Name == "solana_validator_ha_double_vote_guard_tripped" {
+			doubleVoteGuardMetric = metricFamily
+			break
+		}
+	}
+
+	require.NotNil(t, doubleVoteGuardMetric)
+	assert.Len(t, doubleVoteGuardMetric.Metric, 1)
+	assert.Equal(t, float64(1), *doubleVoteGuardMetric.Metric[0].Gauge.Value)
+}
+
+func TestExportMetricClusterRPC(t *testing.T) {
+	metrics := New(Options{
+		Config: createTestConfig(),
+		Logger: createTestLogger(),
+		Cache:  createTestCache(),
+	})
+
+	state := cache.State{
+		ValidatorName:          "test-validator",
+		PublicIP:               "192.168.1.100",
+		ClusterRPCLeaderless:   map[string]bool{"rpc-1.example.com": true, "rpc-2.example.com": false},
+		ClusterRPCDisagreement: true,
+	}
+	metrics.exportMetricClusterRPC(&state)
+
+	metricsList, err := metrics.GetRegistry().Gather()
+	require.NoError(t, err)
+
+	metricFamilies := map[string]*dto.MetricFamily{}
+	for _, metricFamily := range metricsList {
+		metricFamilies[*metricFamily.Name] = metricFamily
+	}
+
+	leaderlessMetric := metricFamilies["solana_validator_ha_cluster_rpc_leaderless"]
+	require.NotNil(t, leaderlessMetric)
+	require.Len(t, leaderlessMetric.Metric, 2)
+	leaderlessByEndpoint := map[string]float64{}
+	for _, metric := range leaderlessMetric.Metric {
+		for _, label := range metric.Label {
+			if *label.Name == rpcEndpointLabelName {
+				leaderlessByEndpoint[*label.Value] = *metric.Gauge.Value
+			}
+		}
+	}
+	assert.Equal(t, map[string]float64{"rpc-1.example.com": 1, "rpc-2.example.com": 0}, leaderlessByEndpoint)
+
+	disagreementMetric := metricFamilies["solana_validator_ha_cluster_rpc_disagreement"]
+	require.NotNil(t, disagreementMetric)
+	assert.Equal(t, float64(1), *disagreementMetric.Metric[0].Gauge.Value)
+
+	// endpoints that stop answering are no longer exported
+	state.ClusterRPCLeaderless = map[string]bool{"rpc-1.example.com": true}
+	metrics.exportMetricClusterRPC(&state)
+	assert.Equal(t, 1, testutil.CollectAndCount(metrics.clusterRPCLeaderless))
+}
+
+func TestExportMetricPeerVoteLag(t *testing.T) {
+	metrics := New(Options{
+		Config: createTestConfig(),
+		Logger: createTestLogger(),
+		Cache:  createTestCache(),
+	})
+
+	state := cache.State{
+		ValidatorName:    "test-validator",
+		PublicIP:         "192.168.1.100",
+		PeerVoteLagSlots: map[string]uint64{"validator-2": 42},
+	}
+	metrics.exportMetricPeerVoteLag(&state)
+
+	metricsList, err := metrics.GetRegistry().Gather()
+	require.NoError(t, err)
+
+	var voteLagMetric *dto.MetricFamily
+	for _, metricFamily := range metricsList {
+		if *metricFamily.Name == "solana_validator_ha_peer_vote_lag_slots" {
+			voteLagMetric = metricFamily
+			break
+		}
+	}
+
+	require.NotNil(t, voteLagMetric)
+	require.Len(t, voteLagMetric.Metric, 1)
+	assert.Equal(t, float64(42), *voteLagMetric.Metric[0].Gauge.Value)
+	var peerName string
+	for _, label := range voteLagMetric.Metric[0].Label {
+		if *label.Name == peerNameLabelName {
+			peerName = *label.Value
+		}
+	}
+	assert.Equal(t, "validator-2", peerName)
+
+	// peers no longer presenting the active identity are no longer exported
+	state.PeerVoteLagSlots = nil
+	metrics.exportMetricPeerVoteLag(&state)
+	assert.Equal(t, 0, testutil.CollectAndCount(metrics.peerVoteLagSlots))
+}
+
+func TestExportMetricRoleTransitions(t *testing.T) {
+	cfg := createTestConfig()
+	cacheInstance := createTestCache()
+	logger := createTestLogger()
+
+	opts := Options{
+		Config: cfg,
+		Logger: logger,
+		Cache:  cacheInstance,
+	}
+
+	metrics := New(opts)
+
+	state := cache.State{
+		ValidatorName:             "test-validator",
+		PublicIP:                  "192.168.1.100",
+		RoleTransitions:           3,
+		RoleTransitionsTotal:      7,
+		TransitionBudgetExhausted: true,
+	}
+
+	metrics.exportMetricRoleTransitions(&state)
+
+	// Verify the metrics were set by checking the registry
+	registry := metrics.GetRegistry()
+	metricsList, err := registry.Gather()
+	require.NoError(t, err)
+
+	expectedValues := map[string]float64{
+		"solana_validator_ha_role_transitions":            3,
+		"solana_validator_ha_role_transitions_total":      7,
+		"solana_validator_ha_transition_budget_exhausted": 1,
+	}
+	for name, expectedValue := range expectedValues {
+		var found *dto.MetricFamily
+		for _, metricFamily := range metricsList {
+			if *metricFamily.Name == name {
+				found = metricFamily
+				break
+			}
+		}
+
+		require.NotNil(t, found, name)
+		assert.Len(t, found.Metric, 1)
+		assert.Equal(t, expectedValue, *found.Metric[0].Gauge.Value, name)
+	}
+}
+
+func TestExportMetricLeaderSchedule(t *testing.T) {
+	cfg := createTestConfig()
+	cacheInstance := createTestCache()
+	logger := createTestLogger()
+
+	opts := Options{
+		Config: cfg,
+		Logger: logger,
+		Cache:  cacheInstance,
+	}
+
+	metrics := New(opts)
+
+	state := cache.State{
+		ValidatorName:         "test-validator",
+		PublicIP:              "192.168.1.100",
+		LeaderSlotsAtRisk:     4,
+		LeaderScheduleWaiting: true,
+	}
+
+	metrics.exportMetricLeaderSchedule(&state)
+
+	// Verify the metrics were set by checking the registry
+	registry := metrics.GetRegistry()
+	metricsList, err := registry.Gather()
+	require.NoError(t, err)
+
+	expectedValues := map[string]float64{
+		"solana_validator_ha_leader_slots_at_risk":    4,
+		"solana_validator_ha_leader_schedule_waiting": 1,
+	}
+	for name, expectedValue := range expectedValues {
+		var found *dto.MetricFamily
+		for _, metricFamily := range metricsList {
+			if *metricFamily.Name == name {
+				found = metricFamily
+				break
+			}
+		}
+
+		require.NotNil(t, found, name)
+		assert.Len(t, found.Metric, 1)
+		assert.Equal(t, expectedValue, *found.Metric[0].Gauge.Value, name)
+	}
+}
+
 func TestExportMetricFailoverStatus(t *testing.T) {
 	cfg := createTestConfig()
 	cacheInstance := createTestCache()
@@ -548,6 +851,47 @@ func TestStartServer(t *testing.T) {
 	}
 }
 
+func TestShutdownServer(t *testing.T) {
+	cfg := createTestConfig()
+	cacheInstance := createTestCache()
+	logger := createTestLogger()
+
+	opts := Options{
+		Config: cfg,
+		Logger: logger,
+		Cache:  cacheInstance,
+	}
+
+	metrics := New(opts)
+
+	// Shutdown server when not started
+	err := metrics.ShutdownServer(context.Background())
+	assert.NoError(t, err)
+
+	// Start server in a goroutine
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- metrics.StartServer(0) // Use port 0 for testing
+	}()
+
+	// Give the server a moment to start
+	time.Sleep(100 * time.Millisecond)
+
+	// Gracefully shutdown the server
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err = metrics.ShutdownServer(ctx)
+	assert.NoError(t, err)
+
+	// Wait for server to stop
+	select {
+	case err := <-serverErr:
+		assert.ErrorIs(t, err, http.ErrServerClosed)
+	case <-time.After(1 * time.Second):
+		t.Fatal("Server did not stop within timeout")
+	}
+}
+
 func TestStartServer_WithInvalidPort(t *testing.T) {
 	cfg := createTestConfig()
 	cacheInstance := createTestCache()
@@ -719,6 +1063,42 @@ func TestMetrics_WithHTTPClient(t *testing.T) {
 	}
 }
 
+func TestHandler_Groups(t *testing.T) {
+	var groupMetrics []*Metrics
+	for _, group := range []string{"mainnet", "testnet"} {
+		cfg := createTestConfig()
+		cfg.Group = group
+		cacheInstance := createTestCache()
+		metrics := New(Options{Config: cfg, Logger: createTestLogger(), Cache: cacheInstance})
+
+		cacheInstance.UpdateState(cache.State{
+			ValidatorName:  group + "-validator",
+			PublicIP:       "192.168.1.100",
+			Role:           "active",
+			Status:         "healthy",
+			PeerCount:      1,
+			FailoverStatus: "stable",
+		})
+		metrics.RefreshMetrics()
+		groupMetrics = append(groupMetrics, metrics)
+	}
+
+	recorder := httptest.NewRecorder()
+	Handler(groupMetrics...).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	body := recorder.Body.String()
+	assert.Contains(t, body, `solana_validator_ha_peer_count{environment="test",group="mainnet",public_ip="192.168.1.100",region="us-west-1",validator_name="mainnet-validator"} 1`)
+	assert.Contains(t, body, `solana_validator_ha_peer_count{environment="test",group="testnet",public_ip="192.168.1.100",region="us-west-1",validator_name="testnet-validator"} 1`)
+}
+
+func TestGetCommonLabels_WithoutGroup(t *testing.T) {
+	metrics := New(Options{Config: createTestConfig(), Logger: createTestLogger(), Cache: createTestCache()})
+
+	assert.NotContains(t, metrics.commonLabelNames, groupLabelName)
+	assert.NotContains(t, metrics.getCommonLabels(&cache.State{}), groupLabelName)
+}
+
 func TestMetrics_ConcurrentAccess(t *testing.T) {
 	cfg := createTestConfig()
 	cacheInstance := createTestCache()
@@ -765,3 +1145,110 @@ func TestMetrics_ConcurrentAccess(t *testing.T) {
 	require.NoError(t, err)
 	assert.NotEmpty(t, metricsList)
 }
+
+func TestExportMetricBalances(t *testing.T) {
+	metrics := New(Options{
+		Config: createTestConfig(),
+		Logger: createTestLogger(),
+		Cache:  createTestCache(),
+	})
+
+	state := cache.State{
+		ValidatorName: "test-validator",
+		PublicIP:      "192.168.1.100",
+		AccountBalances: map[string]cache.AccountBalance{
+			"active_identity":     {Pubkey: "identity-pubkey", Lamports: 2_000_000_000, Level: constants.BalanceLevelWarn},
+			"active_vote_account": {Pubkey: "vote-pubkey", Lamports: 30_000_000, Level: constants.BalanceLevelOK},
+		},
+	}
+	metrics.exportMetricBalances(&state)
+
+	metricsList, err := metrics.GetRegistry().Gather()
+	require.NoError(t, err)
+
+	lamportsByAccount := map[string]float64{}
+	levelByAccount := map[string]float64{}
+	for _, metricFamily := range metricsList {
+		for _, metric := range metricFamily.Metric {
+			var account string
+			for _, label := range metric.Label {
+				if *label.Name == accountLabelName {
+					account = *label.Value
+				}
+			}
+			switch *metricFamily.Name {
+			case "solana_validator_ha_balance_lamports":
+				lamportsByAccount[account] = *metric.Gauge.Value
+			case "solana_validator_ha_balance_level":
+				levelByAccount[account] = *metric.Gauge.Value
+			}
+		}
+	}
+
+	assert.Equal(t, map[string]float64{"active_identity": 2_000_000_000, "active_vote_account": 30_000_000}, lamportsByAccount)
+	assert.Equal(t, map[string]float64{"active_identity": 1, "active_vote_account": 0}, levelByAccount)
+
+	// accounts no longer watched are no longer exported
+	state.AccountBalances = nil
+	metrics.exportMetricBalances(&state)
+	assert.Equal(t, 0, testutil.CollectAndCount(metrics.balanceLamports))
+	assert.Equal(t, 0, testutil.CollectAndCount(metrics.balanceLevel))
+}
+
+func TestExportMetricPeerStats(t *testing.T) {
+	metrics := New(Options{
+		Config: createTestConfig(),
+		Logger: createTestLogger(),
+		Cache:  createTestCache(),
+	})
+
+	state := cache.State{
+		ValidatorName: "test-validator",
+		PublicIP:      "192.168.1.100",
+		PeerStats: map[string]cache.PeerStats{
+			"validator-2": {AvailabilityPercent: 50, TransitionsPerHour: 360, Flapping: true},
+		},
+	}
+	metrics.exportMetricPeerStats(&state)
+
+	labels := metrics.mergeLabels(prometheus.Labels{peerNameLabelName: "validator-2"}, metrics.getCommonLabels(&state))
+	assert.Equal(t, float64(50), testutil.ToFloat64(metrics.peerAvailabilityPercent.With(labels)))
+	assert.Equal(t, float64(360), testutil.ToFloat64(metrics.peerTransitionsPerHour.With(labels)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.peerFlapping.With(labels)))
+
+	// peers no longer configured are no longer exported
+	state.PeerStats = nil
+	metrics.exportMetricPeerStats(&state)
+	assert.Equal(t, 0, testutil.CollectAndCount(metrics.peerAvailabilityPercent))
+	assert.Equal(t, 0, testutil.CollectAndCount(metrics.peerTransitionsPerHour))
+	assert.Equal(t, 0, testutil.CollectAndCount(metrics.peerFlapping))
+}
+
+func TestExportMetricPeerUnexpectedIdentities(t *testing.T) {
+	metrics := New(Options{
+		Config: createTestConfig(),
+		Logger: createTestLogger(),
+		Cache:  createTestCache(),
+	})
+
+	state := cache.State{
+		ValidatorName: "test-validator",
+		PublicIP:      "192.168.1.100",
+		PeerUnexpectedIdentities: map[string]int{
+			"validator-2": 1,
+			"validator-3": 0,
+		},
+	}
+	metrics.exportMetricPeerUnexpectedIdentities(&state)
+
+	commonLabels := metrics.getCommonLabels(&state)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.peerUnexpectedIdentities.With(
+		metrics.mergeLabels(prometheus.Labels{peerNameLabelName: "validator-2"}, commonLabels))))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.peerUnexpectedIdentities.With(
+		metrics.mergeLabels(prometheus.Labels{peerNameLabelName: "validator-3"}, commonLabels))))
+
+	// peers no longer configured are no longer exported
+	state.PeerUnexpectedIdentities = nil
+	metrics.exportMetricPeerUnexpectedIdentities(&state)
+	assert.Equal(t, 0, testutil.CollectAndCount(metrics.peerUnexpectedIdentities))
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/log"
@@ -19,6 +20,8 @@ type Client struct {
 	urls []string
 	// clients is a map of RPC clients, keyed by the rpc URL
 	clients map[string]*rpc.Client
+	// mu guards lastSuccessfulURL - the HA and balance monitor loops share clients
+	mu sync.Mutex
 	// lastSuccessfulURL tracks the last URL that succeeded to avoid it for throttling protection
 	lastSuccessfulURL string
 	timeout           time.Duration
@@ -55,6 +58,9 @@ type rpcOperation[T any] struct {
 
 // getURLsToTry returns URLs to try with lastSuccessfulURL at the end for throttling protection
 func (c *Client) getURLsToTry() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if len(c.urls) <= 1 || c.lastSuccessfulURL == "" {
 		return c.urls
 	}
@@ -103,7 +109,9 @@ func executeWithRetry[T any](c *Client, ctx context.Context, op rpcOperation[T])
 		}
 
 		// Success! Update the last successful URL
+		c.mu.Lock()
 		c.lastSuccessfulURL = url
+		c.mu.Unlock()
 		return result, nil
 	}
 
@@ -111,6 +119,44 @@ func executeWithRetry[T any](c *Client, ctx context.Context, op rpcOperation[T])
 	return zero, fmt.Errorf("method call failed on all RPC endpoints method: %s, attempted_urls: %v, errors: %v", op.name, attemptedURLs, errors)
 }
 
+// EndpointResult is the result of a method call on one RPC endpoint
+type EndpointResult[T any] struct {
+	URL    string
+	Result T
+	Err    error
+}
+
+// executeOnEach executes an RPC method on every URL concurrently, returning the results in URL order
+func executeOnEach[T any](c *Client, ctx context.Context, op rpcOperation[T]) []EndpointResult[T] {
+	results := make([]EndpointResult[T], len(c.urls))
+
+	var wg sync.WaitGroup
+	for i, url := range c.urls {
+		results[i].URL = url
+		client, exists := c.clients[url]
+		if !exists {
+			results[i].Err = fmt.Errorf("no client for %s", url)
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i].Err = c.withTimeout(ctx, func(timeoutCtx context.Context) error {
+				var err error
+				results[i].Result, err = op.execute(client, timeoutCtx)
+				return err
+			})
+			if results[i].Err != nil {
+				c.logger.Debug("method call failed", "method", op.name, "error", results[i].Err, "rpc_url", url)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
 // GetSlot gets the current slot from the first working RPC client
 func (c *Client) GetSlot(ctx context.Context) (uint64, error) {
 	return executeWithRetry(c, ctx, rpcOperation[uint64]{
@@ -121,6 +167,17 @@ func (c *Client) GetSlot(ctx context.Context) (uint64, error) {
 	})
 }
 
+// GetSlotFromEach gets the current slot from every RPC client concurrently, for callers to follow the
+// cluster's progress on each endpoint against that endpoint's own earlier view
+func (c *Client) GetSlotFromEach(ctx context.Context) []EndpointResult[uint64] {
+	return executeOnEach(c, ctx, rpcOperation[uint64]{
+		name: "GetSlot",
+		execute: func(client *rpc.Client, ctx context.Context) (uint64, error) {
+			return client.GetSlot(ctx, rpc.CommitmentProcessed)
+		},
+	})
+}
+
 // GetVoteAccounts gets the vote accounts from the first working RPC client
 
 func (c *Client) GetVoteAccounts(ctx context.Context) (*rpc.GetVoteAccountsResult, error) {
@@ -134,6 +191,43 @@ func (c *Client) GetVoteAccounts(ctx context.Context) (*rpc.GetVoteAccountsResul
 	})
 }
 
+// GetVoteAccountsFromEach gets the vote accounts from every RPC client concurrently, for callers to follow
+// votes on each endpoint against that endpoint's own earlier view
+func (c *Client) GetVoteAccountsFromEach(ctx context.Context) []EndpointResult[*rpc.GetVoteAccountsResult] {
+	return executeOnEach(c, ctx, rpcOperation[*rpc.GetVoteAccountsResult]{
+		name: "GetVoteAccounts",
+		execute: func(client *rpc.Client, ctx context.Context) (*rpc.GetVoteAccountsResult, error) {
+			return client.GetVoteAccounts(ctx, &rpc.GetVoteAccountsOpts{
+				Commitment: rpc.CommitmentProcessed,
+			})
+		},
+	})
+}
+
+// GetEpochInfo gets the current epoch info from the first working RPC client
+func (c *Client) GetEpochInfo(ctx context.Context) (*rpc.GetEpochInfoResult, error) {
+	return executeWithRetry(c, ctx, rpcOperation[*rpc.GetEpochInfoResult]{
+		name: "GetEpochInfo",
+		execute: func(client *rpc.Client, ctx context.Context) (*rpc.GetEpochInfoResult, error) {
+			return client.GetEpochInfo(ctx, rpc.CommitmentProcessed)
+		},
+	})
+}
+
+// GetLeaderSchedule gets identity's leader slot indices for the epoch containing slot from the first working RPC client
+func (c *Client) GetLeaderSchedule(ctx context.Context, identity solana.PublicKey, slot uint64) (rpc.GetLeaderScheduleResult, error) {
+	return executeWithRetry(c, ctx, rpcOperation[rpc.GetLeaderScheduleResult]{
+		name: "GetLeaderSchedule",
+		execute: func(client *rpc.Client, ctx context.Context) (rpc.GetLeaderScheduleResult, error) {
+			return client.GetLeaderScheduleWithOpts(ctx, &rpc.GetLeaderScheduleOpts{
+				Commitment: rpc.CommitmentProcessed,
+				Epoch:      &slot, // despite its name this is a slot in the epoch to get the schedule for
+				Identity:   &identity,
+			})
+		},
+	})
+}
+
 // GetBalance gets the balance from the first working RPC client
 func (c *Client) GetBalance(ctx context.Context, pubkey solana.PublicKey) (*rpc.GetBalanceResult, error) {
 	return executeWithRetry(c, ctx, rpcOperation[*rpc.GetBalanceResult]{
@@ -148,6 +242,17 @@ func (c *Client) GetBalance(ctx context.Context, pubkey solana.PublicKey) (*rpc.
 	})
 }
 
+// GetMinimumBalanceForRentExemption gets the minimum balance for an account with dataSize bytes of data to be
+// rent exempt from the first working RPC client
+func (c *Client) GetMinimumBalanceForRentExemption(ctx context.Context, dataSize uint64) (uint64, error) {
+	return executeWithRetry(c, ctx, rpcOperation[uint64]{
+		name: "GetMinimumBalanceForRentExemption",
+		execute: func(client *rpc.Client, ctx context.Context) (uint64, error) {
+			return client.GetMinimumBalanceForRentExemption(ctx, dataSize, rpc.CommitmentProcessed)
+		},
+	})
+}
+
 // GetClusterNodes tries each RPC client in order and returns the first successful response
 func (c *Client) GetClusterNodes(ctx context.Context) ([]*rpc.GetClusterNodesResult, error) {
 	return executeWithRetry(c, ctx, rpcOperation[[]*rpc.GetClusterNodesResult]{
@@ -158,6 +263,17 @@ func (c *Client) GetClusterNodes(ctx context.Context) ([]*rpc.GetClusterNodesRes
 	})
 }
 
+// GetClusterNodesFromEach gets the cluster nodes from every RPC client concurrently, for callers to compare
+// what each endpoint sees in gossip
+func (c *Client) GetClusterNodesFromEach(ctx context.Context) []EndpointResult[[]*rpc.GetClusterNodesResult] {
+	return executeOnEach(c, ctx, rpcOperation[[]*rpc.GetClusterNodesResult]{
+		name: "GetClusterNodes",
+		execute: func(client *rpc.Client, ctx context.Context) ([]*rpc.GetClusterNodesResult, error) {
+			return client.GetClusterNodes(ctx)
+		},
+	})
+}
+
 // GetIdentity gets the identity from the first working RPC client
 func (c *Client) GetIdentity(ctx context.Context) (*rpc.GetIdentityResult, error) {
 	return executeWithRetry(c, ctx, rpcOperation[*rpc.GetIdentityResult]{
@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/gagliardetto/solana-go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -175,6 +176,92 @@ func TestGetClusterNodes(t *testing.T) {
 	assert.Equal(t, "1.16.0", *result[1].Version)
 }
 
+func TestGetClusterNodesFromEach(t *testing.T) {
+	server1 := mockSolanaRPCServer(t, map[string]interface{}{
+		"getClusterNodes": []map[string]interface{}{{"pubkey": "11111111111111111111111111111111", "gossip": "127.0.0.1:8001"}},
+	})
+	server2 := mockFailingServer(t)
+	server3 := mockSolanaRPCServer(t, map[string]interface{}{
+		"getClusterNodes": []map[string]interface{}{},
+	})
+
+	client := NewClient("test", server1.URL, server2.URL, server3.URL)
+	results := client.GetClusterNodesFromEach(context.Background())
+	require.Len(t, results, 3)
+
+	// results are in URL order, whichever answered first
+	assert.Equal(t, server1.URL, results[0].URL)
+	require.NoError(t, results[0].Err)
+	require.Len(t, results[0].Result, 1)
+	assert.Equal(t, "127.0.0.1:8001", *results[0].Result[0].Gossip)
+
+	assert.Equal(t, server2.URL, results[1].URL)
+	assert.Error(t, results[1].Err)
+
+	assert.Equal(t, server3.URL, results[2].URL)
+	require.NoError(t, results[2].Err)
+	assert.Empty(t, results[2].Result)
+}
+
+func TestGetSlotFromEach(t *testing.T) {
+	server1 := mockSolanaRPCServer(t, map[string]interface{}{"getSlot": 200})
+	server2 := mockFailingServer(t)
+	server3 := mockSolanaRPCServer(t, map[string]interface{}{"getSlot": 150})
+
+	client := NewClient("test", server1.URL, server2.URL, server3.URL)
+	results := client.GetSlotFromEach(context.Background())
+	require.Len(t, results, 3)
+
+	// each endpoint's own view, in URL order
+	assert.Equal(t, server1.URL, results[0].URL)
+	require.NoError(t, results[0].Err)
+	assert.Equal(t, uint64(200), results[0].Result)
+
+	assert.Equal(t, server2.URL, results[1].URL)
+	assert.Error(t, results[1].Err)
+
+	assert.Equal(t, server3.URL, results[2].URL)
+	require.NoError(t, results[2].Err)
+	assert.Equal(t, uint64(150), results[2].Result)
+}
+
+func TestGetVoteAccountsFromEach(t *testing.T) {
+	voteAccounts := func(lastVote uint64) map[string]interface{} {
+		return map[string]interface{}{
+			"current": []map[string]interface{}{{
+				"votePubkey":       "Vote111111111111111111111111111111111111111",
+				"nodePubkey":       "11111111111111111111111111111111",
+				"activatedStake":   1,
+				"epochVoteAccount": true,
+				"commission":       0,
+				"lastVote":         lastVote,
+				"epochCredits":     [][]uint64{},
+				"rootSlot":         0,
+			}},
+			"delinquent": []map[string]interface{}{},
+		}
+	}
+	server1 := mockSolanaRPCServer(t, map[string]interface{}{"getVoteAccounts": voteAccounts(100)})
+	server2 := mockFailingServer(t)
+	server3 := mockSolanaRPCServer(t, map[string]interface{}{"getVoteAccounts": voteAccounts(90)})
+
+	client := NewClient("test", server1.URL, server2.URL, server3.URL)
+	results := client.GetVoteAccountsFromEach(context.Background())
+	require.Len(t, results, 3)
+
+	// each endpoint's own view, in URL order
+	assert.Equal(t, server1.URL, results[0].URL)
+	require.NoError(t, results[0].Err)
+	assert.Equal(t, uint64(100), results[0].Result.Current[0].LastVote)
+
+	assert.Equal(t, server2.URL, results[1].URL)
+	assert.Error(t, results[1].Err)
+
+	assert.Equal(t, server3.URL, results[2].URL)
+	require.NoError(t, results[2].Err)
+	assert.Equal(t, uint64(90), results[2].Result.Current[0].LastVote)
+}
+
 func TestGetIdentity(t *testing.T) {
 	// Mock response for GetIdentity
 	mockResponse := map[string]interface{}{
@@ -193,6 +280,40 @@ func TestGetIdentity(t *testing.T) {
 	assert.Equal(t, "11111111111111111111111111111111", result.Identity.String())
 }
 
+func TestGetEpochInfo(t *testing.T) {
+	server := mockSolanaRPCServer(t, map[string]interface{}{
+		"getEpochInfo": map[string]interface{}{
+			"absoluteSlot":     166598,
+			"blockHeight":      166500,
+			"epoch":            27,
+			"slotIndex":        2790,
+			"slotsInEpoch":     8192,
+			"transactionCount": 22661093,
+		},
+	})
+
+	client := NewClient("test", server.URL)
+	result, err := client.GetEpochInfo(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(166598), result.AbsoluteSlot)
+	assert.Equal(t, uint64(2790), result.SlotIndex)
+	assert.Equal(t, uint64(8192), result.SlotsInEpoch)
+}
+
+func TestGetLeaderSchedule(t *testing.T) {
+	identity := "4Qkev8aNZcqFNSRhQzwyLMFSsi94jHqE8WNVTJzTP99F"
+	server := mockSolanaRPCServer(t, map[string]interface{}{
+		"getLeaderSchedule": map[string]interface{}{
+			identity: []uint64{0, 1, 2, 3},
+		},
+	})
+
+	client := NewClient("test", server.URL)
+	result, err := client.GetLeaderSchedule(context.Background(), solana.MustPublicKeyFromBase58(identity), 100)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{0, 1, 2, 3}, result[solana.MustPublicKeyFromBase58(identity)])
+}
+
 func TestGetHealth(t *testing.T) {
 	// Mock response for GetHealth
 	mockResponse := "ok"
@@ -0,0 +1,425 @@
+package simulation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	solanago "github.com/gagliardetto/solana-go"
+	solanagorpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+	"github.com/sol-strategies/solana-validator-ha/internal/command"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/ha"
+)
+
+const (
+	// SetIdentityCommand is the role command simulated validators understand - its only argument is the
+	// role whose identity to set
+	SetIdentityCommand = "set-identity"
+	// SlotDuration is how long a simulated slot lasts
+	SlotDuration = 400 * time.Millisecond
+	// DelinquentSlots is how many slots the active identity can go without voting before it is delinquent
+	DelinquentSlots = 128
+	// SlotsInEpoch is the number of slots in a simulated epoch
+	SlotsInEpoch = 432000
+	// GossipPort is the port simulated validators gossip on
+	GossipPort = 8001
+	// identityBalance is the balance of every identity - well above the rent-exempt minimum
+	identityBalance = 10 * solanago.LAMPORTS_PER_SOL
+)
+
+// errUnreachable is returned for anything that cannot get through a simulated network partition
+var errUnreachable = errors.New("network unreachable")
+
+// Cluster is a virtual solana cluster of simulated validators sharing a virtual clock. It plays the
+// cluster RPC, the validators' local RPC, their gossip addresses and the network between HA managers
+type Cluster struct {
+	mu           sync.Mutex
+	clock        *clock.Virtual
+	genesis      time.Time
+	activeKey    solanago.PrivateKey
+	gossipDelay  time.Duration
+	nodes        []*Node
+	lastVoteSlot uint64
+	trace        []string
+	step         int
+}
+
+// Node is a simulated validator and the HA manager managing it
+type Node struct {
+	cluster *Cluster
+	name    string
+	ip      string
+
+	passiveKey solanago.PrivateKey
+	// identities are the identity changes of the validator, oldest first, for gossip to lag behind
+	identities []identityChange
+
+	// faults - each lasts for the number of steps left
+	validatorDownSteps  int
+	unhealthySteps      int
+	partitionedSteps    int
+	localRPCOutageSteps int
+	managerDownSteps    int
+	failCommands        int
+
+	managerOptions ha.NewManagerOptions
+	manager        *ha.Manager
+	handler        http.Handler
+}
+
+// identityChange is the identity a validator started running with at a time
+type identityChange struct {
+	at       time.Time
+	identity solanago.PublicKey
+}
+
+// Name returns the name of the node
+func (n *Node) Name() string {
+	return n.name
+}
+
+// IP returns the IP address of the node
+func (n *Node) IP() string {
+	return n.ip
+}
+
+// Manager returns the HA manager of the node
+func (n *Node) Manager() *ha.Manager {
+	return n.manager
+}
+
+// Role returns the role of the identity the validator is running with, or unknown if it is down
+func (n *Node) Role() string {
+	n.cluster.mu.Lock()
+	defer n.cluster.mu.Unlock()
+	return n.role()
+}
+
+// Active returns true if the validator is running with the active identity, whether or not it can vote
+func (n *Node) Active() bool {
+	return n.Role() == constants.RoleNameActive
+}
+
+// Voting returns true if the validator is running with the active identity and its votes land
+func (n *Node) Voting() bool {
+	n.cluster.mu.Lock()
+	defer n.cluster.mu.Unlock()
+	return n.voting()
+}
+
+// role is Role - callers must hold the cluster mu
+func (n *Node) role() string {
+	switch {
+	case n.validatorDownSteps > 0:
+		return constants.RoleNameUnknown
+	case n.identity().Equals(n.cluster.activeKey.PublicKey()):
+		return constants.RoleNameActive
+	default:
+		return constants.RoleNamePassive
+	}
+}
+
+// voting is Voting - callers must hold the cluster mu
+func (n *Node) voting() bool {
+	return n.role() == constants.RoleNameActive && n.unhealthySteps == 0 && n.partitionedSteps == 0
+}
+
+// identity returns the identity the validator is running with - callers must hold the cluster mu
+func (n *Node) identity() solanago.PublicKey {
+	return n.identities[len(n.identities)-1].identity
+}
+
+// gossipIdentity returns the identity the cluster sees the validator with in gossip, which lags behind
+// identity changes by the cluster gossip delay - callers must hold the cluster mu
+func (n *Node) gossipIdentity() solanago.PublicKey {
+	seenAt := n.cluster.clock.Now().Add(-n.cluster.gossipDelay)
+	identity := n.identities[0].identity
+	for _, change := range n.identities {
+		if change.at.After(seenAt) {
+			break
+		}
+		identity = change.identity
+	}
+	return identity
+}
+
+// inGossip returns true if the cluster can see the validator - callers must hold the cluster mu
+func (n *Node) inGossip() bool {
+	return n.validatorDownSteps == 0 && n.partitionedSteps == 0
+}
+
+// setIdentity makes the validator run with the identity of role - callers must hold the cluster mu
+func (n *Node) setIdentity(role string) {
+	identity := n.passiveKey.PublicKey()
+	if role == constants.RoleNameActive {
+		identity = n.cluster.activeKey.PublicKey()
+	}
+
+	if n.identity().Equals(identity) {
+		return
+	}
+
+	n.identities = append(n.identities, identityChange{at: n.cluster.clock.Now(), identity: identity})
+	n.cluster.tracef("%s identity set to %s", n.name, role)
+}
+
+// GetHealth implements ha.LocalRPC
+func (n *Node) GetHealth(ctx context.Context) (string, error) {
+	n.cluster.mu.Lock()
+	defer n.cluster.mu.Unlock()
+
+	if n.validatorDownSteps > 0 || n.localRPCOutageSteps > 0 {
+		return "", errUnreachable
+	}
+	if n.unhealthySteps > 0 {
+		return "", fmt.Errorf("node is behind")
+	}
+	return solanagorpc.HealthOk, nil
+}
+
+// GetIdentity implements ha.LocalRPC
+func (n *Node) GetIdentity(ctx context.Context) (*solanagorpc.GetIdentityResult, error) {
+	n.cluster.mu.Lock()
+	defer n.cluster.mu.Unlock()
+
+	if n.validatorDownSteps > 0 || n.localRPCOutageSteps > 0 {
+		return nil, errUnreachable
+	}
+	return &solanagorpc.GetIdentityResult{Identity: n.identity()}, nil
+}
+
+// Run implements command.Runner - SetIdentityCommand sets the validator identity, any other command
+// (e.g. hooks) succeeds without doing anything
+func (n *Node) Run(opts command.RunOptions) error {
+	n.cluster.mu.Lock()
+	defer n.cluster.mu.Unlock()
+
+	if opts.Command != SetIdentityCommand {
+		return nil
+	}
+
+	if len(opts.Args) != 1 || (opts.Args[0] != constants.RoleNameActive && opts.Args[0] != constants.RoleNamePassive) {
+		return fmt.Errorf("usage: %s %s|%s", SetIdentityCommand, constants.RoleNameActive, constants.RoleNamePassive)
+	}
+
+	if n.failCommands > 0 {
+		n.failCommands--
+		n.cluster.tracef("%s %s command failed", n.name, opts.Args[0])
+		return fmt.Errorf("simulated %s command failure", opts.Args[0])
+	}
+
+	if n.validatorDownSteps > 0 {
+		return fmt.Errorf("validator is not running")
+	}
+
+	n.setIdentity(opts.Args[0])
+	return nil
+}
+
+// dial implements the gossip liveness probe for n - gossip addresses answer if both ends are connected
+func (n *Node) dial(network, address string) (net.Conn, error) {
+	n.cluster.mu.Lock()
+	defer n.cluster.mu.Unlock()
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	peer := n.cluster.node(host)
+	if n.partitionedSteps > 0 || peer == nil || !peer.inGossip() {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: errUnreachable}
+	}
+
+	client, server := net.Pipe()
+	server.Close()
+	return client, nil
+}
+
+// RoundTrip implements http.RoundTripper for requests from n to its peers' HA APIs, served in process
+func (n *Node) RoundTrip(request *http.Request) (*http.Response, error) {
+	n.cluster.mu.Lock()
+	peer := n.cluster.node(request.URL.Hostname())
+	var handler http.Handler
+	if peer != nil && n.partitionedSteps == 0 && peer.partitionedSteps == 0 && peer.managerDownSteps == 0 {
+		handler = peer.handler
+	}
+	n.cluster.mu.Unlock()
+
+	if handler == nil {
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errUnreachable}
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder.Result(), nil
+}
+
+// clusterView is the cluster RPC as seen from a node - none of it is reachable while the node is partitioned
+type clusterView struct {
+	node *Node
+}
+
+// reachable returns errUnreachable if the node is partitioned - callers must hold the cluster mu
+func (v clusterView) reachable() error {
+	if v.node.partitionedSteps > 0 {
+		return errUnreachable
+	}
+	return nil
+}
+
+// GetClusterNodes returns every validator the cluster can see with its identity as seen in gossip
+func (v clusterView) GetClusterNodes(ctx context.Context) ([]*solanagorpc.GetClusterNodesResult, error) {
+	c := v.node.cluster
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := v.reachable(); err != nil {
+		return nil, err
+	}
+
+	var nodes []*solanagorpc.GetClusterNodesResult
+	for _, node := range c.nodes {
+		if !node.inGossip() {
+			continue
+		}
+		gossip := net.JoinHostPort(node.ip, fmt.Sprint(GossipPort))
+		nodes = append(nodes, &solanagorpc.GetClusterNodesResult{
+			Pubkey: node.gossipIdentity(),
+			Gossip: &gossip,
+		})
+	}
+	return nodes, nil
+}
+
+// GetSlot returns the current slot
+func (v clusterView) GetSlot(ctx context.Context) (uint64, error) {
+	c := v.node.cluster
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := v.reachable(); err != nil {
+		return 0, err
+	}
+	return c.slot(), nil
+}
+
+// GetVoteAccounts returns the vote account of the active identity - current while it votes and
+// delinquent once it has not voted for DelinquentSlots
+func (v clusterView) GetVoteAccounts(ctx context.Context) (*solanagorpc.GetVoteAccountsResult, error) {
+	c := v.node.cluster
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := v.reachable(); err != nil {
+		return nil, err
+	}
+
+	c.recordVotes()
+	voteAccount := solanagorpc.VoteAccountsResult{
+		NodePubkey: c.activeKey.PublicKey(),
+		LastVote:   c.lastVoteSlot,
+	}
+
+	result := &solanagorpc.GetVoteAccountsResult{}
+	if c.slot()-c.lastVoteSlot > DelinquentSlots {
+		result.Delinquent = append(result.Delinquent, voteAccount)
+	} else {
+		result.Current = append(result.Current, voteAccount)
+	}
+	return result, nil
+}
+
+// GetBalance returns the balance of an identity
+func (v clusterView) GetBalance(ctx context.Context, pubkey solanago.PublicKey) (*solanagorpc.GetBalanceResult, error) {
+	c := v.node.cluster
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := v.reachable(); err != nil {
+		return nil, err
+	}
+	return &solanagorpc.GetBalanceResult{Value: identityBalance}, nil
+}
+
+// GetMinimumBalanceForRentExemption returns the rent-exempt minimum for dataSize bytes at the default rent
+func (v clusterView) GetMinimumBalanceForRentExemption(ctx context.Context, dataSize uint64) (uint64, error) {
+	c := v.node.cluster
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := v.reachable(); err != nil {
+		return 0, err
+	}
+	return (128 + dataSize) * 3480 * 2, nil
+}
+
+// GetEpochInfo returns the current epoch
+func (v clusterView) GetEpochInfo(ctx context.Context) (*solanagorpc.GetEpochInfoResult, error) {
+	c := v.node.cluster
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := v.reachable(); err != nil {
+		return nil, err
+	}
+
+	slot := c.slot()
+	return &solanagorpc.GetEpochInfoResult{
+		AbsoluteSlot: slot,
+		Epoch:        slot / SlotsInEpoch,
+		SlotIndex:    slot % SlotsInEpoch,
+		SlotsInEpoch: SlotsInEpoch,
+	}, nil
+}
+
+// GetLeaderSchedule returns no leader slots - simulated validators are never leader
+func (v clusterView) GetLeaderSchedule(ctx context.Context, identity solanago.PublicKey, slot uint64) (solanagorpc.GetLeaderScheduleResult, error) {
+	c := v.node.cluster
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := v.reachable(); err != nil {
+		return nil, err
+	}
+	return solanagorpc.GetLeaderScheduleResult{}, nil
+}
+
+// slot returns the current slot - callers must hold mu
+func (c *Cluster) slot() uint64 {
+	return uint64(c.clock.Now().Sub(c.genesis) / SlotDuration)
+}
+
+// recordVotes lands a vote for the current slot if a validator is voting with the active identity -
+// callers must hold mu
+func (c *Cluster) recordVotes() {
+	for _, node := range c.nodes {
+		if node.voting() {
+			c.lastVoteSlot = c.slot()
+			return
+		}
+	}
+}
+
+// node returns the node with ip or nil - callers must hold mu
+func (c *Cluster) node(ip string) *Node {
+	for _, node := range c.nodes {
+		if node.ip == ip {
+			return node
+		}
+	}
+	return nil
+}
+
+// tracef records something that happened in the trace - callers must hold mu
+func (c *Cluster) tracef(format string, args ...any) {
+	line := fmt.Sprintf("step %d %s: %s", c.step, c.clock.Now().UTC().Format(time.TimeOnly), fmt.Sprintf(format, args...))
+	c.trace = append(c.trace, line)
+}
@@ -0,0 +1,409 @@
+package simulation
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	solanago "github.com/gagliardetto/solana-go"
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/ha"
+)
+
+// Options configure a simulation
+type Options struct {
+	// Nodes is the number of validators in the cluster - defaults to 3
+	Nodes int
+	// Seed seeds every random choice in the simulation - runs with the same options and seed are identical
+	Seed int64
+	// Steps is the number of failover.poll_interval_duration samples to simulate - defaults to 200
+	Steps int
+	// QuietSteps are the final steps in which no new faults are injected, to check the cluster settles
+	QuietSteps int
+	// Faults are the probabilities of faults being injected
+	Faults Faults
+	// GossipDelay is how long it takes the cluster to see a validator's identity change in gossip
+	GossipDelay time.Duration
+	// StateDir is the directory each node gets its failover.state_dir in - when empty the event journal
+	// and flap damping state are not persisted
+	StateDir string
+	// Configure adjusts the config of each node before its HA manager is created
+	Configure func(cfg *config.Config)
+	// Invariants are checked after every HA manager step - the simulation stops at the first violation
+	Invariants []Invariant
+}
+
+// Faults are the probabilities per node per step of each fault being injected
+type Faults struct {
+	// ValidatorRestart stops the validator, which comes back with its passive identity
+	ValidatorRestart float64
+	// Unhealthy makes the validator fall behind - it reports unhealthy and its votes do not land
+	Unhealthy float64
+	// Partition cuts the node off from the cluster and its peers
+	Partition float64
+	// LocalRPCOutage makes the validator's RPC unreachable while it keeps running
+	LocalRPCOutage float64
+	// CommandFailure makes the next role command fail
+	CommandFailure float64
+	// ManagerRestart stops the HA manager, which comes back having lost its in-memory state
+	ManagerRestart float64
+	// MaxSteps is the most steps a fault lasts - defaults to 10
+	MaxSteps int
+}
+
+// Invariant is checked against the nodes after every HA manager step of step and returns an error
+// describing any violation
+type Invariant func(step int, nodes []*Node) error
+
+// Result is the outcome of a simulation
+type Result struct {
+	// Trace is every fault injected and identity change made, in order
+	Trace []string
+	// Violation is the first invariant violation, if any
+	Violation error
+	// Nodes are the nodes as they were when the simulation stopped
+	Nodes []*Node
+}
+
+// Voting returns the nodes voting with the active identity when the simulation stopped
+func (r *Result) Voting() (voting []*Node) {
+	for _, node := range r.Nodes {
+		if node.Voting() {
+			voting = append(voting, node)
+		}
+	}
+	return voting
+}
+
+// simulation is a single run of Run
+type simulation struct {
+	opts    Options
+	rand    *rand.Rand
+	clock   *clock.Virtual
+	cluster *Cluster
+}
+
+// Run simulates opts.Nodes HA managers against a shared virtual cluster on a virtual clock. Every step
+// faults are injected and each manager evaluates the HA state once, in random order. The first node
+// starts with the active identity
+func Run(opts Options) (*Result, error) {
+	if opts.Nodes == 0 {
+		opts.Nodes = 3
+	}
+	if opts.Steps == 0 {
+		opts.Steps = 200
+	}
+	if opts.Faults.MaxSteps == 0 {
+		opts.Faults.MaxSteps = 10
+	}
+
+	s := &simulation{
+		opts: opts,
+		rand: rand.New(rand.NewSource(opts.Seed)),
+	}
+
+	// start on a poll interval boundary, as the monitor loop aligns to them
+	genesis := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	s.clock = clock.NewVirtual(genesis)
+	s.cluster = &Cluster{
+		clock:       s.clock,
+		genesis:     genesis,
+		activeKey:   s.newKey(),
+		gossipDelay: opts.GossipDelay,
+	}
+
+	for i := range opts.Nodes {
+		node := &Node{
+			cluster:    s.cluster,
+			name:       fmt.Sprintf("node-%d", i+1),
+			ip:         fmt.Sprintf("10.0.0.%d", i+1),
+			passiveKey: s.newKey(),
+		}
+		role := constants.RoleNamePassive
+		if i == 0 {
+			role = constants.RoleNameActive
+		}
+		node.identities = []identityChange{{at: genesis, identity: node.passiveKey.PublicKey()}}
+		node.setIdentity(role)
+		s.cluster.nodes = append(s.cluster.nodes, node)
+	}
+
+	for _, node := range s.cluster.nodes {
+		if err := s.startManager(node); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &Result{Nodes: s.cluster.nodes}
+	for step := 1; step <= opts.Steps; step++ {
+		restarted := s.beginStep(step, step <= opts.Steps-opts.QuietSteps)
+		for _, node := range restarted {
+			if err := s.startManager(node); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, i := range s.rand.Perm(len(s.cluster.nodes)) {
+			node := s.cluster.nodes[i]
+			if s.managerDown(node) {
+				continue
+			}
+
+			if err := node.manager.Step(); err != nil {
+				return nil, fmt.Errorf("step %d: %s: %w", step, node.name, err)
+			}
+
+			s.cluster.mu.Lock()
+			if node.handler == nil {
+				node.handler = node.manager.APIHandler()
+			}
+			s.cluster.recordVotes()
+			s.cluster.mu.Unlock()
+
+			for _, invariant := range opts.Invariants {
+				if err := invariant(step, s.cluster.nodes); err != nil {
+					result.Violation = fmt.Errorf("step %d after %s: %w", step, node.name, err)
+					result.Trace = s.trace()
+					return result, nil
+				}
+			}
+		}
+
+		// managers sample on poll interval boundaries - waits within a step may have taken us past one
+		interval := s.cluster.nodes[0].managerOptions.Cfg.Failover.PollIntervalDuration
+		s.clock.AdvanceTo(s.clock.Now().Truncate(interval).Add(interval))
+	}
+
+	result.Trace = s.trace()
+	return result, nil
+}
+
+// startManager creates a new HA manager for node as it would be configured on a fresh start
+func (s *simulation) startManager(node *Node) error {
+	cfg := &config.Config{
+		Validator: config.Validator{
+			Name:   node.name,
+			RPCURL: fmt.Sprintf("http://%s:8899", node.ip),
+			Identities: config.ValidatorIdentities{
+				ActiveKeyPair:  &s.cluster.activeKey,
+				PassiveKeyPair: &node.passiveKey,
+			},
+		},
+		Cluster: config.Cluster{
+			Name:    "simulation",
+			RPCURLs: []string{"http://cluster:8899"},
+		},
+		Failover: config.Failover{
+			Peers: config.Peers{},
+			Active: config.Role{
+				Command: SetIdentityCommand,
+				Args:    []string{constants.RoleNameActive},
+			},
+			Passive: config.Role{
+				Command: SetIdentityCommand,
+				Args:    []string{constants.RoleNamePassive},
+			},
+		},
+	}
+
+	for _, peer := range s.cluster.nodes {
+		if peer != node {
+			cfg.Failover.Peers[peer.name] = config.Peer{Name: peer.name, IP: peer.ip}
+		}
+	}
+
+	cfg.Prometheus.SetDefaults()
+	cfg.Failover.SetDefaults()
+	cfg.Failover.StateDir = ""
+	if s.opts.StateDir != "" {
+		cfg.Failover.StateDir = filepath.Join(s.opts.StateDir, node.name)
+		if err := os.MkdirAll(cfg.Failover.StateDir, 0o750); err != nil {
+			return fmt.Errorf("failed to create state dir: %w", err)
+		}
+	}
+
+	if s.opts.Configure != nil {
+		s.opts.Configure(cfg)
+	}
+
+	node.managerOptions = ha.NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: func() (string, error) { return node.ip, nil },
+		Clock:           s.clock,
+		LocalRPC:        node,
+		ClusterRPC:      clusterView{node: node},
+		CommandRunner:   node,
+		PeerTransport:   node,
+		GossipDialFunc:  node.dial,
+		Rand:            rand.New(rand.NewSource(s.rand.Int63())),
+	}
+
+	s.cluster.mu.Lock()
+	defer s.cluster.mu.Unlock()
+
+	// the HA API is served once the manager has initialized on its first step
+	node.manager = ha.NewManager(node.managerOptions)
+	node.handler = nil
+	return nil
+}
+
+// beginStep counts down the faults in progress and, if inject is true, injects new ones. It returns the
+// nodes whose HA manager must be restarted
+func (s *simulation) beginStep(step int, inject bool) (restarted []*Node) {
+	c := s.cluster
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.step = step
+	c.recordVotes()
+
+	for _, node := range c.nodes {
+		if countDown(&node.validatorDownSteps) {
+			c.tracef("%s validator restarted", node.name)
+		}
+		if countDown(&node.unhealthySteps) {
+			c.tracef("%s healthy", node.name)
+		}
+		if countDown(&node.partitionedSteps) {
+			c.tracef("%s reconnected", node.name)
+		}
+		if countDown(&node.localRPCOutageSteps) {
+			c.tracef("%s local rpc reachable", node.name)
+		}
+		if countDown(&node.managerDownSteps) {
+			c.tracef("%s ha manager restarted", node.name)
+			restarted = append(restarted, node)
+		}
+	}
+
+	if !inject {
+		return restarted
+	}
+
+	faults := s.opts.Faults
+	for _, node := range c.nodes {
+		if node.validatorDownSteps == 0 && s.chance(faults.ValidatorRestart) {
+			node.setIdentity(constants.RoleNamePassive)
+			node.validatorDownSteps = s.faultSteps()
+			c.tracef("%s validator down for %d steps", node.name, node.validatorDownSteps)
+		}
+		if node.unhealthySteps == 0 && s.chance(faults.Unhealthy) {
+			node.unhealthySteps = s.faultSteps()
+			c.tracef("%s unhealthy for %d steps", node.name, node.unhealthySteps)
+		}
+		if node.partitionedSteps == 0 && s.chance(faults.Partition) {
+			node.partitionedSteps = s.faultSteps()
+			c.tracef("%s partitioned for %d steps", node.name, node.partitionedSteps)
+		}
+		if node.localRPCOutageSteps == 0 && s.chance(faults.LocalRPCOutage) {
+			node.localRPCOutageSteps = s.faultSteps()
+			c.tracef("%s local rpc unreachable for %d steps", node.name, node.localRPCOutageSteps)
+		}
+		if node.managerDownSteps == 0 && s.chance(faults.ManagerRestart) {
+			node.managerDownSteps = s.faultSteps()
+			c.tracef("%s ha manager down for %d steps", node.name, node.managerDownSteps)
+		}
+		if s.chance(faults.CommandFailure) {
+			node.failCommands++
+			c.tracef("%s next role command will fail", node.name)
+		}
+	}
+
+	return restarted
+}
+
+// managerDown returns true if the HA manager of node is not running
+func (s *simulation) managerDown(node *Node) bool {
+	s.cluster.mu.Lock()
+	defer s.cluster.mu.Unlock()
+	return node.managerDownSteps > 0
+}
+
+// chance returns true with probability p
+func (s *simulation) chance(p float64) bool {
+	return p > 0 && s.rand.Float64() < p
+}
+
+// faultSteps returns a random number of steps for a fault to last
+func (s *simulation) faultSteps() int {
+	return 1 + s.rand.Intn(s.opts.Faults.MaxSteps)
+}
+
+// newKey returns a key derived from the simulation seed
+func (s *simulation) newKey() solanago.PrivateKey {
+	seed := make([]byte, ed25519.SeedSize)
+	s.rand.Read(seed)
+	return solanago.PrivateKey(ed25519.NewKeyFromSeed(seed))
+}
+
+// trace returns a copy of the trace so far
+func (s *simulation) trace() []string {
+	s.cluster.mu.Lock()
+	defer s.cluster.mu.Unlock()
+	return append([]string(nil), s.cluster.trace...)
+}
+
+// countDown decrements a fault's steps left and returns true when the fault has just ended
+func countDown(steps *int) bool {
+	if *steps == 0 {
+		return false
+	}
+	*steps--
+	return *steps == 0
+}
+
+// AtMostOneVoting is violated when more than one validator votes with the active identity - the
+// double signing failover must never cause
+func AtMostOneVoting(step int, nodes []*Node) error {
+	var voting []string
+	for _, node := range nodes {
+		if node.Voting() {
+			voting = append(voting, node.name)
+		}
+	}
+	if len(voting) > 1 {
+		return fmt.Errorf("%d validators voting with the active identity: %v", len(voting), voting)
+	}
+	return nil
+}
+
+// AtMostOneActive is violated when more than one running validator has the active identity, whether or
+// not its votes land
+func AtMostOneActive(step int, nodes []*Node) error {
+	var active []string
+	for _, node := range nodes {
+		if node.Active() {
+			active = append(active, node.name)
+		}
+	}
+	if len(active) > 1 {
+		return fmt.Errorf("%d validators with the active identity: %v", len(active), active)
+	}
+	return nil
+}
+
+// SplitBrainResolvedWithin returns an invariant violated when more than one validator has been voting
+// with the active identity for more than steps consecutive steps - for faults such as partitions that
+// failover cannot prevent but must recover from
+func SplitBrainResolvedWithin(steps int) Invariant {
+	since := 0
+	return func(step int, nodes []*Node) error {
+		err := AtMostOneVoting(step, nodes)
+		if err == nil {
+			since = 0
+			return nil
+		}
+		if since == 0 {
+			since = step
+		}
+		if step-since > steps {
+			return fmt.Errorf("split-brain since step %d: %w", since, err)
+		}
+		return nil
+	}
+}
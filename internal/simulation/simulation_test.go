@@ -0,0 +1,197 @@
+package simulation
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	solanago "github.com/gagliardetto/solana-go"
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/journal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seeds is the number of randomized fault schedules each invariant is checked against, e.g.
+// go test ./internal/simulation -seeds 5000
+var seeds = flag.Int("seeds", 200, "number of randomized fault schedules to simulate per test")
+
+func TestMain(m *testing.M) {
+	// thousands of managers logging every sample drown out test output
+	log.SetOutput(io.Discard)
+	log.SetLevel(log.FatalLevel)
+	os.Exit(m.Run())
+}
+
+// faultsWithoutPartitions are faults failover is expected to handle without two validators ever voting
+var faultsWithoutPartitions = Faults{
+	ValidatorRestart: 0.01,
+	Unhealthy:        0.01,
+	LocalRPCOutage:   0.01,
+	CommandFailure:   0.01,
+	ManagerRestart:   0.01,
+}
+
+// withQuorum enables takeover quorum and self-demotion of an unhealthy active
+func withQuorum(cfg *config.Config) {
+	cfg.Failover.TakeoverQuorum = true
+	cfg.Failover.ActiveUnhealthySamplesThreshold = 2
+}
+
+// runSeeds runs opts once per seed and fails on the first invariant violation or if the cluster has not
+// settled on exactly one voting validator by the end
+func runSeeds(t *testing.T, opts Options) {
+	t.Helper()
+
+	count := *seeds
+	if testing.Short() {
+		count = 10
+	}
+
+	for seed := range int64(count) {
+		opts.Seed = seed
+		result, err := Run(opts)
+		require.NoError(t, err)
+
+		if result.Violation != nil {
+			t.Fatalf("seed %d: %v\n%s", seed, result.Violation, strings.Join(result.Trace, "\n"))
+		}
+		if voting := result.Voting(); len(voting) != 1 {
+			t.Fatalf("seed %d: %d validators voting after %d quiet steps\n%s", seed, len(voting), opts.QuietSteps, strings.Join(result.Trace, "\n"))
+		}
+	}
+}
+
+func TestRun_Deterministic(t *testing.T) {
+	opts := Options{
+		Seed:        42,
+		Steps:       150,
+		GossipDelay: 10 * time.Second,
+		Faults:      faultsWithoutPartitions,
+		Configure:   withQuorum,
+	}
+	opts.Faults.Partition = 0.01
+
+	first, err := Run(opts)
+	require.NoError(t, err)
+	second, err := Run(opts)
+	require.NoError(t, err)
+
+	assert.Equal(t, first.Trace, second.Trace)
+	assert.Greater(t, len(first.Trace), opts.Steps/10, "expected faults and identity changes in the trace")
+
+	opts.Seed = 43
+	other, err := Run(opts)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Trace, other.Trace)
+}
+
+func TestRun_TakeoverQuorumNeverDoubleVotes(t *testing.T) {
+	runSeeds(t, Options{
+		Steps:       150,
+		QuietSteps:  50,
+		GossipDelay: 10 * time.Second,
+		Faults:      faultsWithoutPartitions,
+		Configure:   withQuorum,
+		Invariants:  []Invariant{AtMostOneVoting, AtMostOneActive},
+	})
+}
+
+// TestRun_NegativeControl_WithoutTakeoverQuorum is a negative control - it checks the invariants catch a
+// known double vote rather than that the config is safe. Without takeover quorum, passive peers racing to
+// take over before gossip shows the winner end up both active and voting
+func TestRun_NegativeControl_WithoutTakeoverQuorum(t *testing.T) {
+	opts := Options{
+		Steps:       150,
+		GossipDelay: 10 * time.Second,
+		Faults:      faultsWithoutPartitions,
+		Invariants:  []Invariant{AtMostOneVoting, AtMostOneActive},
+	}
+
+	for seed := range int64(50) {
+		opts.Seed = seed
+		result, err := Run(opts)
+		require.NoError(t, err)
+		if result.Violation != nil {
+			assert.Contains(t, result.Violation.Error(), "with the active identity")
+			return
+		}
+	}
+	t.Fatal("expected at least one schedule to end up with two validators active")
+}
+
+func TestRun_SplitBrainResolved(t *testing.T) {
+	// a partitioned active keeps its identity, so the split-brain once it reconnects must be resolved
+	// within failover.split_brain_samples_threshold samples and a couple more for gossip to catch up
+	runSeeds(t, Options{
+		Steps:       150,
+		QuietSteps:  50,
+		GossipDelay: 10 * time.Second,
+		Faults: Faults{
+			ValidatorRestart: 0.01,
+			Partition:        0.01,
+			CommandFailure:   0.01,
+		},
+		Configure:  withQuorum,
+		Invariants: []Invariant{SplitBrainResolvedWithin(5)},
+	})
+}
+
+func TestRun_StateDir(t *testing.T) {
+	stateDir := t.TempDir()
+
+	result, err := Run(Options{
+		Steps:      50,
+		StateDir:   stateDir,
+		Faults:     Faults{ValidatorRestart: 0.05},
+		Configure:  withQuorum,
+		Invariants: []Invariant{AtMostOneVoting, AtMostOneActive},
+	})
+	require.NoError(t, err)
+	require.NoError(t, result.Violation)
+
+	var events []journal.Event
+	for _, node := range result.Nodes {
+		nodeEvents, err := journal.Read(journal.FilePath(filepath.Join(stateDir, node.Name())))
+		require.NoError(t, err)
+		events = append(events, nodeEvents...)
+	}
+	assert.NotEmpty(t, events, "expected failovers to be journaled")
+}
+
+func TestSplitBrainResolvedWithin(t *testing.T) {
+	cluster := &Cluster{
+		clock:     clock.NewVirtual(time.Now()),
+		activeKey: solanago.NewWallet().PrivateKey,
+	}
+
+	var nodes []*Node
+	for _, name := range []string{"node-1", "node-2"} {
+		node := &Node{cluster: cluster, name: name, passiveKey: solanago.NewWallet().PrivateKey}
+		node.identities = []identityChange{{identity: cluster.activeKey.PublicKey()}}
+		nodes = append(nodes, node)
+	}
+
+	invariant := SplitBrainResolvedWithin(2)
+	assert.NoError(t, invariant(1, nodes))
+	assert.NoError(t, invariant(3, nodes))
+	assert.ErrorContains(t, invariant(4, nodes), "split-brain since step 1")
+
+	// resolving resets the count
+	nodes[1].setIdentity(constants.RoleNamePassive)
+	assert.NoError(t, invariant(5, nodes))
+	assert.NoError(t, AtMostOneVoting(5, nodes))
+
+	// a validator that is behind does not vote
+	nodes[1].setIdentity(constants.RoleNameActive)
+	nodes[1].unhealthySteps = 1
+	assert.NoError(t, AtMostOneVoting(6, nodes))
+	assert.ErrorContains(t, AtMostOneActive(6, nodes), "2 validators with the active identity")
+}